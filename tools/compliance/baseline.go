@@ -0,0 +1,160 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of baseline entries.
+const (
+	// BaselineConflictEntry identifies a recorded SourceSharePrivacyConflict.
+	BaselineConflictEntry = "conflict"
+
+	// BaselineResolutionEntry identifies a recorded resolution of a single condition.
+	BaselineResolutionEntry = "resolution"
+)
+
+// Baseline is the set of known findings e.g. legacy conflicts allowing checks
+// to fail only on new findings, and to ratchet down as the known findings get
+// fixed.
+//
+// A baseline file lists one entry per line in the form `kind field...`
+// where kind is "conflict" followed by the target and the privacy and share
+// condition names, or is "resolution" followed by the attaches-to target, the
+// acts-on target and the condition name. Blank lines and lines beginning with
+// '#' are ignored.
+type Baseline map[string]struct{}
+
+// BaselineConflict returns the baseline entry for `conflict`.
+func BaselineConflict(conflict SourceSharePrivacyConflict) string {
+	return strings.Join([]string{
+		BaselineConflictEntry,
+		conflict.SourceNode.name,
+		conflict.PrivacyCondition.Name(),
+		conflict.ShareCondition.Name(),
+	}, " ")
+}
+
+// BaselineConflicts returns the sorted baseline entries for `conflicts`.
+func BaselineConflicts(conflicts []SourceSharePrivacyConflict) []string {
+	entries := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		entries = append(entries, BaselineConflict(conflict))
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// BaselineResolutions returns the sorted baseline entries for `rs` with one
+// entry per resolved condition.
+func BaselineResolutions(rs ResolutionSet) []string {
+	var entries []string
+	for attachesTo, actionCS := range rs {
+		for actsOn, cs := range actionCS {
+			for _, name := range cs.Names() {
+				entries = append(entries, strings.Join([]string{
+					BaselineResolutionEntry,
+					attachesTo.name,
+					actsOn.name,
+					name,
+				}, " "))
+			}
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// NewBaseline returns the baseline containing `entries`.
+func NewBaseline(entries ...string) Baseline {
+	b := make(Baseline)
+	for _, entry := range entries {
+		b[entry] = struct{}{}
+	}
+	return b
+}
+
+// ReadBaseline reads the baseline file content from `r`.
+func ReadBaseline(r io.Reader) (Baseline, error) {
+	b := make(Baseline)
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 || (fields[0] != BaselineConflictEntry && fields[0] != BaselineResolutionEntry) {
+			return nil, fmt.Errorf("baseline line %d: want `conflict target privacy share` or `resolution attachesTo actsOn condition`, got %q", lineno, line)
+		}
+		b[strings.Join(fields, " ")] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// WriteBaseline writes the sorted, deduplicated `entries` to `w` as a
+// baseline file.
+func WriteBaseline(w io.Writer, entries []string) error {
+	sorted := NewBaseline(entries...).Entries()
+	if _, err := fmt.Fprintln(w, "# Known findings. Remove entries as they get fixed; do not add new ones."); err != nil {
+		return err
+	}
+	for _, entry := range sorted {
+		if _, err := fmt.Fprintln(w, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entries returns the sorted entries of the baseline.
+func (b Baseline) Entries() []string {
+	entries := make([]string, 0, len(b))
+	for entry := range b {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Compare returns the sorted `entries` missing from the baseline i.e. new
+// findings, and the sorted baseline entries missing from `entries` i.e. fixed
+// findings that may be removed from the baseline.
+func (b Baseline) Compare(entries []string) (added, removed []string) {
+	current := NewBaseline(entries...)
+	for entry := range current {
+		if _, ok := b[entry]; !ok {
+			added = append(added, entry)
+		}
+	}
+	for entry := range b {
+		if _, ok := current[entry]; !ok {
+			removed = append(removed, entry)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBaselineEntries(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	lg, err := toGraph(stderr, []string{"proprietary.meta_lic"}, []annotated{
+		{"proprietary.meta_lic", "gplLib.meta_lic", []string{"static"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected test data error: got %s, want no error", err)
+	}
+
+	checkStrings(t, "BaselineConflicts", BaselineConflicts(ConflictingSharedPrivateSource(lg)), []string{
+		"conflict proprietary.meta_lic proprietary restricted",
+	})
+
+	rs := WalkResolutionsForCondition(lg, ImpliesRestricted)
+	checkStrings(t, "BaselineResolutions", BaselineResolutions(rs), []string{
+		"resolution proprietary.meta_lic gplLib.meta_lic restricted",
+		"resolution proprietary.meta_lic proprietary.meta_lic restricted",
+	})
+}
+
+func TestReadWriteBaseline(t *testing.T) {
+	entries := []string{
+		"resolution a.meta_lic b.meta_lic restricted",
+		"conflict b.meta_lic proprietary restricted",
+		"conflict b.meta_lic proprietary restricted",
+	}
+	var buf bytes.Buffer
+	if err := WriteBaseline(&buf, entries); err != nil {
+		t.Fatalf("WriteBaseline: got error %v, want no error", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "#") {
+		t.Fatalf("WriteBaseline: got %q, want comment and 2 sorted entries", buf.String())
+	}
+	checkStrings(t, "WriteBaseline", lines[1:], []string{
+		"conflict b.meta_lic proprietary restricted",
+		"resolution a.meta_lic b.meta_lic restricted",
+	})
+
+	b, err := ReadBaseline(strings.NewReader(buf.String() + "\n  # indented comment\nconflict  c.meta_lic   proprietary restricted \n"))
+	if err != nil {
+		t.Fatalf("ReadBaseline: got error %v, want no error", err)
+	}
+	checkStrings(t, "ReadBaseline", b.Entries(), []string{
+		"conflict b.meta_lic proprietary restricted",
+		"conflict c.meta_lic proprietary restricted",
+		"resolution a.meta_lic b.meta_lic restricted",
+	})
+
+	for _, bad := range []string{
+		"conflict b.meta_lic proprietary\n",
+		"warning b.meta_lic proprietary restricted\n",
+	} {
+		if _, err := ReadBaseline(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadBaseline(%q): got no error, want error", bad)
+		}
+	}
+}
+
+func TestBaselineCompare(t *testing.T) {
+	b := NewBaseline(
+		"conflict a.meta_lic proprietary restricted",
+		"conflict b.meta_lic proprietary restricted",
+	)
+	added, removed := b.Compare([]string{
+		"conflict c.meta_lic proprietary restricted",
+		"conflict b.meta_lic proprietary restricted",
+		"resolution a.meta_lic b.meta_lic restricted",
+	})
+	checkStrings(t, "added", added, []string{
+		"conflict c.meta_lic proprietary restricted",
+		"resolution a.meta_lic b.meta_lic restricted",
+	})
+	checkStrings(t, "removed", removed, []string{
+		"conflict a.meta_lic proprietary restricted",
+	})
+
+	added, removed = b.Compare(b.Entries())
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Compare(Entries()): got added %q removed %q, want none", added, removed)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -101,10 +102,15 @@ Options:
 	}
 
 	outputFile := flags.String("o", "-", "Where to write the bill of materials. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
 	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -149,12 +155,18 @@ Options:
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", ofile.(*bytes.Buffer).Len())
 		err := os.WriteFile(*outputFile, ofile.(*bytes.Buffer).Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q: %s\n", *outputFile, err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -16,23 +16,62 @@ package main
 
 import (
 	"bytes"
+	gocontext "context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
 	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
 	failNoneRequested = fmt.Errorf("\nNo projects requested")
+	failLint          = fmt.Errorf("METADATA lint errors found")
 )
 
+type context struct {
+	lint        bool
+	json        bool
+	metaLic     []string
+	stripPrefix []string
+	disabled    map[string]bool
+}
+
+func (ctx context) strip(installPath string) string {
+	for _, prefix := range ctx.stripPrefix {
+		if strings.HasPrefix(installPath, prefix) {
+			p := strings.TrimPrefix(installPath, prefix)
+			if 0 == len(p) {
+				continue
+			}
+			return p
+		}
+	}
+	return installPath
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
 func main() {
 	var expandedArgs []string
 	for _, arg := range os.Args[1:] {
@@ -58,7 +97,7 @@ func main() {
 	flags := flag.NewFlagSet("flags", flag.ExitOnError)
 
 	flags.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: %s {-o outfile} projectdir {projectdir...}
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} projectdir {projectdir...}
 
 Tries to open the METADATA.android or METADATA file in each projectdir
 reporting any errors on stderr.
@@ -67,20 +106,67 @@ Reports "FAIL" to stdout if any errors found and exits with status 1.
 
 Otherwise, reports "PASS" and the number of project metadata files
 found exiting with status 0.
+
+When -lint flag given, also checks the content of each METADATA file
+and reports each finding on stdout as:
+
+  file: severity: [rule] message
+
+Any finding with error severity reports "FAIL" and exits with status 1.
+Findings with warning severity do not fail. The rules are:
+
+  third_party_version  third_party must have a version
+  third_party_url      third_party must have a url with a value
+  url_type             every url must have a known type
+  license_type         license_type must agree with the restricted,
+                       restricted_if_statically_linked or reciprocal
+                       conditions of the project's targets
+  last_upgrade_date    last_upgrade_date must be a valid date not in
+                       the future
+
+The license_type rule needs the license graph, and only applies when
+-meta_lic flag names the root license metadata files. Projects match
+the projects of the targets after removing any -strip_prefix.
+
+When -json flag given, implies -lint and reports the result and the
+findings as a JSON object for CI annotations instead.
+
+Options:
 `, filepath.Base(os.Args[0]))
 		flags.PrintDefaults()
 	}
 
 	outputFile := flags.String("o", "-", "Where to write the output. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	lint := flags.Bool("lint", false, "Whether to check the content of the METADATA files.")
+	jsonOutput := flags.Bool("json", false, "Whether to output the lint result as JSON. (implies -lint)")
+	metaLic := newMultiString(flags, "meta_lic", "Root license metadata file for the license_type rule. (multiple allowed)")
+	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from project paths. i.e. path to root (multiple allowed)")
+	disable := newMultiString(flags, "disable", "Lint rule ID to skip. (multiple allowed)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
 		os.Exit(2)
 	}
 
+	disabled := make(map[string]bool)
+	for _, name := range *disable {
+		rule, err := projectmetadata.ParseLintRule(name)
+		if err != nil {
+			flags.Usage()
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(2)
+		}
+		disabled[rule] = true
+	}
+
 	if len(*outputFile) == 0 {
 		flags.Usage()
 		fmt.Fprintf(os.Stderr, "must specify file for -o; use - for stdout\n")
@@ -110,39 +196,144 @@ found exiting with status 0.
 		ofile = obuf
 	}
 
-	err := checkProjectMetadata(ofile, os.Stderr, compliance.FS, flags.Args()...)
-	if err != nil {
+	ctx := &context{*lint || *jsonOutput, *jsonOutput, *metaLic, *stripPrefix, disabled}
+
+	err := checkProjectMetadata(ctx, ofile, os.Stderr, compliance.FS, flags.Args()...)
+	if err != nil && err != failLint {
 		if err == failNoneRequested {
 			flags.Usage()
 		}
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		fmt.Fprintln(ofile, "FAIL")
+		if !ctx.json {
+			fmt.Fprintln(ofile, "FAIL")
+		}
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
+	if err == failLint {
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
 // checkProjectMetadata implements the checkmetadata utility.
-func checkProjectMetadata(stdout, stderr io.Writer, rootFS fs.FS, projects ...string) error {
+func checkProjectMetadata(ctx *context, stdout, stderr io.Writer, rootFS fs.FS, projects ...string) error {
 
 	if len(projects) < 1 {
 		return failNoneRequested
 	}
 
-	// Read the project metadata files from `projects`
-	ix := projectmetadata.NewIndex(rootFS)
+	// Read the project metadata files from `projects` -- only METADATA files count
+	ix := projectmetadata.NewIndexWithOptions(gocontext.Background(), rootFS, projectmetadata.IndexOptions{Readers: []projectmetadata.MetadataReader{}})
 	pms, err := ix.MetadataForProjects(projects...)
 	if err != nil {
-		return fmt.Errorf("Unable to read project metadata file(s) %q from %q: %w\n", projects, os.Getenv("PWD"), err)
+		err = fmt.Errorf("Unable to read project metadata file(s) %q from %q: %w\n", projects, os.Getenv("PWD"), err)
+		if ctx.json {
+			writeJSON(stdout, lintResult{Result: "FAIL", Error: strings.TrimSpace(err.Error()), Projects: len(projects), Findings: []projectmetadata.Finding{}})
+		}
+		return err
+	}
+
+	if !ctx.lint {
+		fmt.Fprintf(stdout, "PASS -- parsed %d project metadata files for %d projects\n", len(pms), len(projects))
+		return nil
+	}
+
+	conditions, err := projectConditions(ctx, stderr, rootFS)
+	if err != nil {
+		return err
 	}
 
-	fmt.Fprintf(stdout, "PASS -- parsed %d project metadata files for %d projects\n", len(pms), len(projects))
+	result := lintResult{MetadataFiles: len(pms), Projects: len(projects), Findings: []projectmetadata.Finding{}}
+	for _, pm := range pms {
+		opts := projectmetadata.LintOptions{}
+		if conditions != nil {
+			opts.Conditions = conditions[ctx.strip(filepath.Clean(pm.Project()))]
+		}
+		for _, f := range pm.Lint(opts) {
+			if ctx.disabled[f.Rule] {
+				continue
+			}
+			if f.Severity == projectmetadata.SeverityError {
+				result.Errors++
+			} else {
+				result.Warnings++
+			}
+			result.Findings = append(result.Findings, f)
+		}
+	}
+	result.Result = "PASS"
+	if result.Errors > 0 {
+		result.Result = "FAIL"
+	}
+
+	if ctx.json {
+		writeJSON(stdout, result)
+	} else {
+		for _, f := range result.Findings {
+			fmt.Fprintln(stdout, f.String())
+		}
+		fmt.Fprintf(stdout, "%s -- linted %d project metadata files for %d projects: %d errors, %d warnings\n",
+			result.Result, result.MetadataFiles, result.Projects, result.Errors, result.Warnings)
+	}
+	if result.Errors > 0 {
+		return failLint
+	}
 	return nil
 }
+
+// lintResult describes the JSON output of -json.
+type lintResult struct {
+	Result        string                    `json:"result"`
+	Error         string                    `json:"error,omitempty"`
+	MetadataFiles int                       `json:"metadata_files"`
+	Projects      int                       `json:"projects"`
+	Errors        int                       `json:"errors"`
+	Warnings      int                       `json:"warnings"`
+	Findings      []projectmetadata.Finding `json:"findings"`
+}
+
+// writeJSON writes `result` to `w` as indented JSON.
+func writeJSON(w io.Writer, result lintResult) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(result)
+}
+
+// projectConditions returns the sorted names of the license conditions of
+// the targets in each project of the license graph rooted at ctx.metaLic,
+// or nil when no roots given.
+func projectConditions(ctx *context, stderr io.Writer, rootFS fs.FS) (map[string][]string, error) {
+	if len(ctx.metaLic) == 0 {
+		return nil, nil
+	}
+	lg, err := compliance.ReadLicenseGraph(rootFS, stderr, ctx.metaLic)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read license metadata file(s) %q: %w\n", ctx.metaLic, err)
+	}
+	sets := make(map[string]compliance.LicenseConditionSet)
+	for _, tn := range lg.Targets() {
+		for _, p := range tn.Projects() {
+			sets[filepath.Clean(p)] = sets[filepath.Clean(p)].Union(tn.LicenseConditions())
+		}
+	}
+	result := make(map[string][]string)
+	for p, cs := range sets {
+		names := cs.Names()
+		sort.Strings(names)
+		result[p] = names
+	}
+	return result, nil
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -167,7 +168,7 @@ func Test(t *testing.T) {
 			for _, project := range tt.projects {
 				projects = append(projects, "testdata/"+project)
 			}
-			err := checkProjectMetadata(stdout, stderr, compliance.GetFS(""), projects...)
+			err := checkProjectMetadata(&context{}, stdout, stderr, compliance.GetFS(""), projects...)
 			if err != nil {
 				t.Fatalf("checkmetadata: error = %v, stderr = %v", err, stderr)
 				return
@@ -189,3 +190,111 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func Test_lint(t *testing.T) {
+	tests := []struct {
+		name           string
+		projects       []string
+		ctx            context
+		expectedErr    error
+		expectedStdout []string
+	}{
+		{
+			name:     "clean",
+			projects: []string{"lint/base/library", "lint/firstparty"},
+			ctx: context{
+				lint:        true,
+				metaLic:     []string{"testdata/restricted/application.meta_lic"},
+				stripPrefix: []string{"testdata/lint/"},
+			},
+			expectedStdout: []string{
+				"PASS -- linted 2 project metadata files for 2 projects: 0 errors, 0 warnings",
+			},
+		},
+		{
+			name:     "warnings",
+			projects: []string{"lint/base/library", "lint/incomplete"},
+			ctx: context{
+				lint:     true,
+				disabled: map[string]bool{"third_party_version": true, "third_party_url": true},
+			},
+			expectedStdout: []string{
+				"testdata/lint/incomplete/METADATA: warning: [last_upgrade_date] third_party should have a last_upgrade_date",
+				"PASS -- linted 2 project metadata files for 2 projects: 0 errors, 1 warnings",
+			},
+		},
+		{
+			name:     "errors",
+			projects: []string{"lint/device/library", "lint/incomplete"},
+			ctx: context{
+				lint:        true,
+				metaLic:     []string{"testdata/restricted/application.meta_lic"},
+				stripPrefix: []string{"testdata/lint/"},
+			},
+			expectedErr: failLint,
+			expectedStdout: []string{
+				`testdata/lint/device/library/METADATA: error: [url_type] url 2 ("https://example.com/device/library.tar.gz") must have a type`,
+				"testdata/lint/device/library/METADATA: error: [license_type] license_type NOTICE inconsistent with restricted_if_statically_linked license condition; want RESTRICTED_IF_STATICALLY_LINKED",
+				"testdata/lint/device/library/METADATA: error: [last_upgrade_date] last_upgrade_date 2023-02-30 is not a valid date",
+				"testdata/lint/incomplete/METADATA: error: [third_party_version] third_party must have a version",
+				"testdata/lint/incomplete/METADATA: error: [third_party_url] third_party must have at least one url with a value",
+				"testdata/lint/incomplete/METADATA: warning: [last_upgrade_date] third_party should have a last_upgrade_date",
+				"FAIL -- linted 2 project metadata files for 2 projects: 5 errors, 1 warnings",
+			},
+		},
+		{
+			name:        "nolicensegraph",
+			projects:    []string{"lint/device/library"},
+			ctx:         context{lint: true},
+			expectedErr: failLint,
+			expectedStdout: []string{
+				`testdata/lint/device/library/METADATA: error: [url_type] url 2 ("https://example.com/device/library.tar.gz") must have a type`,
+				"testdata/lint/device/library/METADATA: error: [last_upgrade_date] last_upgrade_date 2023-02-30 is not a valid date",
+				"FAIL -- linted 1 project metadata files for 1 projects: 2 errors, 0 warnings",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			projects := make([]string, 0, len(tt.projects))
+			for _, project := range tt.projects {
+				projects = append(projects, "testdata/"+project)
+			}
+			err := checkProjectMetadata(&tt.ctx, stdout, stderr, compliance.GetFS(""), projects...)
+			if err != tt.expectedErr {
+				t.Fatalf("checkmetadata: got error %v, want %v, stderr = %v", err, tt.expectedErr, stderr)
+			}
+			actual := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			if strings.Join(actual, "\n") != strings.Join(tt.expectedStdout, "\n") {
+				t.Errorf("checkmetadata: unexpected stdout %q, want %q", actual, tt.expectedStdout)
+			}
+		})
+	}
+}
+
+func Test_json(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{lint: true, json: true}
+	err := checkProjectMetadata(ctx, stdout, stderr, compliance.GetFS(""), "testdata/lint/incomplete", "testdata/regressgpl1")
+	if err != failLint {
+		t.Fatalf("checkmetadata: got error %v, want %v, stderr = %v", err, failLint, stderr)
+	}
+	var result lintResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("checkmetadata: invalid json %s: %s", err, stdout)
+	}
+	if result.Result != "FAIL" || result.MetadataFiles != 1 || result.Projects != 2 || result.Errors != 2 || result.Warnings != 1 {
+		t.Errorf("checkmetadata: unexpected result %+v", result)
+	}
+	if len(result.Findings) != 3 {
+		t.Fatalf("checkmetadata: got %d findings, want 3: %+v", len(result.Findings), result.Findings)
+	}
+	f := result.Findings[0]
+	if f.File != "testdata/lint/incomplete/METADATA" || f.Rule != "third_party_version" || f.Severity != "error" {
+		t.Errorf("checkmetadata: unexpected finding %+v", f)
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -35,6 +36,21 @@ var (
 	failNoLicenses    = fmt.Errorf("No licenses")
 )
 
+type context struct {
+	stdout io.Writer
+	stderr io.Writer
+	rootFS fs.FS
+
+	// baseline, when not nil, lists the known findings that do not fail.
+	baseline compliance.Baseline
+
+	// restricted adds the restricted resolutions to the baseline findings.
+	restricted bool
+
+	// baselineOut, when not nil, receives the current findings as a baseline file.
+	baselineOut io.Writer
+}
+
 // byError orders conflicts by error string
 type byError []compliance.SourceSharePrivacyConflict
 
@@ -82,14 +98,29 @@ outputs "PASS" to stdout and exits with status 0.
 
 If policy says any source must both be shared and not be shared,
 outputs "FAIL" to stdout and exits with status 1.
+
+With -baseline, conflicts recorded in the baseline file do not fail, and
+baseline entries no longer found get reported on stderr so the baseline can
+be tightened. With -baseline_restricted, new restricted resolutions also
+fail. Use -write_baseline to record the current findings.
+
+Options:
 `, filepath.Base(os.Args[0]))
 		flags.PrintDefaults()
 	}
 
 	outputFile := flags.String("o", "-", "Where to write the output. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	baselineFile := flags.String("baseline", "", "Known findings that do not fail. (default none)")
+	writeBaselineFile := flags.String("write_baseline", "", "Where to record the current findings as a baseline file.")
+	restricted := flags.Bool("baseline_restricted", false, "Whether baselines include the restricted resolutions.")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -117,6 +148,12 @@ outputs "FAIL" to stdout and exits with status 1.
 		}
 	}
 
+	if *restricted && len(*baselineFile) == 0 && len(*writeBaselineFile) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "-baseline_restricted requires -baseline or -write_baseline\n")
+		os.Exit(2)
+	}
+
 	var ofile io.Writer
 	ofile = os.Stdout
 	var obuf *bytes.Buffer
@@ -125,7 +162,28 @@ outputs "FAIL" to stdout and exits with status 1.
 		ofile = obuf
 	}
 
-	err := checkShare(ofile, os.Stderr, compliance.FS, flags.Args()...)
+	ctx := &context{stdout: ofile, stderr: os.Stderr, rootFS: compliance.FS, restricted: *restricted}
+
+	if len(*baselineFile) > 0 {
+		f, err := os.Open(*baselineFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not open baseline %q: %s\n", *baselineFile, err)
+			os.Exit(1)
+		}
+		ctx.baseline, err = compliance.ReadBaseline(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read baseline %q: %s\n", *baselineFile, err)
+			os.Exit(1)
+		}
+	}
+	var bbuf *bytes.Buffer
+	if len(*writeBaselineFile) > 0 {
+		bbuf = &bytes.Buffer{}
+		ctx.baselineOut = bbuf
+	}
+
+	err := checkShare(ctx, flags.Args()...)
 	if err != nil {
 		if err != failConflicts {
 			if err == failNoneRequested {
@@ -136,24 +194,37 @@ outputs "FAIL" to stdout and exits with status 1.
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
 			os.Exit(1)
 		}
 	}
+	if bbuf != nil {
+		err := os.WriteFile(*writeBaselineFile, bbuf.Bytes(), 0666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write baseline to %q from %q: %s\n", *writeBaselineFile, os.Getenv("PWD"), err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
 // checkShare implements the checkshare utility.
-func checkShare(stdout, stderr io.Writer, rootFS fs.FS, files ...string) error {
+func checkShare(ctx *context, files ...string) error {
 
 	if len(files) < 1 {
 		return failNoneRequested
 	}
 
 	// Read the license graph from the license metadata files (*.meta_lic).
-	licenseGraph, err := compliance.ReadLicenseGraph(rootFS, stderr, files)
+	licenseGraph, err := compliance.ReadLicenseGraph(ctx.rootFS, ctx.stderr, files)
 	if err != nil {
 		return fmt.Errorf("Unable to read license metadata file(s) %q from %q: %w\n", files, os.Getenv("PWD"), err)
 	}
@@ -161,18 +232,65 @@ func checkShare(stdout, stderr io.Writer, rootFS fs.FS, files ...string) error {
 		return failNoLicenses
 	}
 
-	// Apply policy to find conflicts and report them to stderr lexicographically ordered.
+	// Apply policy to find conflicts.
 	conflicts := compliance.ConflictingSharedPrivateSource(licenseGraph)
 	sort.Sort(byError(conflicts))
+
+	if ctx.baseline == nil && ctx.baselineOut == nil {
+		// Report conflicts to stderr lexicographically ordered.
+		for _, conflict := range conflicts {
+			fmt.Fprintln(ctx.stderr, conflict.Error())
+		}
+
+		// Indicate pass or fail on stdout.
+		if len(conflicts) > 0 {
+			fmt.Fprintln(ctx.stdout, "FAIL")
+			return failConflicts
+		}
+		fmt.Fprintln(ctx.stdout, "PASS")
+		return nil
+	}
+
+	entries := compliance.BaselineConflicts(conflicts)
+	if ctx.restricted {
+		rs := compliance.WalkResolutionsForCondition(licenseGraph, compliance.ImpliesRestricted)
+		entries = append(entries, compliance.BaselineResolutions(rs)...)
+	}
+
+	// Record the current findings.
+	if ctx.baselineOut != nil {
+		err = compliance.WriteBaseline(ctx.baselineOut, entries)
+		if err != nil {
+			return fmt.Errorf("Unable to write baseline: %w\n", err)
+		}
+		if ctx.baseline == nil {
+			fmt.Fprintln(ctx.stdout, "PASS")
+			return nil
+		}
+	}
+
+	// Report findings not in the baseline, and baseline entries no longer found.
+	added, removed := ctx.baseline.Compare(entries)
+	newConflicts := compliance.NewBaseline(added...)
 	for _, conflict := range conflicts {
-		fmt.Fprintln(stderr, conflict.Error())
+		if _, ok := newConflicts[compliance.BaselineConflict(conflict)]; ok {
+			fmt.Fprintln(ctx.stderr, conflict.Error())
+		}
+	}
+	for _, entry := range added {
+		if strings.HasPrefix(entry, compliance.BaselineResolutionEntry+" ") {
+			fmt.Fprintf(ctx.stderr, "new finding not in baseline: %s\n", entry)
+		}
+	}
+	for _, entry := range removed {
+		fmt.Fprintf(ctx.stderr, "baseline entry no longer found, remove it: %s\n", entry)
 	}
 
 	// Indicate pass or fail on stdout.
-	if len(conflicts) > 0 {
-		fmt.Fprintln(stdout, "FAIL")
+	if len(added) > 0 {
+		fmt.Fprintln(ctx.stdout, "FAIL")
 		return failConflicts
 	}
-	fmt.Fprintln(stdout, "PASS")
+	fmt.Fprintln(ctx.stdout, "PASS")
 	return nil
 }
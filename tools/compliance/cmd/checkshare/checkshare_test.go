@@ -16,12 +16,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
 
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 func TestMain(m *testing.M) {
@@ -304,7 +306,8 @@ func Test(t *testing.T) {
 			for _, r := range tt.roots {
 				rootFiles = append(rootFiles, "testdata/"+tt.condition+"/"+r)
 			}
-			err := checkShare(stdout, stderr, compliance.GetFS(tt.outDir), rootFiles...)
+			ctx := &context{stdout: stdout, stderr: stderr, rootFS: compliance.GetFS(tt.outDir)}
+			err := checkShare(ctx, rootFiles...)
 			if err != nil && err != failConflicts {
 				t.Fatalf("checkshare: error = %v, stderr = %v", err, stderr)
 				return
@@ -351,3 +354,157 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func Test_trace(t *testing.T) {
+	tracer := tracing.Enable("checkshare")
+	defer tracing.Disable()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout: stdout, stderr: stderr, rootFS: compliance.GetFS("")}
+
+	err := checkShare(ctx, "testdata/restricted/highest.apex.meta_lic")
+	if err != nil && err != failConflicts {
+		t.Fatalf("checkshare: error = %v, stderr = %v", err, stderr)
+	}
+
+	var buf bytes.Buffer
+	if err := tracer.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: error = %v", err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+			Ph   string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("json.Unmarshal: error = %v", err)
+	}
+	found := make(map[string]bool)
+	for _, e := range trace.TraceEvents {
+		found[e.Ph+" "+e.Name] = true
+	}
+	for _, name := range []string{
+		"X ReadLicenseGraph",
+		"X read meta_lic",
+		"X ResolveBottomUpConditions",
+		"X ResolveTopDownConditions",
+		"C meta_lic_files",
+		"C meta_lic_bytes",
+	} {
+		if !found[name] {
+			t.Errorf("trace: missing event %q", name)
+		}
+	}
+	if n := tracer.Counter("meta_lic_files"); n != 7 {
+		t.Errorf("meta_lic_files: got %d, want 7", n)
+	}
+}
+
+func Test_baseline(t *testing.T) {
+	const conflict = "conflict testdata/proprietary/bin/bin2.meta_lic proprietary restricted"
+	const stale = "conflict testdata/proprietary/bin/bin1.meta_lic proprietary restricted"
+	tests := []struct {
+		name             string
+		baseline         []string
+		restricted       bool
+		writeBaseline    bool
+		expectedStdout   string
+		expectedStderr   []string
+		expectedBaseline []string
+	}{
+		{
+			name:             "write",
+			writeBaseline:    true,
+			expectedStdout:   "PASS",
+			expectedBaseline: []string{conflict},
+		},
+		{
+			name:           "known",
+			baseline:       []string{conflict},
+			expectedStdout: "PASS",
+		},
+		{
+			name:           "stale",
+			baseline:       []string{conflict, stale},
+			expectedStdout: "PASS",
+			expectedStderr: []string{"baseline entry no longer found, remove it: " + stale},
+		},
+		{
+			name:           "new",
+			baseline:       []string{},
+			expectedStdout: "FAIL",
+			expectedStderr: []string{"testdata/proprietary/bin/bin2.meta_lic proprietary and must share from restricted condition"},
+		},
+		{
+			name:           "newrestricted",
+			baseline:       []string{conflict},
+			restricted:     true,
+			expectedStdout: "FAIL",
+			expectedStderr: []string{
+				"new finding not in baseline: resolution testdata/proprietary/bin/bin2.meta_lic testdata/proprietary/bin/bin2.meta_lic restricted",
+				"new finding not in baseline: resolution testdata/proprietary/bin/bin2.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+				"new finding not in baseline: resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/bin/bin2.meta_lic restricted",
+				"new finding not in baseline: resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/highest.apex.meta_lic restricted",
+				"new finding not in baseline: resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+				"new finding not in baseline: resolution testdata/proprietary/lib/libb.so.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+			},
+		},
+		{
+			name:           "writerestricted",
+			restricted:     true,
+			writeBaseline:  true,
+			expectedStdout: "PASS",
+			expectedBaseline: []string{
+				conflict,
+				"resolution testdata/proprietary/bin/bin2.meta_lic testdata/proprietary/bin/bin2.meta_lic restricted",
+				"resolution testdata/proprietary/bin/bin2.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+				"resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/bin/bin2.meta_lic restricted",
+				"resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/highest.apex.meta_lic restricted",
+				"resolution testdata/proprietary/highest.apex.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+				"resolution testdata/proprietary/lib/libb.so.meta_lic testdata/proprietary/lib/libb.so.meta_lic restricted",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			baselineOut := &bytes.Buffer{}
+
+			ctx := &context{stdout: stdout, stderr: stderr, rootFS: compliance.GetFS(""), restricted: tt.restricted}
+			if tt.baseline != nil {
+				ctx.baseline = compliance.NewBaseline(tt.baseline...)
+			}
+			if tt.writeBaseline {
+				ctx.baselineOut = baselineOut
+			}
+			err := checkShare(ctx, "testdata/proprietary/highest.apex.meta_lic")
+			if err != nil && err != failConflicts {
+				t.Fatalf("checkshare: error = %v, stderr = %v", err, stderr)
+			}
+			if actual := strings.TrimSpace(stdout.String()); actual != tt.expectedStdout {
+				t.Errorf("checkshare: unexpected stdout %q, want %q", actual, tt.expectedStdout)
+			}
+			var actualStderr []string
+			for _, line := range strings.Split(stderr.String(), "\n") {
+				if len(strings.TrimSpace(line)) > 0 {
+					actualStderr = append(actualStderr, line)
+				}
+			}
+			if strings.Join(actualStderr, "\n") != strings.Join(tt.expectedStderr, "\n") {
+				t.Errorf("checkshare: unexpected stderr %q, want %q", actualStderr, tt.expectedStderr)
+			}
+			if tt.writeBaseline {
+				b, err := compliance.ReadBaseline(baselineOut)
+				if err != nil {
+					t.Fatalf("checkshare: baseline error = %v", err)
+				}
+				if actual := strings.Join(b.Entries(), "\n"); actual != strings.Join(tt.expectedBaseline, "\n") {
+					t.Errorf("checkshare: unexpected baseline %q, want %q", b.Entries(), tt.expectedBaseline)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/response"
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/starlarkpolicy"
+	"android/soong/tools/compliance/tracing"
+)
+
+var (
+	failFindings      = fmt.Errorf("policy findings")
+	failNoneRequested = fmt.Errorf("\nNo license metadata files requested")
+	failNoPolicies    = fmt.Errorf("\nNo policy files requested")
+	failNoLicenses    = fmt.Errorf("No licenses found")
+)
+
+type context struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	rootFS     fs.FS
+	policies   []string
+	jsonOutput bool
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func main() {
+	var expandedArgs []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			f, err := os.Open(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			respArgs, err := response.ReadRspFile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			expandedArgs = append(expandedArgs, respArgs...)
+		} else {
+			expandedArgs = append(expandedArgs, arg)
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} -policy policy.star {-policy policy.star...} file.meta_lic {file.meta_lic...}
+
+Evaluates Starlark policy files over the license graph rooted at the
+given license metadata files, and reports the findings on stderr.
+
+Each policy file must define a check(graph) function returning a list
+of findings created by the finding(rule, message, target=None,
+severity="error") builtin. The graph exposes the targets, edges,
+shipped targets, and resolutions of the license graph.
+
+In text mode, reports each finding on its own line as:
+
+  severity: policy: rule[: target]: message
+
+In json mode, reports a json array of findings.
+
+If no policy reports a finding with severity "error", outputs "PASS" to
+stdout and exits with status 0.
+
+If any policy reports a finding with severity "error", outputs "FAIL"
+to stdout and exits with status 1.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	outputFile := flags.String("o", "-", "Where to write the output. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	policies := newMultiString(flags, "policy", "Path to a Starlark policy file. (multiple allowed)")
+	jsonOutput := flags.Bool("json", false, "Whether to report findings as json.")
+
+	flags.Parse(expandedArgs)
+
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	// Must specify at least one root target.
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if len(*outputFile) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "must specify file for -o; use - for stdout\n")
+		os.Exit(2)
+	} else {
+		dir, err := filepath.Abs(filepath.Dir(*outputFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot determine path to %q: %s\n", *outputFile, err)
+			os.Exit(1)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read directory %q of %q: %s\n", dir, *outputFile, err)
+			os.Exit(1)
+		}
+		if !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "parent %q of %q is not a directory\n", dir, *outputFile)
+			os.Exit(1)
+		}
+	}
+
+	var ofile io.Writer
+	ofile = os.Stdout
+	var obuf *bytes.Buffer
+	if *outputFile != "-" {
+		obuf = &bytes.Buffer{}
+		ofile = obuf
+	}
+
+	ctx := &context{ofile, os.Stderr, compliance.FS, *policies, *jsonOutput}
+
+	err := complianceCheck(ctx, flags.Args()...)
+	if err != nil && err != failFindings {
+		if err == failNoneRequested || err == failNoPolicies {
+			flags.Usage()
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
+		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// complianceCheck implements the compliancecheck utility.
+func complianceCheck(ctx *context, files ...string) error {
+	if len(files) < 1 {
+		return failNoneRequested
+	}
+	if len(ctx.policies) < 1 {
+		return failNoPolicies
+	}
+
+	// Read the license graph from the license metadata files (*.meta_lic).
+	licenseGraph, err := compliance.ReadLicenseGraph(ctx.rootFS, ctx.stderr, files)
+	if err != nil {
+		return fmt.Errorf("Unable to read license metadata file(s) %q from %q: %w\n", files, os.Getenv("PWD"), err)
+	}
+	if licenseGraph == nil {
+		return failNoLicenses
+	}
+
+	checker := starlarkpolicy.NewChecker(licenseGraph, projectmetadata.NewIndex(ctx.rootFS), ctx.stderr)
+
+	findings := make(starlarkpolicy.FindingList, 0)
+	for _, policy := range ctx.policies {
+		f, err := checker.Check(policy, nil)
+		if err != nil {
+			return fmt.Errorf("Unable to check policy %q: %w", policy, err)
+		}
+		findings = append(findings, f...)
+	}
+	sort.Sort(findings)
+
+	// Report the findings to stderr.
+	if ctx.jsonOutput {
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to marshal findings: %w", err)
+		}
+		fmt.Fprintln(ctx.stderr, string(data))
+	} else {
+		for _, f := range findings {
+			fmt.Fprintln(ctx.stderr, f.String())
+		}
+	}
+
+	// Indicate pass or fail on stdout.
+	for _, f := range findings {
+		if f.Severity == starlarkpolicy.Severities[0] {
+			fmt.Fprintln(ctx.stdout, "FAIL")
+			return failFindings
+		}
+	}
+	fmt.Fprintln(ctx.stdout, "PASS")
+	return nil
+}
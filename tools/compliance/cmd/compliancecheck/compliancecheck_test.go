@@ -0,0 +1,189 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/starlarkpolicy"
+)
+
+func TestMain(m *testing.M) {
+	// Change into the parent directory before running the tests
+	// so they can find the testdata directory.
+	if err := os.Chdir(".."); err != nil {
+		fmt.Printf("failed to change to testdata directory: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+var testPolicies = []string{
+	"testdata/policy/dynamic.star",
+	"testdata/policy/restricted.star",
+}
+
+func Test(t *testing.T) {
+	tests := []struct {
+		condition        string
+		name             string
+		outDir           string
+		roots            []string
+		expectedStdout   string
+		expectedFindings []string
+	}{
+		{
+			condition:      "firstparty",
+			name:           "apex",
+			roots:          []string{"highest.apex.meta_lic"},
+			expectedStdout: "PASS",
+		},
+		{
+			condition:      "notice",
+			name:           "container",
+			roots:          []string{"container.zip.meta_lic"},
+			expectedStdout: "PASS",
+		},
+		{
+			condition:      "reciprocal",
+			name:           "application",
+			roots:          []string{"application.meta_lic"},
+			expectedStdout: "PASS",
+		},
+		{
+			condition:      "restricted",
+			name:           "apex",
+			roots:          []string{"highest.apex.meta_lic"},
+			expectedStdout: "FAIL",
+			expectedFindings: []string{
+				"warning: testdata/policy/dynamic.star: dynamic: testdata/restricted/bin/bin2.meta_lic: dynamically links restricted testdata/restricted/lib/libb.so.meta_lic",
+				"error: testdata/policy/restricted.star: no-restricted: testdata/restricted/lib/liba.so.meta_lic: ships restricted_if_statically_linked code",
+				"error: testdata/policy/restricted.star: no-restricted: testdata/restricted/lib/libb.so.meta_lic: ships restricted code",
+			},
+		},
+		{
+			condition:      "restricted",
+			name:           "application",
+			roots:          []string{"application.meta_lic"},
+			expectedStdout: "FAIL",
+			expectedFindings: []string{
+				"warning: testdata/policy/dynamic.star: dynamic: testdata/restricted/application.meta_lic: dynamically links restricted testdata/restricted/lib/libb.so.meta_lic",
+				"error: testdata/policy/restricted.star: no-restricted: testdata/restricted/lib/liba.so.meta_lic: ships restricted_if_statically_linked code",
+			},
+		},
+		{
+			condition:      "restricted",
+			name:           "library",
+			roots:          []string{"lib/libd.so.meta_lic"},
+			expectedStdout: "PASS",
+		},
+		{
+			condition:      "proprietary",
+			name:           "container",
+			roots:          []string{"container.zip.meta_lic"},
+			expectedStdout: "FAIL",
+			expectedFindings: []string{
+				"warning: testdata/policy/dynamic.star: dynamic: testdata/proprietary/bin/bin2.meta_lic: dynamically links restricted testdata/proprietary/lib/libb.so.meta_lic",
+				"error: testdata/policy/restricted.star: no-restricted: testdata/proprietary/lib/libb.so.meta_lic: ships restricted code",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			rootFiles := make([]string, 0, len(tt.roots))
+			for _, r := range tt.roots {
+				rootFiles = append(rootFiles, "testdata/"+tt.condition+"/"+r)
+			}
+			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), testPolicies, false}
+			err := complianceCheck(&ctx, rootFiles...)
+			if err != nil && err != failFindings {
+				t.Fatalf("compliancecheck: error = %v, stderr = %v", err, stderr)
+				return
+			}
+			actualStdout := strings.TrimSpace(stdout.String())
+			if actualStdout != tt.expectedStdout {
+				t.Errorf("compliancecheck: unexpected stdout %q, want %q", actualStdout, tt.expectedStdout)
+			}
+			if (err == failFindings) != (tt.expectedStdout == "FAIL") {
+				t.Errorf("compliancecheck: unexpected error %v for stdout %q", err, tt.expectedStdout)
+			}
+			actualFindings := make([]string, 0)
+			for _, line := range strings.Split(stderr.String(), "\n") {
+				if len(strings.TrimSpace(line)) > 0 {
+					actualFindings = append(actualFindings, line)
+				}
+			}
+			if strings.Join(actualFindings, "\n") != strings.Join(tt.expectedFindings, "\n") {
+				t.Errorf("compliancecheck: unexpected findings:\ngot:\n%s\nwant:\n%s",
+					strings.Join(actualFindings, "\n"), strings.Join(tt.expectedFindings, "\n"))
+			}
+		})
+	}
+}
+
+func TestJson(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	ctx := context{stdout, stderr, compliance.GetFS(""), testPolicies, true}
+	err := complianceCheck(&ctx, "testdata/restricted/application.meta_lic")
+	if err != failFindings {
+		t.Fatalf("compliancecheck: unexpected error %v, want %v", err, failFindings)
+	}
+	var findings starlarkpolicy.FindingList
+	if err := json.Unmarshal(stderr.Bytes(), &findings); err != nil {
+		t.Fatalf("compliancecheck: cannot parse json findings: %s\n%s", err, stderr)
+	}
+	expected := starlarkpolicy.FindingList{
+		{
+			Policy:   "testdata/policy/dynamic.star",
+			Rule:     "dynamic",
+			Severity: "warning",
+			Target:   "testdata/restricted/application.meta_lic",
+			Message:  "dynamically links restricted testdata/restricted/lib/libb.so.meta_lic",
+		},
+		{
+			Policy:   "testdata/policy/restricted.star",
+			Rule:     "no-restricted",
+			Severity: "error",
+			Target:   "testdata/restricted/lib/liba.so.meta_lic",
+			Message:  "ships restricted_if_statically_linked code",
+		},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("compliancecheck: unexpected findings %v, want %v", findings, expected)
+	}
+	for i := range findings {
+		if findings[i] != expected[i] {
+			t.Errorf("compliancecheck: unexpected finding #%d: got %v, want %v", i+1, findings[i], expected[i])
+		}
+	}
+}
+
+func TestNoPolicies(t *testing.T) {
+	ctx := context{&bytes.Buffer{}, &bytes.Buffer{}, compliance.GetFS(""), nil, false}
+	if err := complianceCheck(&ctx, "testdata/firstparty/application.meta_lic"); err != failNoPolicies {
+		t.Errorf("compliancecheck: unexpected error %v, want %v", err, failNoPolicies)
+	}
+}
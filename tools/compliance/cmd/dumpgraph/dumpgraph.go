@@ -27,6 +27,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -38,6 +39,10 @@ type context struct {
 	graphViz        bool
 	labelConditions bool
 	stripPrefix     []string
+	groupBy         compliance.GroupBy
+	graphML         bool
+	neo4jDir        string
+	resolutions     bool
 }
 
 func (ctx context) strip(installPath string) string {
@@ -102,6 +107,20 @@ e.g. multiple annotations appear as annotation1:annotation2:annotation3
 or when -label_conditions is requested, Target and Dependency become
 target:condition1:condition2 etc.
 
+When -group_by is given, condenses the graph so each node is a project
+or a package name instead of a license metadata file. The conditions of
+each node are the union of the conditions of its targets, and the
+annotations of each edge are the union of the annotations of the edges
+between targets in different nodes.
+
+When -graphml flag given, outputs the graph as a GraphML document for
+graph tools. When -neo4j_dir flag given, writes nodes.csv and edges.csv
+in the given directory for "neo4j-admin database import". Exported
+nodes carry conditions, license kinds, projects and install paths, and
+exported edges carry annotations. When -resolutions flag also given,
+exported graphs include a RESOLVES edge from each target a resolution
+attaches to, to each target it acts on, with the resolved conditions.
+
 Options:
 `, filepath.Base(os.Args[0]))
 		flags.PrintDefaults()
@@ -110,10 +129,58 @@ Options:
 	graphViz := flags.Bool("dot", false, "Whether to output graphviz (i.e. dot) format.")
 	labelConditions := flags.Bool("label_conditions", false, "Whether to label target nodes with conditions.")
 	outputFile := flags.String("o", "-", "Where to write the output. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
 	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+	groupBy := flags.String("group_by", "", "Condense the graph by project or package. (default no grouping)")
+	graphML := flags.Bool("graphml", false, "Whether to output GraphML format.")
+	neo4jDir := flags.String("neo4j_dir", "", "Directory in which to write neo4j admin import nodes.csv and edges.csv.")
+	resolutions := flags.Bool("resolutions", false, "Whether to add resolution edges to -graphml or -neo4j_dir output.")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	var gb compliance.GroupBy
+	if len(*groupBy) > 0 {
+		var err error
+		gb, err = compliance.ParseGroupBy(*groupBy)
+		if err != nil {
+			flags.Usage()
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	exporting := *graphML || len(*neo4jDir) > 0
+	if exporting && (*graphViz || len(*groupBy) > 0) {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "-graphml and -neo4j_dir cannot be combined with -dot or -group_by\n")
+		os.Exit(2)
+	}
+	if *graphML && len(*neo4jDir) > 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "-graphml and -neo4j_dir cannot be combined\n")
+		os.Exit(2)
+	}
+	if *resolutions && !exporting {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "-resolutions requires -graphml or -neo4j_dir\n")
+		os.Exit(2)
+	}
+	if len(*neo4jDir) > 0 {
+		fi, err := os.Stat(*neo4jDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read directory %q: %s\n", *neo4jDir, err)
+			os.Exit(1)
+		}
+		if !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "%q is not a directory\n", *neo4jDir)
+			os.Exit(1)
+		}
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -149,7 +216,7 @@ Options:
 		ofile = obuf
 	}
 
-	ctx := &context{*graphViz, *labelConditions, *stripPrefix}
+	ctx := &context{*graphViz, *labelConditions, *stripPrefix, gb, *graphML, *neo4jDir, *resolutions}
 
 	err := dumpGraph(ctx, ofile, os.Stderr, compliance.FS, flags.Args()...)
 	if err != nil {
@@ -160,12 +227,18 @@ Options:
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -184,6 +257,15 @@ func dumpGraph(ctx *context, stdout, stderr io.Writer, rootFS fs.FS, files ...st
 		return failNoLicenses
 	}
 
+	if ctx.graphML || len(ctx.neo4jDir) > 0 {
+		return exportGraph(ctx, stdout, licenseGraph)
+	}
+
+	if ctx.groupBy != 0 {
+		dumpCondensedGraph(ctx, stdout, compliance.CondenseGraph(licenseGraph, ctx.groupBy))
+		return nil
+	}
+
 	// Sort the edges of the graph.
 	edges := licenseGraph.Edges()
 	sort.Sort(edges)
@@ -264,3 +346,84 @@ func dumpGraph(ctx *context, stdout, stderr io.Writer, rootFS fs.FS, files ...st
 	}
 	return nil
 }
+
+// dumpCondensedGraph outputs the condensed graph `cg` in the same formats as dumpGraph.
+func dumpCondensedGraph(ctx *context, stdout io.Writer, cg *compliance.CondensedGraph) {
+	// nodes maps group names to graphViz node names when ctx.graphViz is true.
+	nodes := make(map[string]string)
+
+	// groupOut calculates the string to output for `cn` separating conditions as needed using `sep`.
+	groupOut := func(cn *compliance.CondensedNode, sep string) string {
+		gOut := ctx.strip(cn.Name())
+		if ctx.labelConditions {
+			conditions := cn.LicenseConditions().Names()
+			sort.Strings(conditions)
+			if len(conditions) > 0 {
+				gOut += sep + strings.Join(conditions, sep)
+			}
+		}
+		return gOut
+	}
+
+	// If graphviz output, map groups to node names, and start the directed graph.
+	if ctx.graphViz {
+		fmt.Fprintf(stdout, "strict digraph {\n\trankdir=RL;\n")
+		for i, cn := range cg.Nodes() {
+			nodeName := fmt.Sprintf("n%d", i)
+			nodes[cn.Name()] = nodeName
+			fmt.Fprintf(stdout, "\t%s [label=\"%s\"];\n", nodeName, groupOut(cn, "\\n"))
+		}
+	}
+
+	// Print the sorted edges to stdout ...
+	for _, e := range cg.Edges() {
+		// sort the annotations for repeatability/stability
+		annotations := e.Annotations().AsList()
+		sort.Strings(annotations)
+
+		if ctx.graphViz {
+			// ... one edge per line labelled with \\n-separated annotations.
+			fmt.Fprintf(stdout, "\t%s -> %s [label=\"%s\"];\n", nodes[e.Dependency().Name()], nodes[e.Target().Name()], strings.Join(annotations, "\\n"))
+		} else {
+			// ... one edge per line with annotations in a colon-separated tuple.
+			fmt.Fprintf(stdout, "%s %s %s\n", groupOut(e.Target(), ":"), groupOut(e.Dependency(), ":"), strings.Join(annotations, ":"))
+		}
+	}
+
+	// If graphViz output, rank the root nodes together, and complete the directed graph.
+	if ctx.graphViz {
+		fmt.Fprintf(stdout, "\t{rank=same;")
+		for _, cn := range cg.Roots() {
+			fmt.Fprintf(stdout, " %s", nodes[cn.Name()])
+		}
+		fmt.Fprintf(stdout, "}\n}\n")
+	}
+}
+
+// exportGraph outputs `lg` as GraphML to `stdout` or as neo4j admin import
+// files in ctx.neo4jDir.
+func exportGraph(ctx *context, stdout io.Writer, lg *compliance.LicenseGraph) error {
+	opts := compliance.ExportOptions{StripPrefix: ctx.stripPrefix}
+	if ctx.resolutions {
+		compliance.ResolveTopDownConditions(lg)
+		opts.Resolutions = compliance.WalkResolutionsForCondition(lg, compliance.AllLicenseConditions)
+	}
+
+	if ctx.graphML {
+		return compliance.WriteGraphML(stdout, lg, opts)
+	}
+
+	var nodes, edges bytes.Buffer
+	err := compliance.WriteNeo4jCSV(&nodes, &edges, lg, opts)
+	if err != nil {
+		return err
+	}
+	for name, buf := range map[string]*bytes.Buffer{"nodes.csv": &nodes, "edges.csv": &edges} {
+		path := filepath.Join(ctx.neo4jDir, name)
+		err = os.WriteFile(path, buf.Bytes(), 0666)
+		if err != nil {
+			return fmt.Errorf("could not write %q: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -478,6 +479,32 @@ func Test_plaintext(t *testing.T) {
 			roots:       []string{"lib/libd.so.meta_lic"},
 			expectedOut: []string{},
 		},
+		{
+			condition: "restricted",
+			name:      "apex_by_project",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByProject, labelConditions: true},
+			expectedOut: []string{
+				"dynamic/binary:notice base/library:restricted dynamic",
+				"dynamic/binary:notice dynamic/library:notice dynamic",
+				"highest/apex:notice base/library:restricted static",
+				"highest/apex:notice device/library:restricted_if_statically_linked static",
+				"highest/apex:notice dynamic/binary:notice static",
+				"highest/apex:notice static/binary:notice static",
+				"static/binary:notice device/library:restricted_if_statically_linked static",
+				"static/binary:notice static/library:reciprocal static",
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "apex_by_package",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByPackage},
+			expectedOut: []string{
+				"Android Device static",
+				"Android External dynamic:static",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
@@ -1201,6 +1228,42 @@ func Test_graphviz(t *testing.T) {
 			roots:       []string{"lib/libd.so.meta_lic"},
 			expectedOut: []getMatcher{matchTarget("testdata/proprietary/lib/libd.so.meta_lic")},
 		},
+		{
+			condition: "restricted",
+			name:      "apex_by_project",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByProject, labelConditions: true},
+			expectedOut: []getMatcher{
+				matchTarget("base/library", "restricted"),
+				matchTarget("device/library", "restricted_if_statically_linked"),
+				matchTarget("dynamic/binary", "notice"),
+				matchTarget("dynamic/library", "notice"),
+				matchTarget("highest/apex", "notice"),
+				matchTarget("static/binary", "notice"),
+				matchTarget("static/library", "reciprocal"),
+				matchEdge("dynamic/binary", "base/library", "dynamic"),
+				matchEdge("dynamic/binary", "dynamic/library", "dynamic"),
+				matchEdge("highest/apex", "base/library", "static"),
+				matchEdge("highest/apex", "device/library", "static"),
+				matchEdge("highest/apex", "dynamic/binary", "static"),
+				matchEdge("highest/apex", "static/binary", "static"),
+				matchEdge("static/binary", "device/library", "static"),
+				matchEdge("static/binary", "static/library", "static"),
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "apex_by_package",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByPackage},
+			expectedOut: []getMatcher{
+				matchTarget("Android"),
+				matchTarget("Device"),
+				matchTarget("External"),
+				matchEdge("Android", "Device", "static"),
+				matchEdge("Android", "External", "dynamic", "static"),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
@@ -1271,3 +1334,97 @@ func Test_graphviz(t *testing.T) {
 		})
 	}
 }
+
+func Test_export(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context
+		expectedOut   []string
+		expectedNodes []string
+		expectedEdges []string
+	}{
+		{
+			name: "graphml",
+			ctx:  context{graphML: true, stripPrefix: []string{"testdata/restricted/"}},
+			expectedOut: []string{
+				`<node id="application.meta_lic">`,
+				`<data key="projects">device/library</data>`,
+				`<edge source="application.meta_lic" target="lib/liba.so.meta_lic">`,
+				`<data key="annotations">static</data>`,
+			},
+		},
+		{
+			name: "graphml_resolutions",
+			ctx:  context{graphML: true, resolutions: true, stripPrefix: []string{"testdata/restricted/"}},
+			expectedOut: []string{
+				`<data key="type">RESOLVES</data>`,
+				`<data key="resolves">restricted;restricted_if_statically_linked</data>`,
+			},
+		},
+		{
+			name: "neo4j",
+			ctx:  context{stripPrefix: []string{"testdata/restricted/"}},
+			expectedNodes: []string{
+				"name:ID,:LABEL,package_name,conditions:string[],license_kinds:string[],projects:string[],installed:string[]",
+				"application.meta_lic,Target,Android,notice,SPDX-license-identifier-Apache-2.0,distributable/application,out/target/product/fictional/bin/application",
+				"bin/bin3.meta_lic,Target,Compiler,restricted_if_statically_linked,SPDX-license-identifier-LGPL-2.0,standalone/binary,out/target/product/fictional/system/bin/bin3",
+				"lib/liba.so.meta_lic,Target,Device,restricted_if_statically_linked,SPDX-license-identifier-LGPL-2.0,device/library,out/target/product/fictional/system/lib/liba.so",
+				"lib/libb.so.meta_lic,Target,Android,restricted,SPDX-license-identifier-GPL-2.0,base/library,out/target/product/fictional/system/lib/libb.so",
+			},
+			expectedEdges: []string{
+				":START_ID,:END_ID,:TYPE,annotations:string[],resolves:string[]",
+				"application.meta_lic,bin/bin3.meta_lic,DEPENDS_ON,toolchain,",
+				"application.meta_lic,lib/liba.so.meta_lic,DEPENDS_ON,static,",
+				"application.meta_lic,lib/libb.so.meta_lic,DEPENDS_ON,dynamic,",
+			},
+		},
+		{
+			name: "neo4j_resolutions",
+			ctx:  context{resolutions: true, stripPrefix: []string{"testdata/restricted/"}},
+			expectedEdges: []string{
+				":START_ID,:END_ID,:TYPE,annotations:string[],resolves:string[]",
+				"application.meta_lic,bin/bin3.meta_lic,DEPENDS_ON,toolchain,",
+				"application.meta_lic,lib/liba.so.meta_lic,DEPENDS_ON,static,",
+				"application.meta_lic,lib/libb.so.meta_lic,DEPENDS_ON,dynamic,",
+				"application.meta_lic,application.meta_lic,RESOLVES,,notice;restricted;restricted_if_statically_linked",
+				"application.meta_lic,lib/liba.so.meta_lic,RESOLVES,,restricted;restricted_if_statically_linked",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ctx.graphML {
+				tt.ctx.neo4jDir = t.TempDir()
+			}
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			err := dumpGraph(&tt.ctx, stdout, stderr, compliance.GetFS(""), "testdata/restricted/application.meta_lic")
+			if err != nil {
+				t.Fatalf("dumpgraph: error = %v, stderr = %v", err, stderr)
+			}
+			if stderr.Len() > 0 {
+				t.Errorf("dumpgraph: gotStderr = %v, want none", stderr)
+			}
+			for _, eo := range tt.expectedOut {
+				if !strings.Contains(stdout.String(), eo) {
+					t.Errorf("dumpgraph: missing %q in stdout %s", eo, stdout)
+				}
+			}
+			checkFile := func(name string, expected []string) {
+				if expected == nil {
+					return
+				}
+				b, err := os.ReadFile(filepath.Join(tt.ctx.neo4jDir, name))
+				if err != nil {
+					t.Fatalf("dumpgraph: cannot read %s: %s", name, err)
+				}
+				actual := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+				if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+					t.Errorf("dumpgraph: unexpected %s: got %q, want %q", name, actual, expected)
+				}
+			}
+			checkFile("nodes.csv", tt.expectedNodes)
+			checkFile("edges.csv", tt.expectedEdges)
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -39,6 +40,7 @@ type context struct {
 	graphViz        bool
 	labelConditions bool
 	stripPrefix     []string
+	groupBy         compliance.GroupBy
 }
 
 func (ctx context) strip(installPath string) string {
@@ -106,6 +108,10 @@ In plain text mode, when '-label_conditions' is requested, the Target
 and Origin have colon-separated license conditions appended:
 i.e. target:condition1:condition2 etc.
 
+When -group_by is given, condenses the resolutions so each Target and
+Origin is a project or a package name instead of a license metadata
+file, and the conditions are the union of the conditions resolved.
+
 Options:
 `, filepath.Base(os.Args[0]))
 		flags.PrintDefaults()
@@ -115,10 +121,27 @@ Options:
 	graphViz := flags.Bool("dot", false, "Whether to output graphviz (i.e. dot) format.")
 	labelConditions := flags.Bool("label_conditions", false, "Whether to label target nodes with conditions.")
 	outputFile := flags.String("o", "-", "Where to write the output. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
 	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+	groupBy := flags.String("group_by", "", "Condense the resolutions by project or package. (default no grouping)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	var gb compliance.GroupBy
+	if len(*groupBy) > 0 {
+		var err error
+		gb, err = compliance.ParseGroupBy(*groupBy)
+		if err != nil {
+			flags.Usage()
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -163,6 +186,7 @@ Options:
 		graphViz:        *graphViz,
 		labelConditions: *labelConditions,
 		stripPrefix:     *stripPrefix,
+		groupBy:         gb,
 	}
 	_, err := dumpResolutions(ctx, ofile, os.Stderr, compliance.FS, flags.Args()...)
 	if err != nil {
@@ -173,12 +197,18 @@ Options:
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -208,6 +238,11 @@ func dumpResolutions(ctx *context, stdout, stderr io.Writer, rootFS fs.FS, files
 
 	resolutions := compliance.WalkResolutionsForCondition(licenseGraph, cs)
 
+	if ctx.groupBy != 0 {
+		dumpCondensedResolutions(ctx, stdout, compliance.CondenseGraph(licenseGraph, ctx.groupBy), resolutions)
+		return licenseGraph, nil
+	}
+
 	// nodes maps license metadata file names to graphViz node names when graphViz requested.
 	nodes := make(map[string]string)
 	n := 0
@@ -310,3 +345,63 @@ func dumpResolutions(ctx *context, stdout, stderr io.Writer, rootFS fs.FS, files
 	}
 	return licenseGraph, nil
 }
+
+// dumpCondensedResolutions outputs `resolutions` condensed into the groups of
+// `cg` in the same formats as dumpResolutions.
+func dumpCondensedResolutions(ctx *context, stdout io.Writer, cg *compliance.CondensedGraph, resolutions compliance.ResolutionSet) {
+	crl := cg.CondenseResolutions(resolutions)
+
+	// nodes maps group names to graphViz node names when graphViz requested.
+	nodes := make(map[string]string)
+
+	// groupOut calculates the string to output for `cn` adding `sep`-separated conditions as needed.
+	groupOut := func(cn *compliance.CondensedNode, sep string) string {
+		gOut := ctx.strip(cn.Name())
+		if ctx.labelConditions {
+			conditions := cn.LicenseConditions().Names()
+			if len(conditions) > 0 {
+				gOut += sep + strings.Join(conditions, sep)
+			}
+		}
+		return gOut
+	}
+
+	// makeNode maps `cn` to a graphViz node name.
+	makeNode := func(cn *compliance.CondensedNode) {
+		if _, ok := nodes[cn.Name()]; !ok {
+			nodeName := fmt.Sprintf("n%d", len(nodes))
+			nodes[cn.Name()] = nodeName
+			fmt.Fprintf(stdout, "\t%s [label=\"%s\"];\n", nodeName, groupOut(cn, "\\n"))
+		}
+	}
+
+	// If graphviz output, start the directed graph.
+	if ctx.graphViz {
+		fmt.Fprintf(stdout, "strict digraph {\n\trankdir=LR;\n")
+		for _, cr := range crl {
+			makeNode(cr.AttachesTo())
+			makeNode(cr.ActsOn())
+		}
+	}
+
+	// Output 1 line for each attachesTo+actsOn combination.
+	for _, cr := range crl {
+		cnames := cr.Resolves().Names()
+		if ctx.graphViz {
+			fmt.Fprintf(stdout, "\t%s -> %s [label=\"%s\"];\n", nodes[cr.AttachesTo().Name()], nodes[cr.ActsOn().Name()], strings.Join(cnames, "\\n"))
+		} else {
+			fmt.Fprintf(stdout, "%s %s %s\n", groupOut(cr.AttachesTo(), ":"), groupOut(cr.ActsOn(), ":"), strings.Join(cnames, ":"))
+		}
+	}
+
+	// If graphViz output, rank the root nodes together, and complete the directed graph.
+	if ctx.graphViz {
+		fmt.Fprintf(stdout, "\t{rank=same;")
+		for _, cn := range cg.Roots() {
+			if node, ok := nodes[cn.Name()]; ok {
+				fmt.Fprintf(stdout, " %s", node)
+			}
+		}
+		fmt.Fprintf(stdout, "}\n}\n")
+	}
+}
@@ -887,6 +887,155 @@ func Test_plaintext(t *testing.T) {
 				"testdata/proprietary/lib/libd.so.meta_lic testdata/proprietary/lib/libd.so.meta_lic notice",
 			},
 		},
+		{
+			condition: "networkuse",
+			name:      "apex",
+			roots:     []string{"highest.apex.meta_lic"},
+			expectedOut: []string{
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/bin/bin1.meta_lic notice:restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin2.meta_lic testdata/networkuse/bin/bin2.meta_lic notice:restricted:network_use",
+				"testdata/networkuse/bin/bin2.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/bin/bin1.meta_lic notice:restricted_if_statically_linked",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/bin/bin2.meta_lic notice:restricted:network_use",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/highest.apex.meta_lic notice:restricted:restricted_if_statically_linked:network_use",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+				"testdata/networkuse/highest.apex.meta_lic testdata/networkuse/lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"testdata/networkuse/lib/liba.so.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/lib/libb.so.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "apex_trimmed_network_use",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx: context{
+				conditions:  compliance.ImpliesNetworkUse.AsList(),
+				stripPrefix: []string{"testdata/networkuse/"},
+			},
+			expectedOut: []string{
+				"bin/bin2.meta_lic bin/bin2.meta_lic network_use",
+				"bin/bin2.meta_lic lib/libb.so.meta_lic network_use",
+				"highest.apex.meta_lic bin/bin2.meta_lic network_use",
+				"highest.apex.meta_lic highest.apex.meta_lic network_use",
+				"highest.apex.meta_lic lib/libb.so.meta_lic network_use",
+				"lib/libb.so.meta_lic lib/libb.so.meta_lic network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "library_trimmed_patent_retaliation",
+			roots:     []string{"lib/libd.so.meta_lic"},
+			ctx: context{
+				conditions:  compliance.ImpliesPatentRetaliation.AsList(),
+				stripPrefix: []string{"testdata/networkuse/"},
+			},
+			expectedOut: []string{
+				"lib/libd.so.meta_lic lib/libd.so.meta_lic patent_retaliation",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "apex_trimmed_share",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx: context{
+				conditions:  compliance.ImpliesShared.AsList(),
+				stripPrefix: []string{"testdata/networkuse/"},
+			},
+			expectedOut: []string{
+				"bin/bin1.meta_lic bin/bin1.meta_lic restricted_if_statically_linked",
+				"bin/bin1.meta_lic lib/liba.so.meta_lic restricted_if_statically_linked",
+				"bin/bin1.meta_lic lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"bin/bin2.meta_lic bin/bin2.meta_lic restricted:network_use",
+				"bin/bin2.meta_lic lib/libb.so.meta_lic restricted:network_use",
+				"highest.apex.meta_lic bin/bin1.meta_lic restricted_if_statically_linked",
+				"highest.apex.meta_lic bin/bin2.meta_lic restricted:network_use",
+				"highest.apex.meta_lic highest.apex.meta_lic restricted:restricted_if_statically_linked:network_use",
+				"highest.apex.meta_lic lib/liba.so.meta_lic restricted_if_statically_linked",
+				"highest.apex.meta_lic lib/libb.so.meta_lic restricted:network_use",
+				"highest.apex.meta_lic lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"lib/liba.so.meta_lic lib/liba.so.meta_lic restricted_if_statically_linked",
+				"lib/libb.so.meta_lic lib/libb.so.meta_lic restricted:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "apex_trimmed_labelled",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx:       context{stripPrefix: []string{"testdata/networkuse/"}, labelConditions: true},
+			expectedOut: []string{
+				"bin/bin1.meta_lic:notice bin/bin1.meta_lic:notice notice:restricted_if_statically_linked",
+				"bin/bin1.meta_lic:notice lib/liba.so.meta_lic:restricted_if_statically_linked restricted_if_statically_linked",
+				"bin/bin1.meta_lic:notice lib/libc.a.meta_lic:reciprocal reciprocal:restricted_if_statically_linked",
+				"bin/bin2.meta_lic:notice bin/bin2.meta_lic:notice notice:restricted:network_use",
+				"bin/bin2.meta_lic:notice lib/libb.so.meta_lic:restricted:network_use restricted:network_use",
+				"highest.apex.meta_lic:notice bin/bin1.meta_lic:notice notice:restricted_if_statically_linked",
+				"highest.apex.meta_lic:notice bin/bin2.meta_lic:notice notice:restricted:network_use",
+				"highest.apex.meta_lic:notice highest.apex.meta_lic:notice notice:restricted:restricted_if_statically_linked:network_use",
+				"highest.apex.meta_lic:notice lib/liba.so.meta_lic:restricted_if_statically_linked restricted_if_statically_linked",
+				"highest.apex.meta_lic:notice lib/libb.so.meta_lic:restricted:network_use restricted:network_use",
+				"highest.apex.meta_lic:notice lib/libc.a.meta_lic:reciprocal reciprocal:restricted_if_statically_linked",
+				"lib/liba.so.meta_lic:restricted_if_statically_linked lib/liba.so.meta_lic:restricted_if_statically_linked restricted_if_statically_linked",
+				"lib/libb.so.meta_lic:restricted:network_use lib/libb.so.meta_lic:restricted:network_use restricted:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "container",
+			roots:     []string{"container.zip.meta_lic"},
+			expectedOut: []string{
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/bin/bin1.meta_lic notice:restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin1.meta_lic testdata/networkuse/lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"testdata/networkuse/bin/bin2.meta_lic testdata/networkuse/bin/bin2.meta_lic notice:restricted:network_use",
+				"testdata/networkuse/bin/bin2.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/bin/bin1.meta_lic notice:restricted_if_statically_linked",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/bin/bin2.meta_lic notice:restricted:network_use",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/container.zip.meta_lic notice:restricted:restricted_if_statically_linked:network_use",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+				"testdata/networkuse/container.zip.meta_lic testdata/networkuse/lib/libc.a.meta_lic reciprocal:restricted_if_statically_linked",
+				"testdata/networkuse/lib/liba.so.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted_if_statically_linked",
+				"testdata/networkuse/lib/libb.so.meta_lic testdata/networkuse/lib/libb.so.meta_lic restricted:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "application",
+			roots:     []string{"application.meta_lic"},
+			expectedOut: []string{
+				"testdata/networkuse/application.meta_lic testdata/networkuse/application.meta_lic notice:restricted:restricted_if_statically_linked:network_use",
+				"testdata/networkuse/application.meta_lic testdata/networkuse/lib/liba.so.meta_lic restricted:restricted_if_statically_linked:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "binary",
+			roots:     []string{"bin/bin2.meta_lic"},
+			expectedOut: []string{
+				"testdata/networkuse/bin/bin2.meta_lic testdata/networkuse/bin/bin2.meta_lic notice:restricted:network_use",
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "library",
+			roots:     []string{"lib/libd.so.meta_lic"},
+			expectedOut: []string{
+				"testdata/networkuse/lib/libd.so.meta_lic testdata/networkuse/lib/libd.so.meta_lic notice:patent_retaliation",
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "application_by_project",
+			roots:     []string{"application.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByProject, labelConditions: true},
+			expectedOut: []string{
+				"distributable/application:notice device/library:restricted_if_statically_linked restricted:restricted_if_statically_linked",
+				"distributable/application:notice distributable/application:notice notice:restricted:restricted_if_statically_linked",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
@@ -3287,6 +3436,279 @@ func Test_graphviz(t *testing.T) {
 					"notice"),
 			},
 		},
+		{
+			condition: "networkuse",
+			name:      "apex",
+			roots:     []string{"highest.apex.meta_lic"},
+			expectedOut: []getMatcher{
+				matchTarget("testdata/networkuse/bin/bin1.meta_lic"),
+				matchTarget("testdata/networkuse/lib/liba.so.meta_lic"),
+				matchTarget("testdata/networkuse/lib/libc.a.meta_lic"),
+				matchTarget("testdata/networkuse/bin/bin2.meta_lic"),
+				matchTarget("testdata/networkuse/lib/libb.so.meta_lic"),
+				matchTarget("testdata/networkuse/highest.apex.meta_lic"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"notice",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/lib/libc.a.meta_lic",
+					"reciprocal",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"notice",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"notice",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"notice",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/highest.apex.meta_lic",
+					"notice",
+					"restricted",
+					"restricted_if_statically_linked",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/highest.apex.meta_lic",
+					"testdata/networkuse/lib/libc.a.meta_lic",
+					"reciprocal",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "apex_trimmed_network_use",
+			roots:     []string{"highest.apex.meta_lic"},
+			ctx: context{
+				conditions:  compliance.ImpliesNetworkUse.AsList(),
+				stripPrefix: []string{"testdata/networkuse/"},
+			},
+			expectedOut: []getMatcher{
+				matchTarget("bin/bin2.meta_lic"),
+				matchTarget("lib/libb.so.meta_lic"),
+				matchTarget("highest.apex.meta_lic"),
+				matchResolution(
+					"bin/bin2.meta_lic",
+					"bin/bin2.meta_lic",
+					"network_use"),
+				matchResolution(
+					"bin/bin2.meta_lic",
+					"lib/libb.so.meta_lic",
+					"network_use"),
+				matchResolution(
+					"highest.apex.meta_lic",
+					"bin/bin2.meta_lic",
+					"network_use"),
+				matchResolution(
+					"highest.apex.meta_lic",
+					"highest.apex.meta_lic",
+					"network_use"),
+				matchResolution(
+					"highest.apex.meta_lic",
+					"lib/libb.so.meta_lic",
+					"network_use"),
+				matchResolution(
+					"lib/libb.so.meta_lic",
+					"lib/libb.so.meta_lic",
+					"network_use"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "library_trimmed_patent_retaliation",
+			roots:     []string{"lib/libd.so.meta_lic"},
+			ctx: context{
+				conditions:  compliance.ImpliesPatentRetaliation.AsList(),
+				stripPrefix: []string{"testdata/networkuse/"},
+			},
+			expectedOut: []getMatcher{
+				matchTarget("lib/libd.so.meta_lic"),
+				matchResolution(
+					"lib/libd.so.meta_lic",
+					"lib/libd.so.meta_lic",
+					"patent_retaliation"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "container",
+			roots:     []string{"container.zip.meta_lic"},
+			expectedOut: []getMatcher{
+				matchTarget("testdata/networkuse/bin/bin1.meta_lic"),
+				matchTarget("testdata/networkuse/lib/liba.so.meta_lic"),
+				matchTarget("testdata/networkuse/lib/libc.a.meta_lic"),
+				matchTarget("testdata/networkuse/bin/bin2.meta_lic"),
+				matchTarget("testdata/networkuse/lib/libb.so.meta_lic"),
+				matchTarget("testdata/networkuse/container.zip.meta_lic"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"notice",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"testdata/networkuse/lib/libc.a.meta_lic",
+					"reciprocal",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"notice",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/bin/bin1.meta_lic",
+					"notice",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"notice",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/container.zip.meta_lic",
+					"notice",
+					"restricted",
+					"restricted_if_statically_linked",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/container.zip.meta_lic",
+					"testdata/networkuse/lib/libc.a.meta_lic",
+					"reciprocal",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted_if_statically_linked"),
+				matchResolution(
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"testdata/networkuse/lib/libb.so.meta_lic",
+					"restricted",
+					"network_use"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "application",
+			roots:     []string{"application.meta_lic"},
+			expectedOut: []getMatcher{
+				matchTarget("testdata/networkuse/application.meta_lic"),
+				matchTarget("testdata/networkuse/lib/liba.so.meta_lic"),
+				matchResolution(
+					"testdata/networkuse/application.meta_lic",
+					"testdata/networkuse/application.meta_lic",
+					"notice",
+					"restricted",
+					"restricted_if_statically_linked",
+					"network_use"),
+				matchResolution(
+					"testdata/networkuse/application.meta_lic",
+					"testdata/networkuse/lib/liba.so.meta_lic",
+					"restricted",
+					"restricted_if_statically_linked",
+					"network_use"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "binary",
+			roots:     []string{"bin/bin2.meta_lic"},
+			expectedOut: []getMatcher{
+				matchTarget("testdata/networkuse/bin/bin2.meta_lic"),
+				matchResolution(
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"testdata/networkuse/bin/bin2.meta_lic",
+					"notice",
+					"restricted",
+					"network_use"),
+			},
+		},
+		{
+			condition: "networkuse",
+			name:      "library",
+			roots:     []string{"lib/libd.so.meta_lic"},
+			expectedOut: []getMatcher{
+				matchTarget("testdata/networkuse/lib/libd.so.meta_lic"),
+				matchResolution(
+					"testdata/networkuse/lib/libd.so.meta_lic",
+					"testdata/networkuse/lib/libd.so.meta_lic",
+					"notice",
+					"patent_retaliation"),
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "application_by_package",
+			roots:     []string{"application.meta_lic"},
+			ctx:       context{groupBy: compliance.GroupByPackage},
+			expectedOut: []getMatcher{
+				matchTarget("Android"),
+				matchTarget("Device"),
+				matchResolution("Android", "Android", "notice", "restricted", "restricted_if_statically_linked"),
+				matchResolution("Android", "Device", "restricted", "restricted_if_statically_linked"),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
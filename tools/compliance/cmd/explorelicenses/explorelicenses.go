@@ -0,0 +1,353 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/response"
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
+)
+
+var (
+	failNoneRequested = fmt.Errorf("\nNo license metadata files requested")
+	failNoLicenses    = fmt.Errorf("No licenses found")
+)
+
+type context struct {
+	stdout      io.Writer
+	stderr      io.Writer
+	rootFS      fs.FS
+	stripPrefix []string
+	title       string
+}
+
+func (ctx context) strip(installPath string) string {
+	for _, prefix := range ctx.stripPrefix {
+		if strings.HasPrefix(installPath, prefix) {
+			p := strings.TrimPrefix(installPath, prefix)
+			if 0 == len(p) {
+				continue
+			}
+			return p
+		}
+	}
+	return installPath
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func main() {
+	var expandedArgs []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			f, err := os.Open(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			respArgs, err := response.ReadRspFile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			expandedArgs = append(expandedArgs, respArgs...)
+		} else {
+			expandedArgs = append(expandedArgs, arg)
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} file.meta_lic {file.meta_lic...}
+
+Outputs a self-contained html page for exploring the license graph
+rooted at the given license metadata files.
+
+The page embeds the targets, edges, and resolutions of the graph, and
+needs no network access or external files to view. It supports search
+by target or project, collapsing targets by project, filtering by
+license condition or edge annotation, and highlighting the resolutions
+for a chosen license condition as reported by dumpresolutions.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	outputFile := flags.String("o", "-", "Where to write the html page. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+	title := flags.String("title", "", "The title of the html page.")
+
+	flags.Parse(expandedArgs)
+
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	// Must specify at least one root target.
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if len(*outputFile) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "must specify file for -o; use - for stdout\n")
+		os.Exit(2)
+	} else {
+		dir, err := filepath.Abs(filepath.Dir(*outputFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot determine path to %q: %s\n", *outputFile, err)
+			os.Exit(1)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read directory %q of %q: %s\n", dir, *outputFile, err)
+			os.Exit(1)
+		}
+		if !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "parent %q of %q is not a directory\n", dir, *outputFile)
+			os.Exit(1)
+		}
+	}
+
+	var ofile io.Writer
+	ofile = os.Stdout
+	var obuf *bytes.Buffer
+	if *outputFile != "-" {
+		obuf = &bytes.Buffer{}
+		ofile = obuf
+	}
+
+	ctx := &context{ofile, os.Stderr, compliance.FS, *stripPrefix, *title}
+
+	err := exploreLicenses(ctx, flags.Args()...)
+	if err != nil {
+		if err == failNoneRequested {
+			flags.Usage()
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
+		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// graphData describes the license graph embedded in the page.
+type graphData struct {
+	Title       string           `json:"title"`
+	Roots       []int            `json:"roots"`
+	Conditions  []string         `json:"conditions"`
+	Annotations []string         `json:"annotations"`
+	Targets     []targetData     `json:"targets"`
+	Edges       []edgeData       `json:"edges"`
+	Resolutions []resolutionData `json:"resolutions"`
+}
+
+// targetData describes a target node by its attributes.
+type targetData struct {
+	Name        string   `json:"name"`
+	Package     string   `json:"package"`
+	Projects    []string `json:"projects"`
+	Kinds       []string `json:"kinds"`
+	Conditions  []string `json:"conditions"`
+	Installed   []string `json:"installed"`
+	IsContainer bool     `json:"container"`
+}
+
+// edgeData describes an edge by the indexes of its target nodes.
+type edgeData struct {
+	Target      int      `json:"target"`
+	Dependency  int      `json:"dependency"`
+	Annotations []string `json:"annotations"`
+}
+
+// resolutionData describes a resolution by the indexes of its target nodes.
+type resolutionData struct {
+	AttachesTo int      `json:"attachesTo"`
+	ActsOn     int      `json:"actsOn"`
+	Conditions []string `json:"conditions"`
+}
+
+// exploreLicenses implements the explorelicenses utility.
+func exploreLicenses(ctx *context, files ...string) error {
+	// Must be at least one root file.
+	if len(files) < 1 {
+		return failNoneRequested
+	}
+
+	// Read the license graph from the license metadata files (*.meta_lic).
+	licenseGraph, err := compliance.ReadLicenseGraph(ctx.rootFS, ctx.stderr, files)
+	if err != nil {
+		return fmt.Errorf("Unable to read license metadata file(s) %q: %v\n", files, err)
+	}
+	if licenseGraph == nil {
+		return failNoLicenses
+	}
+
+	data, err := collectGraphData(ctx, licenseGraph)
+	if err != nil {
+		return err
+	}
+
+	// json.Marshal escapes <, >, and & so the data cannot terminate the script element.
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal the license graph: %w", err)
+	}
+
+	title := ctx.title
+	if len(title) == 0 {
+		title = "License Graph"
+	}
+	fmt.Fprintln(ctx.stdout, "<!DOCTYPE html>")
+	fmt.Fprintln(ctx.stdout, "<html><head>")
+	fmt.Fprintln(ctx.stdout, "<meta charset=\"utf-8\">")
+	fmt.Fprintf(ctx.stdout, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintln(ctx.stdout, "<style type=\"text/css\">")
+	fmt.Fprint(ctx.stdout, explorerStyle)
+	fmt.Fprintln(ctx.stdout, "</style>")
+	fmt.Fprintln(ctx.stdout, "</head>")
+	fmt.Fprintln(ctx.stdout, "<body>")
+	fmt.Fprintf(ctx.stdout, "<h1>%s</h1>\n", html.EscapeString(title))
+	fmt.Fprint(ctx.stdout, explorerBody)
+	fmt.Fprintf(ctx.stdout, "<script type=\"application/json\" id=\"graph-data\">%s</script>\n", b)
+	fmt.Fprintln(ctx.stdout, "<script>")
+	fmt.Fprint(ctx.stdout, explorerScript)
+	fmt.Fprintln(ctx.stdout, "</script>")
+	fmt.Fprintln(ctx.stdout, "</body></html>")
+
+	return nil
+}
+
+// collectGraphData converts `lg` and its resolutions into the data embedded
+// in the page.
+func collectGraphData(ctx *context, lg *compliance.LicenseGraph) (*graphData, error) {
+	data := &graphData{
+		Title:       ctx.title,
+		Roots:       []int{},
+		Conditions:  compliance.AllLicenseConditions.Names(),
+		Annotations: []string{},
+		Targets:     []targetData{},
+		Edges:       []edgeData{},
+		Resolutions: []resolutionData{},
+	}
+	sort.Strings(data.Conditions)
+
+	targets := lg.Targets()
+	sort.Sort(targets)
+
+	// index maps target nodes to their position in data.Targets.
+	index := make(map[*compliance.TargetNode]int)
+	for i, tn := range targets {
+		index[tn] = i
+		installed := tn.Installed()
+		for j := range installed {
+			installed[j] = ctx.strip(installed[j])
+		}
+		data.Targets = append(data.Targets, targetData{
+			Name:        ctx.strip(tn.Name()),
+			Package:     tn.PackageName(),
+			Projects:    sortedStrings(tn.Projects()),
+			Kinds:       sortedStrings(tn.LicenseKinds()),
+			Conditions:  sortedStrings(tn.LicenseConditions().Names()),
+			Installed:   sortedStrings(installed),
+			IsContainer: tn.IsContainer(),
+		})
+	}
+
+	for _, tn := range lg.Roots() {
+		data.Roots = append(data.Roots, index[tn])
+	}
+	sort.Ints(data.Roots)
+
+	edges := lg.Edges()
+	sort.Sort(edges)
+	annotations := make(map[string]struct{})
+	for _, e := range edges {
+		ea := sortedStrings(e.Annotations().AsList())
+		for _, a := range ea {
+			annotations[a] = struct{}{}
+		}
+		data.Edges = append(data.Edges, edgeData{index[e.Target()], index[e.Dependency()], ea})
+	}
+	for a := range annotations {
+		data.Annotations = append(data.Annotations, a)
+	}
+	sort.Strings(data.Annotations)
+
+	compliance.ResolveTopDownConditions(lg)
+	rs := compliance.WalkResolutionsForCondition(lg, compliance.AllLicenseConditions)
+	attachesTo := rs.AttachesTo()
+	sort.Sort(attachesTo)
+	for _, tn := range attachesTo {
+		rl := rs.Resolutions(tn)
+		sort.Sort(rl)
+		for _, r := range rl {
+			ai, ok := index[r.ActsOn()]
+			if !ok {
+				return nil, fmt.Errorf("resolution acts on unknown target %q", r.ActsOn().Name())
+			}
+			data.Resolutions = append(data.Resolutions, resolutionData{index[tn], ai, sortedStrings(r.Resolves().Names())})
+		}
+	}
+	return data, nil
+}
+
+// sortedStrings returns `s` sorted and never nil.
+func sortedStrings(s []string) []string {
+	result := append([]string{}, s...)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"android/soong/tools/compliance"
+)
+
+var (
+	graphDataRe = regexp.MustCompile(`<script type="application/json" id="graph-data">(.*)</script>`)
+	externalRe  = regexp.MustCompile(`(?i)(\ssrc=|\shref=|@import|url\()`)
+)
+
+func TestMain(m *testing.M) {
+	// Change into the parent directory before running the tests
+	// so they can find the testdata directory.
+	if err := os.Chdir(".."); err != nil {
+		fmt.Printf("failed to change to testdata directory: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func Test(t *testing.T) {
+	tests := []struct {
+		condition           string
+		name                string
+		roots               []string
+		title               string
+		expectedTargets     []string
+		expectedRoots       []string
+		expectedEdges       int
+		expectedAnnotations []string
+		expectedResolutions []string
+	}{
+		{
+			condition: "firstparty",
+			name:      "binary",
+			roots:     []string{"bin/bin1.meta_lic"},
+			expectedTargets: []string{
+				"bin/bin1.meta_lic",
+				"lib/liba.so.meta_lic",
+				"lib/libc.a.meta_lic",
+			},
+			expectedRoots:       []string{"bin/bin1.meta_lic"},
+			expectedEdges:       2,
+			expectedAnnotations: []string{"static"},
+			expectedResolutions: []string{
+				"bin/bin1.meta_lic bin/bin1.meta_lic notice",
+				"bin/bin1.meta_lic lib/liba.so.meta_lic notice",
+				"bin/bin1.meta_lic lib/libc.a.meta_lic notice",
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "application",
+			roots:     []string{"application.meta_lic"},
+			title:     "Explore <application>",
+			expectedTargets: []string{
+				"application.meta_lic",
+				"bin/bin3.meta_lic",
+				"lib/liba.so.meta_lic",
+				"lib/libb.so.meta_lic",
+			},
+			expectedRoots:       []string{"application.meta_lic"},
+			expectedEdges:       3,
+			expectedAnnotations: []string{"dynamic", "static", "toolchain"},
+			expectedResolutions: []string{
+				"application.meta_lic application.meta_lic notice:restricted:restricted_if_statically_linked",
+				"application.meta_lic lib/liba.so.meta_lic restricted:restricted_if_statically_linked",
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "library",
+			roots:     []string{"lib/libd.so.meta_lic"},
+			expectedTargets: []string{
+				"lib/libd.so.meta_lic",
+			},
+			expectedRoots:       []string{"lib/libd.so.meta_lic"},
+			expectedAnnotations: []string{},
+			expectedResolutions: []string{
+				"lib/libd.so.meta_lic lib/libd.so.meta_lic notice",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+
+			rootFiles := make([]string, 0, len(tt.roots))
+			for _, r := range tt.roots {
+				rootFiles = append(rootFiles, "testdata/"+tt.condition+"/"+r)
+			}
+
+			ctx := context{stdout, stderr, compliance.GetFS(""), []string{"testdata/" + tt.condition + "/"}, tt.title}
+
+			err := exploreLicenses(&ctx, rootFiles...)
+			if err != nil {
+				t.Fatalf("exploreLicenses: error = %v, stderr = %v", err, stderr)
+			}
+			if stderr.Len() > 0 {
+				t.Errorf("exploreLicenses: gotStderr = %v, want none", stderr)
+			}
+
+			page := stdout.String()
+			if loc := externalRe.FindStringIndex(page); loc != nil {
+				t.Errorf("exploreLicenses: unexpected external reference %q", page[loc[0]:loc[1]])
+			}
+			if len(tt.title) > 0 && !strings.Contains(page, "<title>Explore &lt;application&gt;</title>") {
+				t.Errorf("exploreLicenses: missing escaped title %q", tt.title)
+			}
+
+			m := graphDataRe.FindStringSubmatch(page)
+			if m == nil {
+				t.Fatalf("exploreLicenses: missing graph data in %s", page)
+			}
+			var data graphData
+			if err := json.Unmarshal([]byte(m[1]), &data); err != nil {
+				t.Fatalf("exploreLicenses: cannot parse graph data: %s", err)
+			}
+
+			names := func(indexes []int) string {
+				result := make([]string, 0, len(indexes))
+				for _, i := range indexes {
+					result = append(result, data.Targets[i].Name)
+				}
+				return strings.Join(result, ", ")
+			}
+
+			actualTargets := make([]string, 0, len(data.Targets))
+			for _, target := range data.Targets {
+				actualTargets = append(actualTargets, target.Name)
+			}
+			if strings.Join(actualTargets, ", ") != strings.Join(tt.expectedTargets, ", ") {
+				t.Errorf("unexpected targets: got [%s], want [%s]", strings.Join(actualTargets, ", "), strings.Join(tt.expectedTargets, ", "))
+			}
+			if names(data.Roots) != strings.Join(tt.expectedRoots, ", ") {
+				t.Errorf("unexpected roots: got [%s], want [%s]", names(data.Roots), strings.Join(tt.expectedRoots, ", "))
+			}
+			if len(data.Edges) != tt.expectedEdges {
+				t.Errorf("unexpected edges: got %d edges, want %d edges", len(data.Edges), tt.expectedEdges)
+			}
+			if strings.Join(data.Annotations, ", ") != strings.Join(tt.expectedAnnotations, ", ") {
+				t.Errorf("unexpected annotations: got %v, want %v", data.Annotations, tt.expectedAnnotations)
+			}
+			actualResolutions := make([]string, 0, len(data.Resolutions))
+			for _, r := range data.Resolutions {
+				actualResolutions = append(actualResolutions, fmt.Sprintf("%s %s %s",
+					data.Targets[r.AttachesTo].Name, data.Targets[r.ActsOn].Name, strings.Join(r.Conditions, ":")))
+			}
+			if strings.Join(actualResolutions, "\n") != strings.Join(tt.expectedResolutions, "\n") {
+				t.Errorf("unexpected resolutions:\ngot:\n%s\nwant:\n%s", strings.Join(actualResolutions, "\n"), strings.Join(tt.expectedResolutions, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// The explorer page is self-contained: the style, markup, and script below
+// reference no external assets so the page works offline.
+
+// explorerStyle is the css for the explorer page.
+const explorerStyle = `body { font-family: sans-serif; margin: 0; padding: 0 8px; }
+h1 { font-size: 1.3em; margin: 8px 0; }
+#controls { display: flex; flex-wrap: wrap; gap: 8px; align-items: center; padding: 4px 0; border-bottom: 1px solid #ccc; }
+#main { display: flex; gap: 12px; }
+#list { flex: 0 0 35%; max-height: 85vh; overflow: auto; }
+#details { flex: 1 1 auto; max-height: 85vh; overflow: auto; }
+#list ul, #details ul { list-style-type: none; margin: 0; padding: 0; }
+#list li { padding: 1px 4px; cursor: pointer; white-space: nowrap; }
+#list li:hover { background: #eef; }
+#list li.selected { background: #ccf; }
+.root { font-weight: bold; }
+.acted-on { background: #fdd; }
+.attaches { border-left: 4px solid #c33; }
+.conditions { color: #666; font-size: 0.85em; margin-left: 0.5em; }
+.link { color: #00c; cursor: pointer; text-decoration: underline; }
+.note { color: #666; font-style: italic; }
+table { border-collapse: collapse; }
+td, th { padding: 1px 6px; text-align: left; vertical-align: top; }
+svg text { font-size: 11px; cursor: pointer; }
+svg line { stroke: #999; }
+svg line.highlight { stroke: #c33; stroke-width: 2; }
+`
+
+// explorerBody is the markup for the controls and panes of the explorer page.
+const explorerBody = `<div id="controls">
+  <label>Search <input id="search" type="search" placeholder="target or project"></label>
+  <label>Condition <select id="condition"><option value="">any</option></select></label>
+  <label>Annotation <select id="annotation"><option value="">any</option></select></label>
+  <label><input id="collapse" type="checkbox"> Collapse by project</label>
+  <label>Resolutions for <select id="resolve"><option value="">none</option></select></label>
+  <span id="count" class="note"></span>
+</div>
+<div id="main">
+  <div id="list"></div>
+  <div id="details"><p class="note">Select a target or project.</p></div>
+</div>
+`
+
+// explorerScript is the javascript for the explorer page.
+const explorerScript = `(function() {
+  'use strict';
+  var MAX_ITEMS = 1000;
+  var MAX_NEIGHBORS = 30;
+  var NO_PROJECT = '(no project)';
+  var data = JSON.parse(document.getElementById('graph-data').textContent);
+  var targets = data.targets;
+  var roots = {};
+  data.roots.forEach(function(i) { roots[i] = true; });
+
+  // Index the edges and resolutions by target node.
+  targets.forEach(function(t, i) {
+    t.id = i;
+    t.deps = [];
+    t.users = [];
+    t.attached = [];
+    t.actedOn = [];
+    t.lowerName = t.name.toLowerCase();
+  });
+  data.edges.forEach(function(e) {
+    targets[e.target].deps.push(e);
+    targets[e.dependency].users.push(e);
+  });
+  data.resolutions.forEach(function(r) {
+    targets[r.attachesTo].attached.push(r);
+    targets[r.actsOn].actedOn.push(r);
+  });
+
+  // Group the target nodes by project.
+  var projects = {};
+  var projectNames = [];
+  targets.forEach(function(t) {
+    var ps = t.projects.length > 0 ? t.projects : [NO_PROJECT];
+    t.projectKeys = ps;
+    ps.forEach(function(p) {
+      if (!projects.hasOwnProperty(p)) {
+        projects[p] = {name: p, targets: [], conditions: {}};
+        projectNames.push(p);
+      }
+      projects[p].targets.push(t);
+      t.conditions.forEach(function(c) { projects[p].conditions[c] = true; });
+    });
+  });
+  projectNames.sort();
+
+  var state = {search: '', condition: '', annotation: '', collapse: false, resolve: '', selected: null};
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    if (attrs) {
+      Object.keys(attrs).forEach(function(k) {
+        if (k === 'onclick') {
+          e.addEventListener('click', attrs[k]);
+        } else {
+          e.setAttribute(k, attrs[k]);
+        }
+      });
+    }
+    (children || []).forEach(function(c) {
+      e.appendChild(typeof c === 'string' ? document.createTextNode(c) : c);
+    });
+    return e;
+  }
+
+  function has(list, value) {
+    return list.indexOf(value) >= 0;
+  }
+
+  function edgeMatches(e) {
+    return state.annotation === '' || has(e.annotations, state.annotation);
+  }
+
+  function targetMatches(t) {
+    if (state.search !== '') {
+      var s = state.search;
+      if (t.lowerName.indexOf(s) < 0 && !t.projects.some(function(p) { return p.toLowerCase().indexOf(s) >= 0; })) {
+        return false;
+      }
+    }
+    if (state.condition !== '' && !has(t.conditions, state.condition)) {
+      return false;
+    }
+    if (state.annotation !== '' && !t.deps.concat(t.users).some(edgeMatches)) {
+      return false;
+    }
+    return true;
+  }
+
+  function resolves(r) {
+    return state.resolve !== '' && has(r.conditions, state.resolve);
+  }
+
+  function isActedOn(t) {
+    return t.actedOn.some(resolves);
+  }
+
+  function isAttached(t) {
+    return t.attached.some(resolves);
+  }
+
+  function targetClass(t) {
+    var c = [];
+    if (roots[t.id]) { c.push('root'); }
+    if (isActedOn(t)) { c.push('acted-on'); }
+    if (isAttached(t)) { c.push('attaches'); }
+    if (state.selected && state.selected.kind === 'target' && state.selected.id === t.id) { c.push('selected'); }
+    return c.join(' ');
+  }
+
+  function targetLink(t) {
+    return el('span', {'class': 'link ' + targetClass(t), onclick: function() { select('target', t.id); }}, [t.name]);
+  }
+
+  function projectLink(p) {
+    return el('span', {'class': 'link', onclick: function() { select('project', p); }}, [p]);
+  }
+
+  function conditionsOf(list) {
+    return el('span', {'class': 'conditions'}, [list.join(', ')]);
+  }
+
+  function renderList() {
+    var list = document.getElementById('list');
+    list.textContent = '';
+    var ul = el('ul');
+    var shown = 0;
+    var matched = 0;
+    if (state.collapse) {
+      projectNames.forEach(function(p) {
+        var members = projects[p].targets.filter(targetMatches);
+        if (members.length === 0) { return; }
+        matched++;
+        if (shown >= MAX_ITEMS) { return; }
+        shown++;
+        var c = [];
+        if (members.some(isActedOn)) { c.push('acted-on'); }
+        if (members.some(isAttached)) { c.push('attaches'); }
+        if (state.selected && state.selected.kind === 'project' && state.selected.id === p) { c.push('selected'); }
+        ul.appendChild(el('li', {'class': c.join(' '), onclick: function() { select('project', p); }},
+            [p + ' (' + members.length + ')', conditionsOf(Object.keys(projects[p].conditions).sort())]));
+      });
+    } else {
+      targets.forEach(function(t) {
+        if (!targetMatches(t)) { return; }
+        matched++;
+        if (shown >= MAX_ITEMS) { return; }
+        shown++;
+        ul.appendChild(el('li', {'class': targetClass(t), onclick: function() { select('target', t.id); }},
+            [t.name, conditionsOf(t.conditions)]));
+      });
+    }
+    list.appendChild(ul);
+    if (matched > shown) {
+      list.appendChild(el('p', {'class': 'note'}, [(matched - shown) + ' more; refine the search to see them.']));
+    }
+    document.getElementById('count').textContent = matched + (state.collapse ? ' projects' : ' targets');
+  }
+
+  function section(title, children) {
+    return el('div', {}, [el('h3', {}, [title])].concat(children));
+  }
+
+  function edgeTable(edges, other) {
+    var rows = edges.filter(edgeMatches).map(function(e) {
+      return el('tr', {}, [el('td', {}, [targetLink(targets[other(e)])]), el('td', {}, [e.annotations.join(', ')])]);
+    });
+    if (rows.length === 0) { return el('p', {'class': 'note'}, ['none']); }
+    return el('table', {}, rows);
+  }
+
+  function resolutionList(rs, other) {
+    var items = rs.filter(function(r) { return state.resolve === '' || resolves(r); }).map(function(r) {
+      return el('li', {}, [targetLink(targets[other(r)]), conditionsOf(r.conditions)]);
+    });
+    if (items.length === 0) { return el('p', {'class': 'note'}, ['none']); }
+    return el('ul', {}, items);
+  }
+
+  // neighborhood draws the selected target between its dependents and dependencies.
+  function neighborhood(t) {
+    var ns = 'http://www.w3.org/2000/svg';
+    var users = t.users.filter(edgeMatches).slice(0, MAX_NEIGHBORS);
+    var deps = t.deps.filter(edgeMatches).slice(0, MAX_NEIGHBORS);
+    var rowHeight = 16;
+    var height = Math.max(users.length, deps.length, 1) * rowHeight + 20;
+    var width = 900;
+    var svg = document.createElementNS(ns, 'svg');
+    svg.setAttribute('width', width);
+    svg.setAttribute('height', height);
+    function node(x, y, target, anchor) {
+      var text = document.createElementNS(ns, 'text');
+      text.setAttribute('x', x);
+      text.setAttribute('y', y + 4);
+      text.setAttribute('text-anchor', anchor);
+      text.setAttribute('class', targetClass(target));
+      text.textContent = target.name;
+      text.addEventListener('click', function() { select('target', target.id); });
+      svg.appendChild(text);
+    }
+    function line(x1, y1, x2, y2, highlight) {
+      var l = document.createElementNS(ns, 'line');
+      l.setAttribute('x1', x1);
+      l.setAttribute('y1', y1);
+      l.setAttribute('x2', x2);
+      l.setAttribute('y2', y2);
+      if (highlight) { l.setAttribute('class', 'highlight'); }
+      svg.appendChild(l);
+    }
+    var cy = height / 2;
+    function column(edges, x, lineX, other, anchor) {
+      var top = cy - (edges.length - 1) * rowHeight / 2;
+      edges.forEach(function(e, i) {
+        var o = targets[other(e)];
+        var y = top + i * rowHeight;
+        line(lineX, y, width / 2 + (x < width / 2 ? -60 : 60), cy, isActedOn(o) && isActedOn(t));
+        node(x, y, o, anchor);
+      });
+    }
+    column(users, 290, 295, function(e) { return e.target; }, 'end');
+    column(deps, 610, 605, function(e) { return e.dependency; }, 'start');
+    node(width / 2, cy, t, 'middle');
+    return svg;
+  }
+
+  function renderTarget(t) {
+    var d = document.getElementById('details');
+    d.textContent = '';
+    d.appendChild(el('h2', {'class': targetClass(t)}, [t.name + (roots[t.id] ? ' (root)' : '')]));
+    var props = [
+      ['Package', [t.package]],
+      ['Projects', t.projects.map(function(p) { return projectLink(p); })],
+      ['License kinds', [t.kinds.join(', ')]],
+      ['License conditions', [t.conditions.join(', ')]],
+      ['Installed', [t.installed.join(', ')]],
+      ['Container', [t.container ? 'yes' : 'no']]
+    ];
+    d.appendChild(el('table', {}, props.map(function(p) {
+      var cells = [];
+      p[1].forEach(function(v, i) {
+        if (i > 0) { cells.push(', '); }
+        cells.push(v);
+      });
+      return el('tr', {}, [el('th', {}, [p[0]]), el('td', {}, cells)]);
+    })));
+    d.appendChild(neighborhood(t));
+    d.appendChild(section('Dependencies', [edgeTable(t.deps, function(e) { return e.dependency; })]));
+    d.appendChild(section('Dependents', [edgeTable(t.users, function(e) { return e.target; })]));
+    var suffix = state.resolve === '' ? '' : ' (' + state.resolve + ')';
+    d.appendChild(section('Resolutions attached to this target' + suffix,
+        [resolutionList(t.attached, function(r) { return r.actsOn; })]));
+    d.appendChild(section('Resolutions acting on this target' + suffix,
+        [resolutionList(t.actedOn, function(r) { return r.attachesTo; })]));
+  }
+
+  function renderProject(p) {
+    var d = document.getElementById('details');
+    d.textContent = '';
+    var project = projects[p];
+    d.appendChild(el('h2', {}, [p]));
+    d.appendChild(el('p', {}, ['License conditions: ' + Object.keys(project.conditions).sort().join(', ')]));
+    d.appendChild(section('Targets', [el('ul', {}, project.targets.map(function(t) {
+      return el('li', {}, [targetLink(t), conditionsOf(t.conditions)]);
+    }))]));
+    var deps = {};
+    var users = {};
+    project.targets.forEach(function(t) {
+      t.deps.filter(edgeMatches).forEach(function(e) {
+        targets[e.dependency].projectKeys.forEach(function(q) { if (q !== p) { deps[q] = true; } });
+      });
+      t.users.filter(edgeMatches).forEach(function(e) {
+        targets[e.target].projectKeys.forEach(function(q) { if (q !== p) { users[q] = true; } });
+      });
+    });
+    function projectList(ps) {
+      var names = Object.keys(ps).sort();
+      if (names.length === 0) { return el('p', {'class': 'note'}, ['none']); }
+      return el('ul', {}, names.map(function(q) { return el('li', {}, [projectLink(q)]); }));
+    }
+    d.appendChild(section('Depends on projects', [projectList(deps)]));
+    d.appendChild(section('Used by projects', [projectList(users)]));
+  }
+
+  function renderDetails() {
+    if (!state.selected) { return; }
+    if (state.selected.kind === 'target') {
+      renderTarget(targets[state.selected.id]);
+    } else {
+      renderProject(state.selected.id);
+    }
+  }
+
+  function select(kind, id) {
+    state.selected = {kind: kind, id: id};
+    render();
+  }
+
+  function render() {
+    renderList();
+    renderDetails();
+  }
+
+  function fill(id, values) {
+    var s = document.getElementById(id);
+    values.forEach(function(v) { s.appendChild(el('option', {value: v}, [v])); });
+  }
+
+  fill('condition', data.conditions);
+  fill('annotation', data.annotations);
+  fill('resolve', data.conditions);
+  document.getElementById('search').addEventListener('input', function(ev) {
+    state.search = ev.target.value.toLowerCase();
+    renderList();
+  });
+  ['condition', 'annotation', 'resolve'].forEach(function(id) {
+    document.getElementById(id).addEventListener('change', function(ev) {
+      state[id] = ev.target.value;
+      render();
+    });
+  });
+  document.getElementById('collapse').addEventListener('change', function(ev) {
+    state.collapse = ev.target.checked;
+    renderList();
+  });
+  if (data.roots.length > 0) {
+    select('target', data.roots[0]);
+  } else {
+    render();
+  }
+})();
+`
@@ -17,6 +17,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"html"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"testing"
 
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -809,7 +811,7 @@ func Test(t *testing.T) {
 
 			var deps []string
 
-			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), tt.includeTOC, "", []string{tt.stripPrefix}, tt.title, &deps}
+			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), tt.includeTOC, "", []string{tt.stripPrefix}, tt.title, &deps, compliance.InstallPathFilter{}}
 
 			err := htmlNotice(&ctx, rootFiles...)
 			if err != nil {
@@ -1072,3 +1074,56 @@ func (l matcherList) String() string {
 	}
 	return sb.String()
 }
+
+func Test_trace(t *testing.T) {
+	tracer := tracing.Enable("htmlnotice")
+	defer tracing.Disable()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	var deps []string
+	ctx := context{stdout, stderr, compliance.GetFS(""), true, "", []string{}, "", &deps, compliance.InstallPathFilter{}}
+
+	err := htmlNotice(&ctx, "testdata/notice/highest.apex.meta_lic")
+	if err != nil {
+		t.Fatalf("htmlnotice: error = %v, stderr = %v", err, stderr)
+	}
+
+	var buf bytes.Buffer
+	if err := tracer.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: error = %v", err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+			Ph   string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("json.Unmarshal: error = %v", err)
+	}
+	found := make(map[string]bool)
+	for _, e := range trace.TraceEvents {
+		found[e.Ph+" "+e.Name] = true
+	}
+	for _, name := range []string{
+		"X ReadLicenseGraph",
+		"X read meta_lic",
+		"X ResolveBottomUpConditions",
+		"X ResolveTopDownConditions",
+		"X IndexLicenseTexts",
+		"X read project metadata",
+		"X render html",
+		"C meta_lic_files",
+		"C meta_lic_bytes",
+		"C license_text_files",
+		"C license_text_bytes",
+	} {
+		if !found[name] {
+			t.Errorf("trace: missing event %q", name)
+		}
+	}
+	if n := tracer.Counter("meta_lic_files"); n != 7 {
+		t.Errorf("meta_lic_files: got %d, want 7", n)
+	}
+}
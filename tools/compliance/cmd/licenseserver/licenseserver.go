@@ -0,0 +1,588 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"android/soong/response"
+	"android/soong/tools/compliance"
+)
+
+var (
+	failNoneRequested = fmt.Errorf("\nNo license metadata files requested")
+	failNoLicenses    = fmt.Errorf("No licenses found")
+)
+
+type context struct {
+	stderr      io.Writer
+	rootFS      fs.FS
+	stripPrefix []string
+}
+
+func (ctx context) strip(installPath string) string {
+	for _, prefix := range ctx.stripPrefix {
+		if strings.HasPrefix(installPath, prefix) {
+			p := strings.TrimPrefix(installPath, prefix)
+			if 0 == len(p) {
+				continue
+			}
+			return p
+		}
+	}
+	return installPath
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func main() {
+	var expandedArgs []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			f, err := os.Open(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			respArgs, err := response.ReadRspFile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			expandedArgs = append(expandedArgs, respArgs...)
+		} else {
+			expandedArgs = append(expandedArgs, arg)
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} file.meta_lic {file.meta_lic...}
+
+Loads the license graph rooted at the given license metadata files once,
+and answers queries about it as JSON over HTTP on localhost until
+interrupted.
+
+Endpoints:
+  GET  /status       the roots, load time and size of the graph
+  GET  /targets      targets; filter with ?name= or ?project=
+  GET  /edges        edges; filter with ?target= or ?dependency=
+  GET  /shipped      shipped targets; filter with ?project=
+  GET  /resolutions  resolutions for each ?condition= (default all);
+                     filter with ?attaches_to= or ?acts_on=
+  GET  /conflicts    source-sharing and source-privacy conflicts
+  POST /reload       reload the graph now
+
+The server reloads the graph whenever the size or modification time of
+a root license metadata file changes, and keeps serving the previous
+graph if the reload fails.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	addr := flags.String("addr", "localhost:8080", "Loopback address and port on which to listen.")
+	poll := flags.Duration("poll", 2*time.Second, "How often to check the root files for changes. (0 disables)")
+	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+
+	flags.Parse(expandedArgs)
+
+	// Must specify at least one root target.
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := checkLoopback(*addr); err != nil {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(2)
+	}
+
+	ctx := &context{os.Stderr, compliance.FS, *stripPrefix}
+
+	s, err := newServer(ctx, flags.Args()...)
+	if err != nil {
+		if err == failNoneRequested {
+			flags.Usage()
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if *poll > 0 {
+		go func() {
+			for range time.Tick(*poll) {
+				if _, err := s.reloadIfChanged(); err != nil {
+					fmt.Fprintf(os.Stderr, "reload failed, serving previous graph: %s\n", err)
+				}
+			}
+		}()
+	}
+
+	fmt.Fprintf(os.Stderr, "serving license graph on http://%s/\n", *addr)
+	err = http.ListenAndServe(*addr, s.handler())
+	fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	os.Exit(1)
+}
+
+// checkLoopback returns an error unless `addr` is a loopback host and port.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid -addr %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("invalid -addr %q: host must be localhost or a loopback address", addr)
+}
+
+// fileStamp identifies a version of a root file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// loadedGraph holds a license graph and the results computed when loaded.
+type loadedGraph struct {
+	lg        *compliance.LicenseGraph
+	stamps    map[string]fileStamp
+	loadedAt  time.Time
+	shipped   compliance.TargetNodeSet
+	conflicts []compliance.SourceSharePrivacyConflict
+}
+
+// server answers queries about the license graph rooted at `roots`.
+type server struct {
+	ctx   *context
+	roots []string
+
+	// reloadMu serializes reloads from the stamp check to the swap, so an
+	// older read never replaces a newer graph.
+	reloadMu sync.Mutex
+
+	// mu guards `graph`.
+	mu    sync.RWMutex
+	graph *loadedGraph
+}
+
+// newServer returns a server with the license graph rooted at `files` loaded.
+func newServer(ctx *context, files ...string) (*server, error) {
+	if len(files) < 1 {
+		return nil, failNoneRequested
+	}
+	// Add the suffix like ReadLicenseGraph so the roots can be stat'd.
+	roots := make([]string, 0, len(files))
+	for _, f := range files {
+		if !strings.HasSuffix(f, "meta_lic") {
+			f += ".meta_lic"
+		}
+		roots = append(roots, f)
+	}
+	s := &server{ctx: ctx, roots: roots}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// stamp returns the current size and modification time of each root file.
+func (s *server) stamp() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, f := range s.roots {
+		fi, err := fs.Stat(s.ctx.rootFS, f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot stat %q: %w", f, err)
+		}
+		stamps[f] = fileStamp{fi.Size(), fi.ModTime()}
+	}
+	return stamps, nil
+}
+
+// reload reads the license graph and replaces the one being served.
+func (s *server) reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	return s.load()
+}
+
+// load reads the license graph and replaces the one being served. The caller
+// must hold `reloadMu`.
+func (s *server) load() error {
+	stamps, err := s.stamp()
+	if err != nil {
+		return err
+	}
+	lg, err := compliance.ReadLicenseGraph(s.ctx.rootFS, s.ctx.stderr, s.roots)
+	if err != nil {
+		return fmt.Errorf("Unable to read license metadata file(s) %q: %w\n", s.roots, err)
+	}
+	if lg == nil {
+		return failNoLicenses
+	}
+
+	// Compute the results shared by every query before serving the graph.
+	compliance.ResolveTopDownConditions(lg)
+	g := &loadedGraph{
+		lg:        lg,
+		stamps:    stamps,
+		loadedAt:  time.Now(),
+		shipped:   compliance.ShippedNodes(lg),
+		conflicts: compliance.ConflictingSharedPrivateSource(lg),
+	}
+
+	s.mu.Lock()
+	s.graph = g
+	s.mu.Unlock()
+	return nil
+}
+
+// reloadIfChanged reloads the license graph when any root file changed
+// since the last load, and returns whether it reloaded.
+func (s *server) reloadIfChanged() (bool, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	stamps, err := s.stamp()
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	previous := s.graph.stamps
+	s.mu.RUnlock()
+	changed := false
+	for f, stamp := range stamps {
+		if p, ok := previous[f]; !ok || p.size != stamp.size || !p.modTime.Equal(stamp.modTime) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, s.load()
+}
+
+// current returns the license graph being served.
+func (s *server) current() *loadedGraph {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.graph
+}
+
+// handler returns the http.Handler serving the endpoints.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.get(s.status))
+	mux.HandleFunc("/targets", s.get(s.targets))
+	mux.HandleFunc("/edges", s.get(s.edges))
+	mux.HandleFunc("/shipped", s.get(s.shippedTargets))
+	mux.HandleFunc("/resolutions", s.get(s.resolutions))
+	mux.HandleFunc("/conflicts", s.get(s.conflicts))
+	mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires POST", r.URL.Path))
+			return
+		}
+		if err := s.reload(); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.get(s.status)(w, r)
+	})
+	return mux
+}
+
+// queryFunc answers a query against the graph `g`.
+type queryFunc func(g *loadedGraph, r *http.Request) (interface{}, error)
+
+// badRequest describes a query with invalid parameters.
+type badRequest struct {
+	err error
+}
+
+func (e badRequest) Error() string { return e.err.Error() }
+
+// get adapts `query` to an http.HandlerFunc writing JSON.
+func (s *server) get(query queryFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires GET", r.URL.Path))
+			return
+		}
+		result, err := query(s.current(), r)
+		if err != nil {
+			if _, ok := err.(badRequest); ok {
+				writeError(w, http.StatusBadRequest, err)
+			} else {
+				writeError(w, http.StatusInternalServerError, err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// writeJSON writes `v` as the JSON response body with `status`.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes `err` as a JSON error response with `status`.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{strings.TrimSpace(err.Error())})
+}
+
+// statusData describes the graph being served.
+type statusData struct {
+	Roots    []string `json:"roots"`
+	LoadedAt string   `json:"loaded_at"`
+	Targets  int      `json:"targets"`
+	Edges    int      `json:"edges"`
+}
+
+// targetData describes a target node.
+type targetData struct {
+	Name         string   `json:"name"`
+	PackageName  string   `json:"package_name,omitempty"`
+	Conditions   []string `json:"conditions"`
+	LicenseKinds []string `json:"license_kinds"`
+	Projects     []string `json:"projects"`
+	Installed    []string `json:"installed"`
+	IsContainer  bool     `json:"is_container,omitempty"`
+}
+
+// edgeData describes an edge from a target to its dependency.
+type edgeData struct {
+	Target      string   `json:"target"`
+	Dependency  string   `json:"dependency"`
+	Annotations []string `json:"annotations"`
+}
+
+// resolutionData describes a resolution.
+type resolutionData struct {
+	AttachesTo string   `json:"attaches_to"`
+	ActsOn     string   `json:"acts_on"`
+	Conditions []string `json:"conditions"`
+}
+
+// conflictData describes a source-sharing and source-privacy conflict.
+type conflictData struct {
+	Target           string `json:"target"`
+	ShareCondition   string `json:"share_condition"`
+	PrivacyCondition string `json:"privacy_condition"`
+}
+
+// sortedStrings returns a sorted copy of `values`.
+func sortedStrings(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+// target converts `tn` to targetData.
+func (s *server) target(tn *compliance.TargetNode) targetData {
+	installed := make([]string, 0, len(tn.Installed()))
+	for _, p := range tn.Installed() {
+		installed = append(installed, s.ctx.strip(p))
+	}
+	return targetData{
+		Name:         s.ctx.strip(tn.Name()),
+		PackageName:  tn.PackageName(),
+		Conditions:   sortedStrings(tn.LicenseConditions().Names()),
+		LicenseKinds: sortedStrings(tn.LicenseKinds()),
+		Projects:     sortedStrings(tn.Projects()),
+		Installed:    installed,
+		IsContainer:  tn.IsContainer(),
+	}
+}
+
+// matchesName returns true when `name` is empty or names `tn`.
+func (s *server) matchesName(tn *compliance.TargetNode, name string) bool {
+	return len(name) == 0 || tn.Name() == name || s.ctx.strip(tn.Name()) == name
+}
+
+// hasProject returns true when `project` is empty or is a project of `tn`.
+func hasProject(tn *compliance.TargetNode, project string) bool {
+	if len(project) == 0 {
+		return true
+	}
+	for _, p := range tn.Projects() {
+		if p == project {
+			return true
+		}
+	}
+	return false
+}
+
+// status answers /status.
+func (s *server) status(g *loadedGraph, r *http.Request) (interface{}, error) {
+	return statusData{
+		Roots:    s.roots,
+		LoadedAt: g.loadedAt.UTC().Format(time.RFC3339),
+		Targets:  len(g.lg.Targets()),
+		Edges:    len(g.lg.Edges()),
+	}, nil
+}
+
+// targets answers /targets.
+func (s *server) targets(g *loadedGraph, r *http.Request) (interface{}, error) {
+	name := r.URL.Query().Get("name")
+	project := r.URL.Query().Get("project")
+	targets := g.lg.Targets()
+	sort.Sort(targets)
+	result := []targetData{}
+	for _, tn := range targets {
+		if s.matchesName(tn, name) && hasProject(tn, project) {
+			result = append(result, s.target(tn))
+		}
+	}
+	return result, nil
+}
+
+// edges answers /edges.
+func (s *server) edges(g *loadedGraph, r *http.Request) (interface{}, error) {
+	target := r.URL.Query().Get("target")
+	dependency := r.URL.Query().Get("dependency")
+	edges := g.lg.Edges()
+	sort.Sort(edges)
+	result := []edgeData{}
+	for _, e := range edges {
+		if !s.matchesName(e.Target(), target) || !s.matchesName(e.Dependency(), dependency) {
+			continue
+		}
+		result = append(result, edgeData{
+			Target:      s.ctx.strip(e.Target().Name()),
+			Dependency:  s.ctx.strip(e.Dependency().Name()),
+			Annotations: sortedStrings(e.Annotations().AsList()),
+		})
+	}
+	return result, nil
+}
+
+// shippedTargets answers /shipped.
+func (s *server) shippedTargets(g *loadedGraph, r *http.Request) (interface{}, error) {
+	project := r.URL.Query().Get("project")
+	shipped := make(compliance.TargetNodeList, 0, len(g.shipped))
+	for tn := range g.shipped {
+		shipped = append(shipped, tn)
+	}
+	sort.Sort(shipped)
+	result := []targetData{}
+	for _, tn := range shipped {
+		if hasProject(tn, project) {
+			result = append(result, s.target(tn))
+		}
+	}
+	return result, nil
+}
+
+// resolutions answers /resolutions.
+func (s *server) resolutions(g *loadedGraph, r *http.Request) (interface{}, error) {
+	conditions := compliance.AllLicenseConditions
+	if names := r.URL.Query()["condition"]; len(names) > 0 {
+		conditions = compliance.NewLicenseConditionSet()
+		for _, name := range names {
+			lc, ok := compliance.RecognizedConditionNames[name]
+			if !ok {
+				return nil, badRequest{fmt.Errorf("unknown condition %q", name)}
+			}
+			conditions = conditions.Plus(lc)
+		}
+	}
+	attachesTo := r.URL.Query().Get("attaches_to")
+	actsOn := r.URL.Query().Get("acts_on")
+
+	rs := compliance.WalkResolutionsForCondition(g.lg, conditions)
+	targets := rs.AttachesTo()
+	sort.Sort(targets)
+	result := []resolutionData{}
+	for _, tn := range targets {
+		if !s.matchesName(tn, attachesTo) {
+			continue
+		}
+		rl := rs.Resolutions(tn)
+		sort.Sort(rl)
+		for _, res := range rl {
+			if !s.matchesName(res.ActsOn(), actsOn) {
+				continue
+			}
+			result = append(result, resolutionData{
+				AttachesTo: s.ctx.strip(res.AttachesTo().Name()),
+				ActsOn:     s.ctx.strip(res.ActsOn().Name()),
+				Conditions: sortedStrings(res.Resolves().Names()),
+			})
+		}
+	}
+	return result, nil
+}
+
+// conflicts answers /conflicts.
+func (s *server) conflicts(g *loadedGraph, r *http.Request) (interface{}, error) {
+	result := make([]conflictData, 0, len(g.conflicts))
+	for _, c := range g.conflicts {
+		result = append(result, conflictData{
+			Target:           s.ctx.strip(c.SourceNode.Name()),
+			ShareCondition:   c.ShareCondition.Name(),
+			PrivacyCondition: c.PrivacyCondition.Name(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Target != result[j].Target {
+			return result[i].Target < result[j].Target
+		}
+		if result[i].ShareCondition != result[j].ShareCondition {
+			return result[i].ShareCondition < result[j].ShareCondition
+		}
+		return result[i].PrivacyCondition < result[j].PrivacyCondition
+	})
+	return result, nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/testfs"
+)
+
+func TestMain(m *testing.M) {
+	// Change into the parent directory before running the tests
+	// so they can find the testdata directory.
+	if err := os.Chdir(".."); err != nil {
+		fmt.Printf("failed to change to testdata directory: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func Test_checkLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"localhost:8080", false},
+		{"127.0.0.1:0", false},
+		{"[::1]:8080", false},
+		{":8080", true},
+		{"0.0.0.0:8080", true},
+		{"example.com:80", true},
+		{"localhost", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := checkLoopback(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkLoopback(%q): got error %v, want error %t", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_endpoints(t *testing.T) {
+	tests := []struct {
+		condition      string
+		name           string
+		root           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedOut    []string
+	}{
+		{
+			condition:      "restricted",
+			name:           "status",
+			root:           "application.meta_lic",
+			path:           "/status",
+			expectedStatus: http.StatusOK,
+			expectedOut:    []string{`"targets": 4`, `"edges": 3`},
+		},
+		{
+			condition:      "restricted",
+			name:           "targets_by_project",
+			root:           "application.meta_lic",
+			path:           "/targets?project=device/library",
+			expectedStatus: http.StatusOK,
+			expectedOut: []string{
+				`"name": "lib/liba.so.meta_lic"`,
+				`"out/target/product/fictional/system/lib/liba.so"`,
+			},
+		},
+		{
+			condition:      "restricted",
+			name:           "edges_by_dependency",
+			root:           "application.meta_lic",
+			path:           "/edges?dependency=lib/libb.so.meta_lic",
+			expectedStatus: http.StatusOK,
+			expectedOut:    []string{`"target": "application.meta_lic"`, `"dynamic"`},
+		},
+		{
+			condition:      "restricted",
+			name:           "shipped",
+			root:           "application.meta_lic",
+			path:           "/shipped?project=device/library",
+			expectedStatus: http.StatusOK,
+			expectedOut:    []string{`"name": "lib/liba.so.meta_lic"`},
+		},
+		{
+			condition:      "restricted",
+			name:           "resolutions",
+			root:           "application.meta_lic",
+			path:           "/resolutions?condition=restricted&acts_on=lib/liba.so.meta_lic",
+			expectedStatus: http.StatusOK,
+			expectedOut: []string{
+				`"attaches_to": "application.meta_lic"`,
+				`"acts_on": "lib/liba.so.meta_lic"`,
+				`"restricted"`,
+			},
+		},
+		{
+			condition:      "restricted",
+			name:           "unknown_condition",
+			root:           "application.meta_lic",
+			path:           "/resolutions?condition=bogus",
+			expectedStatus: http.StatusBadRequest,
+			expectedOut:    []string{`"error": "unknown condition \"bogus\""`},
+		},
+		{
+			condition:      "proprietary",
+			name:           "conflicts",
+			root:           "highest.apex.meta_lic",
+			path:           "/conflicts",
+			expectedStatus: http.StatusOK,
+			expectedOut: []string{
+				`"target": "bin/bin2.meta_lic"`,
+				`"share_condition": "restricted"`,
+				`"privacy_condition": "proprietary"`,
+			},
+		},
+		{
+			condition:      "restricted",
+			name:           "reload_get",
+			root:           "application.meta_lic",
+			path:           "/reload",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			condition:      "restricted",
+			name:           "reload",
+			root:           "application.meta_lic",
+			method:         http.MethodPost,
+			path:           "/reload",
+			expectedStatus: http.StatusOK,
+			expectedOut:    []string{`"targets": 4`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
+			prefix := "testdata/" + tt.condition + "/"
+			ctx := &context{&bytes.Buffer{}, compliance.GetFS(""), []string{prefix}}
+			s, err := newServer(ctx, prefix+tt.root)
+			if err != nil {
+				t.Fatalf("licenseserver: unexpected error %s", err)
+			}
+			method := tt.method
+			if len(method) == 0 {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, httptest.NewRequest(method, tt.path, nil))
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("licenseserver: got status %d, want %d: %s", rec.Code, tt.expectedStatus, rec.Body)
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Errorf("licenseserver: invalid json %s", rec.Body)
+			}
+			for _, eo := range tt.expectedOut {
+				if !strings.Contains(rec.Body.String(), eo) {
+					t.Errorf("licenseserver: missing %s in %s", eo, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func Test_reloadIfChanged(t *testing.T) {
+	const license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	tfs := testfs.TestFS{
+		"bin.meta_lic": []byte(license + "deps: {\n  file: \"lib.meta_lic\"\n  annotations: \"static\"\n}\n"),
+		"lib.meta_lic": []byte(license),
+		"gpl.meta_lic": []byte("license_conditions: \"restricted\"\n"),
+	}
+	s, err := newServer(&context{&bytes.Buffer{}, &tfs, nil}, "bin.meta_lic")
+	if err != nil {
+		t.Fatalf("licenseserver: unexpected error %s", err)
+	}
+
+	reloaded, err := s.reloadIfChanged()
+	if err != nil || reloaded {
+		t.Fatalf("reloadIfChanged: got %t, %v, want false, no error", reloaded, err)
+	}
+	first := s.current()
+
+	tfs["bin.meta_lic"] = append(tfs["bin.meta_lic"], []byte("deps: {\n  file: \"gpl.meta_lic\"\n  annotations: \"dynamic\"\n}\n")...)
+	reloaded, err = s.reloadIfChanged()
+	if err != nil || !reloaded {
+		t.Fatalf("reloadIfChanged: got %t, %v, want true, no error", reloaded, err)
+	}
+	if s.current() == first {
+		t.Errorf("reloadIfChanged: still serving the previous graph")
+	}
+	if len(s.current().lg.Edges()) != 2 {
+		t.Errorf("reloadIfChanged: got %d edges, want 2", len(s.current().lg.Edges()))
+	}
+
+	// A failed reload keeps serving the last good graph.
+	second := s.current()
+	delete(tfs, "bin.meta_lic")
+	if _, err = s.reloadIfChanged(); err == nil {
+		t.Errorf("reloadIfChanged: got no error for missing root, want error")
+	}
+	if s.current() != second {
+		t.Errorf("reloadIfChanged: replaced the graph after a failed reload")
+	}
+}
+
+func Test_rootWithoutSuffix(t *testing.T) {
+	const license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	tfs := testfs.TestFS{"bin.meta_lic": []byte(license)}
+	s, err := newServer(&context{&bytes.Buffer{}, &tfs, nil}, "bin")
+	if err != nil {
+		t.Fatalf("licenseserver: unexpected error %s", err)
+	}
+	if len(s.roots) != 1 || s.roots[0] != "bin.meta_lic" {
+		t.Errorf("licenseserver: got roots %q, want [\"bin.meta_lic\"]", s.roots)
+	}
+	if reloaded, err := s.reloadIfChanged(); err != nil || reloaded {
+		t.Errorf("reloadIfChanged: got %t, %v, want false, no error", reloaded, err)
+	}
+}
+
+// gatedFS holds the first open of `file` once armed until `release` closes,
+// after reading its content and closing `opened`.
+type gatedFS struct {
+	fs.FS
+	file    string
+	armed   atomic.Bool
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedFS) Open(name string) (fs.File, error) {
+	if name != g.file || !g.armed.CompareAndSwap(true, false) {
+		return g.FS.Open(name)
+	}
+	data, err := fs.ReadFile(g.FS, name)
+	if err != nil {
+		return nil, err
+	}
+	close(g.opened)
+	<-g.release
+	return fstest.MapFS{name: {Data: data}}.Open(name)
+}
+
+func (g *gatedFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(g.FS, name)
+}
+
+func Test_concurrentReloads(t *testing.T) {
+	const license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	const bin = license + "deps: {\n  file: \"lib.meta_lic\"\n  annotations: \"static\"\n}\n"
+	tfs := testfs.TestFS{
+		"bin.meta_lic": []byte(bin),
+		"lib.meta_lic": []byte(license),
+		"gpl.meta_lic": []byte("license_conditions: \"restricted\"\n"),
+	}
+	g := &gatedFS{FS: &tfs, file: "bin.meta_lic", opened: make(chan struct{}), release: make(chan struct{})}
+	s, err := newServer(&context{&bytes.Buffer{}, g, nil}, "bin.meta_lic")
+	if err != nil {
+		t.Fatalf("licenseserver: unexpected error %s", err)
+	}
+
+	// A POST /reload reads the old root and stalls while the root changes
+	// and the poll notices.
+	g.armed.Store(true)
+	errs := make(chan error, 2)
+	go func() { errs <- s.reload() }()
+	<-g.opened
+	tfs["bin.meta_lic"] = []byte(bin + "deps: {\n  file: \"gpl.meta_lic\"\n  annotations: \"dynamic\"\n}\n")
+	go func() {
+		_, err := s.reloadIfChanged()
+		errs <- err
+	}()
+	// Give an unserialized poll time to publish first.
+	time.Sleep(50 * time.Millisecond)
+	close(g.release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("reload: unexpected error %s", err)
+		}
+	}
+	if len(s.current().lg.Edges()) != 2 {
+		t.Errorf("reload: got %d edges, want 2 from the newer root", len(s.current().lg.Edges()))
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -34,6 +35,19 @@ var (
 	failNoLicenses    = fmt.Errorf("No licenses found")
 )
 
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
 func main() {
 	var expandedArgs []string
 	for _, arg := range os.Args[1:] {
@@ -59,7 +73,7 @@ func main() {
 	flags := flag.NewFlagSet("flags", flag.ExitOnError)
 
 	flags.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: %s {-o outfile} file.meta_lic {file.meta_lic...}
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} file.meta_lic {file.meta_lic...}
 
 Outputs a csv file with 1 project per line in the first field followed
 by target:condition pairs describing why the project must be shared.
@@ -67,13 +81,30 @@ by target:condition pairs describing why the project must be shared.
 Each target is the path to a generated license metadata file for a
 Soong module or Make target, and the license condition is either
 restricted (e.g. GPL) or reciprocal (e.g. MPL).
+
+Options:
 `, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
 	}
 
 	outputFile := flags.String("o", "-", "Where to write the list of projects to share. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	includeInstalled := newMultiString(flags, "include_installed", "Glob matching install paths to include. (multiple allowed)")
+	excludeInstalled := newMultiString(flags, "exclude_installed", "Glob matching install paths to exclude. (multiple allowed)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	filter, err := compliance.NewInstallPathFilter(*includeInstalled, *excludeInstalled)
+	if err != nil {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(2)
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -109,7 +140,7 @@ restricted (e.g. GPL) or reciprocal (e.g. MPL).
 		ofile = obuf
 	}
 
-	err := listShare(ofile, os.Stderr, compliance.FS, flags.Args()...)
+	err = listShare(ofile, os.Stderr, compliance.FS, filter, flags.Args()...)
 	if err != nil {
 		if err == failNoneRequested {
 			flags.Usage()
@@ -118,17 +149,24 @@ restricted (e.g. GPL) or reciprocal (e.g. MPL).
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
-// listShare implements the listshare utility.
-func listShare(stdout, stderr io.Writer, rootFS fs.FS, files ...string) error {
+// listShare implements the listshare utility limited to the shipped targets
+// selected by `filter`.
+func listShare(stdout, stderr io.Writer, rootFS fs.FS, filter compliance.InstallPathFilter, files ...string) error {
 	// Must be at least one root file.
 	if len(files) < 1 {
 		return failNoneRequested
@@ -146,6 +184,9 @@ func listShare(stdout, stderr io.Writer, rootFS fs.FS, files ...string) error {
 	// shareSource contains all source-sharing resolutions.
 	shareSource := compliance.ResolveSourceSharing(licenseGraph)
 
+	// selected contains the shipped targets within the scope of the filter.
+	selected := compliance.ShippedNodesMatching(licenseGraph, filter)
+
 	// Group the resolutions by project.
 	presolution := make(map[string]compliance.LicenseConditionSet)
 	for _, target := range shareSource.AttachesTo() {
@@ -155,6 +196,10 @@ func listShare(stdout, stderr io.Writer, rootFS fs.FS, files ...string) error {
 		rl := shareSource.Resolutions(target)
 		sort.Sort(rl)
 		for _, r := range rl {
+			// Shared libraries ship outside of their dependents, so match their own install paths.
+			if !filter.IsEmpty() && !selected.Contains(r.ActsOn()) && !filter.Matches(r.ActsOn()) {
+				continue
+			}
 			for _, p := range r.ActsOn().Projects() {
 				if _, ok := presolution[p]; !ok {
 					presolution[p] = r.Resolves()
@@ -44,6 +44,7 @@ func Test(t *testing.T) {
 		name        string
 		outDir      string
 		roots       []string
+		include     []string
 		expectedOut []projectShare
 	}{
 		{
@@ -287,6 +288,43 @@ func Test(t *testing.T) {
 				},
 			},
 		},
+		{
+			condition: "restricted",
+			name:      "apexincludebin1",
+			roots:     []string{"highest.apex.meta_lic"},
+			include:   []string{"**/bin/bin1"},
+			expectedOut: []projectShare{
+				{
+					project:    "device/library",
+					conditions: []string{"restricted_if_statically_linked"},
+				},
+				{
+					project: "static/binary",
+					conditions: []string{
+						"restricted_if_statically_linked",
+					},
+				},
+				{
+					project: "static/library",
+					conditions: []string{
+						"reciprocal",
+						"restricted_if_statically_linked",
+					},
+				},
+			},
+		},
+		{
+			condition: "restricted",
+			name:      "apexincludebin2",
+			roots:     []string{"highest.apex.meta_lic"},
+			include:   []string{"**/bin/bin2"},
+			expectedOut: []projectShare{
+				{
+					project:    "dynamic/binary",
+					conditions: []string{"restricted"},
+				},
+			},
+		},
 		{
 			condition:   "restricted",
 			name:        "library",
@@ -446,7 +484,11 @@ func Test(t *testing.T) {
 			for _, r := range tt.roots {
 				rootFiles = append(rootFiles, "testdata/"+tt.condition+"/"+r)
 			}
-			err := listShare(stdout, stderr, compliance.GetFS(tt.outDir), rootFiles...)
+			filter, err := compliance.NewInstallPathFilter(tt.include, nil)
+			if err != nil {
+				t.Fatalf("unexpected filter error: got %s, want no error", err)
+			}
+			err = listShare(stdout, stderr, compliance.GetFS(tt.outDir), filter, rootFiles...)
 			if err != nil {
 				t.Fatalf("listshare: error = %v, stderr = %v", err, stderr)
 				return
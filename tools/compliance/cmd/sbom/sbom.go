@@ -31,6 +31,7 @@ import (
 	"android/soong/response"
 	"android/soong/tools/compliance"
 	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/tracing"
 
 	"github.com/google/blueprint/deptools"
 
@@ -123,12 +124,17 @@ Options:
 
 	outputFile := flags.String("o", "-", "Where to write the SBOM spdx file. (default stdout)")
 	depsFile := flags.String("d", "", "Where to write the deps file")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
 	product := flags.String("product", "", "The name of the product for which the notice is generated.")
 	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
 	buildid := flags.String("build_id", "", "Uniquely identifies the build. (default timestamp)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -177,13 +183,17 @@ Options:
 	}
 
 	// writing the spdx Doc created
+	span := tracing.Begin(tracing.CategoryOutput, "render spdx")
 	if err := spdx_json.Save2_2(spdxDoc, ofile); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to write document to %v: %v", *outputFile, err)
 		os.Exit(1)
 	}
+	span.End()
 
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q: %s\n", *outputFile, err)
 			os.Exit(1)
@@ -197,6 +207,10 @@ Options:
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -429,7 +443,25 @@ func sbomGenerator(ctx *context, files ...string) (*spdx.Document, []string, err
 				// Check parent and identify annotation
 				parent := path[len(path)-1]
 				targetEdge := parent.Edge()
-				if targetEdge.IsRuntimeDependency() {
+				if targetEdge.IsTestDependency() {
+					// Adding the test annotation as a TEST_DEPENDENCY_OF relationship
+					rln := &spdx.Relationship{
+						RefA:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, tn))),
+						RefB:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, targetEdge.Target()))),
+						Relationship: "TEST_DEPENDENCY_OF",
+					}
+					relationships = append(relationships, rln)
+
+				} else if targetEdge.IsDataDependency() {
+					// Adding the data annotation as a DATA_FILE_OF relationship
+					rln := &spdx.Relationship{
+						RefA:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, tn))),
+						RefB:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, targetEdge.Target()))),
+						Relationship: "DATA_FILE_OF",
+					}
+					relationships = append(relationships, rln)
+
+				} else if targetEdge.IsRuntimeDependency() {
 					// Adding the dynamic link annotation RUNTIME_DEPENDENCY_OF relationship
 					rln := &spdx.Relationship{
 						RefA:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, tn))),
@@ -438,6 +470,15 @@ func sbomGenerator(ctx *context, files ...string) (*spdx.Document, []string, err
 					}
 					relationships = append(relationships, rln)
 
+				} else if targetEdge.IsHeaderOnly() {
+					// Adding the header_only annotation as a DEPENDS_ON relationship
+					rln := &spdx.Relationship{
+						RefA:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, targetEdge.Target()))),
+						RefB:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, tn))),
+						Relationship: "DEPENDS_ON",
+					}
+					relationships = append(relationships, rln)
+
 				} else if targetEdge.IsDerivation() {
 					// Adding the  derivation annotation as a CONTAINS relationship
 					rln := &spdx.Relationship{
@@ -457,7 +498,13 @@ func sbomGenerator(ctx *context, files ...string) (*spdx.Document, []string, err
 					relationships = append(relationships, rln)
 
 				} else {
-					panic(fmt.Errorf("Unknown dependency type: %v", targetEdge.Annotations()))
+					// Any other kind of edge is a generic DEPENDENCY_OF relationship
+					rln := &spdx.Relationship{
+						RefA:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, tn))),
+						RefB:         common.MakeDocElementID("", replaceSlashes(getPackageName(ctx, targetEdge.Target()))),
+						Relationship: "DEPENDENCY_OF",
+					}
+					relationships = append(relationships, rln)
 				}
 			}
 
@@ -26,6 +26,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -37,8 +38,22 @@ type context struct {
 	stdout io.Writer
 	stderr io.Writer
 	rootFS fs.FS
+	filter compliance.InstallPathFilter
 }
 
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
 func main() {
 	var expandedArgs []string
 	for _, arg := range os.Args[1:] {
@@ -64,6 +79,9 @@ func main() {
 	flags := flag.NewFlagSet("flags", flag.ExitOnError)
 
 	outputFile := flags.String("o", "-", "Where to write the library list. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	includeInstalled := newMultiString(flags, "include_installed", "Glob matching install paths to include. (multiple allowed)")
+	excludeInstalled := newMultiString(flags, "exclude_installed", "Glob matching install paths to exclude. (multiple allowed)")
 
 	flags.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: %s {options} file.meta_lic {file.meta_lic...}
@@ -81,6 +99,17 @@ Options:
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 	}
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	filter, err := compliance.NewInstallPathFilter(*includeInstalled, *excludeInstalled)
+	if err != nil {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(2)
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -114,7 +143,7 @@ Options:
 		ofile = &bytes.Buffer{}
 	}
 
-	ctx := &context{ofile, os.Stderr, compliance.FS}
+	ctx := &context{ofile, os.Stderr, compliance.FS, filter}
 
 	err = shippedLibs(ctx, flags.Args()...)
 	if err != nil {
@@ -125,12 +154,18 @@ Options:
 		os.Exit(1)
 	}
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", ofile.(*bytes.Buffer).Len())
 		err := os.WriteFile(*outputFile, ofile.(*bytes.Buffer).Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q: %s\n", *outputFile, err)
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -153,7 +188,7 @@ func shippedLibs(ctx *context, files ...string) error {
 	// rs contains all notice resolutions.
 	rs := compliance.ResolveNotices(licenseGraph)
 
-	ni, err := compliance.IndexLicenseTexts(ctx.rootFS, licenseGraph, rs)
+	ni, err := compliance.IndexLicenseTextsMatching(ctx.rootFS, licenseGraph, rs, ctx.filter)
 	if err != nil {
 		return fmt.Errorf("Unable to read license text file(s) for %q: %v\n", files, err)
 	}
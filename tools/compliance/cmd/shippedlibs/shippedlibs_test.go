@@ -41,6 +41,8 @@ func Test(t *testing.T) {
 		name        string
 		outDir      string
 		roots       []string
+		include     []string
+		exclude     []string
 		expectedOut []string
 	}{
 		{
@@ -193,6 +195,41 @@ func Test(t *testing.T) {
 			roots:       []string{"lib/libd.so.meta_lic"},
 			expectedOut: []string{"External"},
 		},
+		{
+			condition:   "notice",
+			name:        "apexincludebinary",
+			roots:       []string{"highest.apex.meta_lic"},
+			include:     []string{"**/bin/bin1"},
+			expectedOut: []string{"Android", "Device", "External"},
+		},
+		{
+			condition:   "notice",
+			name:        "apexincludecontainerpath",
+			roots:       []string{"highest.apex.meta_lic"},
+			include:     []string{"**/highest.apex/lib"},
+			expectedOut: []string{"Android", "Device"},
+		},
+		{
+			condition:   "notice",
+			name:        "apexexclude",
+			roots:       []string{"highest.apex.meta_lic"},
+			exclude:     []string{"**/bin/*"},
+			expectedOut: []string{"Android", "Device"},
+		},
+		{
+			condition:   "notice",
+			name:        "containerinclude",
+			roots:       []string{"container.zip.meta_lic"},
+			include:     []string{"out/target/product/*/system/lib"},
+			expectedOut: []string{"Android", "Device"},
+		},
+		{
+			condition:   "reciprocal",
+			name:        "apexnomatch",
+			roots:       []string{"highest.apex.meta_lic"},
+			include:     []string{"**/vendor"},
+			expectedOut: []string{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.condition+" "+tt.name, func(t *testing.T) {
@@ -204,9 +241,14 @@ func Test(t *testing.T) {
 				rootFiles = append(rootFiles, "testdata/"+tt.condition+"/"+r)
 			}
 
-			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir)}
+			filter, err := compliance.NewInstallPathFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("unexpected filter error: got %s, want no error", err)
+			}
+
+			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), filter}
 
-			err := shippedLibs(&ctx, rootFiles...)
+			err = shippedLibs(&ctx, rootFiles...)
 			if err != nil {
 				t.Fatalf("shippedLibs: error = %v, stderr = %v", err, stderr)
 				return
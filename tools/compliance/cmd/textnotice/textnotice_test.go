@@ -17,6 +17,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
@@ -25,6 +26,7 @@ import (
 	"testing"
 
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -693,7 +695,7 @@ func Test(t *testing.T) {
 
 			var deps []string
 
-			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), "", []string{tt.stripPrefix}, "", &deps}
+			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), "", []string{tt.stripPrefix}, "", &deps, compliance.InstallPathFilter{}}
 
 			err := textNotice(&ctx, rootFiles...)
 			if err != nil {
@@ -738,6 +740,58 @@ func Test(t *testing.T) {
 	}
 }
 
+func Test_trace(t *testing.T) {
+	tracer := tracing.Enable("textnotice")
+	defer tracing.Disable()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	var deps []string
+	ctx := context{stdout, stderr, compliance.GetFS(""), "", []string{}, "", &deps, compliance.InstallPathFilter{}}
+
+	err := textNotice(&ctx, "testdata/notice/highest.apex.meta_lic")
+	if err != nil {
+		t.Fatalf("textnotice: error = %v, stderr = %v", err, stderr)
+	}
+
+	var buf bytes.Buffer
+	if err := tracer.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: error = %v", err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+			Ph   string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("json.Unmarshal: error = %v", err)
+	}
+	found := make(map[string]bool)
+	for _, e := range trace.TraceEvents {
+		found[e.Ph+" "+e.Name] = true
+	}
+	for _, name := range []string{
+		"X ReadLicenseGraph",
+		"X read meta_lic",
+		"X ResolveBottomUpConditions",
+		"X ResolveTopDownConditions",
+		"X IndexLicenseTexts",
+		"X render text",
+		"C meta_lic_files",
+		"C meta_lic_bytes",
+		"C license_text_files",
+		"C license_text_bytes",
+	} {
+		if !found[name] {
+			t.Errorf("trace: missing event %q", name)
+		}
+	}
+	if n := tracer.Counter("meta_lic_files"); n != 7 {
+		t.Errorf("meta_lic_files: got %d, want 7", n)
+	}
+}
+
 type matcher interface {
 	isMatch(line string) bool
 	String() string
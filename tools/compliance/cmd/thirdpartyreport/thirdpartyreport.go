@@ -0,0 +1,371 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/response"
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/tracing"
+)
+
+var (
+	failNoneRequested = fmt.Errorf("\nNo license metadata files requested")
+	failNoLicenses    = fmt.Errorf("No licenses found")
+)
+
+type context struct {
+	stdout      io.Writer
+	stderr      io.Writer
+	rootFS      fs.FS
+	format      string
+	product     string
+	stripPrefix []string
+	now         time.Time
+}
+
+func (ctx context) strip(installPath string) string {
+	for _, prefix := range ctx.stripPrefix {
+		if strings.HasPrefix(installPath, prefix) {
+			p := strings.TrimPrefix(installPath, prefix)
+			if 0 == len(p) {
+				continue
+			}
+			return p
+		}
+	}
+	return installPath
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func main() {
+	var expandedArgs []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			f, err := os.Open(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			respArgs, err := response.ReadRspFile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			expandedArgs = append(expandedArgs, respArgs...)
+		} else {
+			expandedArgs = append(expandedArgs, arg)
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s {options} file.meta_lic {file.meta_lic...}
+
+Outputs a report of the third-party projects shipped by the product
+rooted at the given license metadata files, most stale first.
+
+For each project with a third_party section in its METADATA file, the
+report lists the project, name, version, last upgrade date, age in days,
+download URL and license type. Projects without a valid last upgrade
+date sort first, followed by the oldest upgrade dates.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	format := flags.String("format", "csv", "Output format: csv, json or html.")
+	outputFile := flags.String("o", "-", "Where to write the report. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	product := flags.String("product", "", "The name of the product for the report.")
+	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+
+	flags.Parse(expandedArgs)
+
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	// Must specify at least one root target.
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "csv", "json", "html":
+	default:
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "unknown -format %q: want csv, json or html\n", *format)
+		os.Exit(2)
+	}
+
+	if len(*outputFile) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "must specify file for -o; use - for stdout\n")
+		os.Exit(2)
+	} else {
+		dir, err := filepath.Abs(filepath.Dir(*outputFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot determine path to %q: %s\n", *outputFile, err)
+			os.Exit(1)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read directory %q of %q: %s\n", dir, *outputFile, err)
+			os.Exit(1)
+		}
+		if !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "parent %q of %q is not a directory\n", dir, *outputFile)
+			os.Exit(1)
+		}
+	}
+
+	var ofile io.Writer
+	ofile = os.Stdout
+	var obuf *bytes.Buffer
+	if *outputFile != "-" {
+		obuf = &bytes.Buffer{}
+		ofile = obuf
+	}
+
+	ctx := &context{ofile, os.Stderr, compliance.FS, *format, *product, *stripPrefix, time.Now()}
+
+	err := thirdPartyReport(ctx, flags.Args()...)
+	if err != nil {
+		if err == failNoneRequested {
+			flags.Usage()
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
+		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// projectData describes a row of the report.
+type projectData struct {
+	Project         string `json:"project"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	LastUpgradeDate string `json:"last_upgrade_date"`
+	AgeDays         *int   `json:"age_days"`
+	DownloadURL     string `json:"download_url"`
+	LicenseType     string `json:"license_type"`
+}
+
+// reportData describes the JSON report.
+type reportData struct {
+	Product     string        `json:"product,omitempty"`
+	GeneratedAt string        `json:"generated_at"`
+	Projects    []projectData `json:"projects"`
+}
+
+// thirdPartyReport implements the thirdpartyreport utility.
+func thirdPartyReport(ctx *context, files ...string) error {
+	if len(files) < 1 {
+		return failNoneRequested
+	}
+
+	// Read the license graph from the license metadata files (*.meta_lic).
+	licenseGraph, err := compliance.ReadLicenseGraph(ctx.rootFS, ctx.stderr, files)
+	if err != nil {
+		return fmt.Errorf("Unable to read license metadata file(s) %q: %w\n", files, err)
+	}
+	if licenseGraph == nil {
+		return failNoLicenses
+	}
+
+	// Identify the projects of the shipped nodes.
+	projectSet := make(map[string]struct{})
+	for tn := range compliance.ShippedNodes(licenseGraph) {
+		for _, p := range tn.Projects() {
+			projectSet[p] = struct{}{}
+		}
+	}
+	projects := make([]string, 0, len(projectSet))
+	for p := range projectSet {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+
+	pms, err := projectmetadata.NewIndex(ctx.rootFS).MetadataForProjects(projects...)
+	if err != nil {
+		return fmt.Errorf("Unable to read project metadata file(s) for %q: %w\n", files, err)
+	}
+
+	type row struct {
+		data  projectData
+		date  time.Time
+		dated bool
+	}
+	rows := make([]row, 0, len(pms))
+	for _, pm := range pms {
+		if !pm.IsThirdParty() {
+			continue
+		}
+		r := row{data: projectData{
+			Project:     ctx.strip(pm.Project()),
+			Name:        pm.Name(),
+			Version:     pm.Version(),
+			DownloadURL: pm.UrlsByTypeName().DownloadUrl(),
+			LicenseType: pm.LicenseType(),
+		}}
+		r.date, r.dated = pm.LastUpgradeDate()
+		if r.dated {
+			age := int(ctx.now.Sub(r.date).Hours() / 24)
+			r.data.LastUpgradeDate = r.date.Format("2006-01-02")
+			r.data.AgeDays = &age
+		}
+		rows = append(rows, r)
+	}
+
+	// Most stale first: undated projects, then the oldest upgrades.
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].dated != rows[j].dated {
+			return !rows[i].dated
+		}
+		if !rows[i].date.Equal(rows[j].date) {
+			return rows[i].date.Before(rows[j].date)
+		}
+		return rows[i].data.Project < rows[j].data.Project
+	})
+
+	report := reportData{
+		Product:     ctx.product,
+		GeneratedAt: ctx.now.UTC().Format(time.RFC3339),
+		Projects:    make([]projectData, 0, len(rows)),
+	}
+	for _, r := range rows {
+		report.Projects = append(report.Projects, r.data)
+	}
+
+	switch ctx.format {
+	case "json":
+		enc := json.NewEncoder(ctx.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "html":
+		writeHTML(ctx.stdout, report)
+		return nil
+	}
+	return writeCSV(ctx.stdout, report)
+}
+
+// ageString returns the age in days of `pd` or the empty string if unknown.
+func ageString(pd projectData) string {
+	if pd.AgeDays == nil {
+		return ""
+	}
+	return strconv.Itoa(*pd.AgeDays)
+}
+
+// writeCSV outputs `report` as comma-separated values with a header row.
+func writeCSV(w io.Writer, report reportData) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"project", "name", "version", "last_upgrade_date", "age_days", "download_url", "license_type"})
+	for _, pd := range report.Projects {
+		cw.Write([]string{pd.Project, pd.Name, pd.Version, pd.LastUpgradeDate, ageString(pd), pd.DownloadURL, pd.LicenseType})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeHTML outputs `report` as a self-contained html page.
+func writeHTML(w io.Writer, report reportData) {
+	title := "Third-party projects"
+	if len(report.Product) > 0 {
+		title += " in " + report.Product
+	}
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, "<html><head>")
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintln(w, "<style type=\"text/css\">")
+	fmt.Fprintln(w, "table { border-collapse: collapse; }")
+	fmt.Fprintln(w, "th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; }")
+	fmt.Fprintln(w, "td.age { text-align: right; }")
+	fmt.Fprintln(w, "</style>")
+	fmt.Fprintln(w, "</head><body>")
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(title))
+	fmt.Fprintf(w, "<p>Generated %s. Most stale first.</p>\n", html.EscapeString(report.GeneratedAt))
+	fmt.Fprintln(w, "<table>")
+	fmt.Fprintln(w, "<tr><th>Project</th><th>Name</th><th>Version</th><th>Last upgrade</th><th>Age (days)</th><th>Download URL</th><th>License type</th></tr>")
+	for _, pd := range report.Projects {
+		url := html.EscapeString(pd.DownloadURL)
+		if isWebURL(pd.DownloadURL) {
+			url = fmt.Sprintf("<a href=\"%s\">%s</a>", url, url)
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td class=\"age\">%s</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(pd.Project), html.EscapeString(pd.Name), html.EscapeString(pd.Version),
+			html.EscapeString(pd.LastUpgradeDate), ageString(pd), url, html.EscapeString(pd.LicenseType))
+	}
+	fmt.Fprintln(w, "</table>")
+	fmt.Fprintln(w, "</body></html>")
+}
+
+// isWebURL returns true when `u` is an http or https url, which alone are
+// safe to link since METADATA could give e.g. a javascript: url.
+func isWebURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	return (scheme == "http" || scheme == "https") && len(parsed.Host) > 0
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/tools/compliance/testfs"
+)
+
+// newTestFS returns a product shipping 3 third-party projects and 2 first-party projects,
+// one of them described only by a package.json.
+func newTestFS() *testfs.TestFS {
+	const license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	dep := func(file, annotation string) string {
+		return "deps: {\n  file: \"" + file + "\"\n  annotations: \"" + annotation + "\"\n}\n"
+	}
+	project := func(name string) string {
+		return "projects: \"" + name + "\"\n"
+	}
+	return &testfs.TestFS{
+		"product.meta_lic": []byte(license + "is_container: true\n" + project("build/product") +
+			dep("old.meta_lic", "static") + dep("new.meta_lic", "static") + dep("undated.meta_lic", "static") +
+			dep("framework.meta_lic", "static") + dep("web.meta_lic", "static") + dep("testonly.meta_lic", "test")),
+		"old.meta_lic":       []byte(license + project("external/old")),
+		"new.meta_lic":       []byte(license + project("external/new")),
+		"undated.meta_lic":   []byte(license + project("external/undated")),
+		"framework.meta_lic": []byte(license + project("frameworks/base")),
+		"web.meta_lic":       []byte(license + project("tools/web")),
+		"testonly.meta_lic":  []byte(license + project("external/testonly")),
+		"external/old/METADATA": []byte(`name: "old" third_party {
+  url { type: GIT value: "https://example.com/old.git" }
+  version: "0.9"
+  license_type: NOTICE
+  last_upgrade_date { year: 2020 month: 5 day: 22 }
+}`),
+		"external/new/METADATA": []byte(`name: "new" third_party {
+  url { type: HOMEPAGE value: "https://example.com/new" }
+  url { type: ARCHIVE value: "https://example.com/new.tar.gz" }
+  version: "3.1"
+  license_type: RECIPROCAL
+  last_upgrade_date { year: 2024 month: 5 day: 12 }
+}`),
+		"external/undated/METADATA": []byte(`name: "undated" third_party {
+  version: "1.0"
+}`),
+		"frameworks/base/METADATA": []byte(`name: "framework"`),
+		"tools/web/package.json":   []byte(`{"name": "web", "version": "1.2.0", "homepage": "https://web.example/"}`),
+		"external/testonly/METADATA": []byte(`name: "testonly" third_party {
+  version: "0.1"
+  last_upgrade_date { year: 2010 month: 1 day: 1 }
+}`),
+	}
+}
+
+func Test_csv(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	now := time.Date(2024, time.May, 22, 12, 0, 0, 0, time.UTC)
+	ctx := &context{stdout, stderr, newTestFS(), "csv", "", nil, now}
+	if err := thirdPartyReport(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("thirdpartyreport: error = %v, stderr = %v", err, stderr)
+	}
+	records, err := csv.NewReader(stdout).ReadAll()
+	if err != nil {
+		t.Fatalf("thirdpartyreport: invalid csv %s: %s", err, stdout)
+	}
+	expected := []string{
+		"project,name,version,last_upgrade_date,age_days,download_url,license_type",
+		"external/undated,undated,1.0,,,,",
+		"external/old,old,0.9,2020-05-22,1461,https://example.com/old.git,NOTICE",
+		"external/new,new,3.1,2024-05-12,10,,RECIPROCAL",
+	}
+	actual := make([]string, 0, len(records))
+	for _, r := range records {
+		actual = append(actual, strings.Join(r, ","))
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("thirdpartyreport: got %q, want %q", actual, expected)
+	}
+}
+
+func Test_json(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	now := time.Date(2024, time.May, 22, 12, 0, 0, 0, time.UTC)
+	ctx := &context{stdout, stderr, newTestFS(), "json", "fictional", []string{"external/"}, now}
+	if err := thirdPartyReport(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("thirdpartyreport: error = %v, stderr = %v", err, stderr)
+	}
+	var report reportData
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("thirdpartyreport: invalid json %s: %s", err, stdout)
+	}
+	if report.Product != "fictional" || report.GeneratedAt != "2024-05-22T12:00:00Z" {
+		t.Errorf("thirdpartyreport: unexpected product %q generated at %q", report.Product, report.GeneratedAt)
+	}
+	if len(report.Projects) != 3 {
+		t.Fatalf("thirdpartyreport: got %d projects, want 3: %+v", len(report.Projects), report.Projects)
+	}
+	if report.Projects[0].Project != "undated" || report.Projects[0].AgeDays != nil {
+		t.Errorf("thirdpartyreport: got first project %+v, want undated without age", report.Projects[0])
+	}
+	if report.Projects[1].Project != "old" || report.Projects[1].AgeDays == nil || *report.Projects[1].AgeDays != 1461 {
+		t.Errorf("thirdpartyreport: got second project %+v, want old aged 1461 days", report.Projects[1])
+	}
+}
+
+func Test_html(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	now := time.Date(2024, time.May, 22, 12, 0, 0, 0, time.UTC)
+	ctx := &context{stdout, stderr, newTestFS(), "html", "<fictional>", nil, now}
+	if err := thirdPartyReport(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("thirdpartyreport: error = %v, stderr = %v", err, stderr)
+	}
+	out := stdout.String()
+	for _, expected := range []string{
+		"<title>Third-party projects in &lt;fictional&gt;</title>",
+		`<a href="https://example.com/old.git">https://example.com/old.git</a>`,
+		`<td class="age">1461</td>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("thirdpartyreport: missing %q in %s", expected, out)
+		}
+	}
+	if strings.Index(out, "external/undated") > strings.Index(out, "external/old") {
+		t.Errorf("thirdpartyreport: want undated project before old project in %s", out)
+	}
+	if strings.Contains(out, "testonly") || strings.Contains(out, "framework") {
+		t.Errorf("thirdpartyreport: unexpected unshipped or first-party project in %s", out)
+	}
+}
+
+func Test_htmlLinks(t *testing.T) {
+	report := reportData{Projects: []projectData{
+		{Project: "web", DownloadURL: "HTTPS://example.com/web"},
+		{Project: "script", DownloadURL: "javascript:alert(1)"},
+		{Project: "data", DownloadURL: "data:text/html,<b>hi</b>"},
+		{Project: "relative", DownloadURL: "//example.com/relative"},
+	}}
+	var buf bytes.Buffer
+	writeHTML(&buf, report)
+	out := buf.String()
+	if !strings.Contains(out, `<a href="HTTPS://example.com/web">`) {
+		t.Errorf("thirdpartyreport: missing link to https url in %s", out)
+	}
+	if n := strings.Count(out, "<a "); n != 1 {
+		t.Errorf("thirdpartyreport: got %d links, want only the https url linked in %s", n, out)
+	}
+	for _, expected := range []string{
+		"<td>javascript:alert(1)</td>",
+		"<td>data:text/html,&lt;b&gt;hi&lt;/b&gt;</td>",
+		"<td>//example.com/relative</td>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("thirdpartyreport: missing plain text %q in %s", expected, out)
+		}
+	}
+}
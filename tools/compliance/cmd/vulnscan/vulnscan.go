@@ -0,0 +1,608 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"android/soong/response"
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/osv"
+	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/tracing"
+)
+
+var (
+	failNoneRequested = fmt.Errorf("\nNo license metadata files requested")
+	failNoLicenses    = fmt.Errorf("No licenses found")
+
+	// fallbackEcosystems maps the fallback metadata files to the OSV
+	// ecosystem of the projects they describe.
+	fallbackEcosystems = map[string]string{
+		"Cargo.toml":   "crates.io",
+		"go.mod":       "Go",
+		"package.json": "npm",
+		"pom.xml":      "Maven",
+	}
+)
+
+const (
+	// NOASSERTION is the SPDX value for unknown information.
+	NOASSERTION = "NOASSERTION"
+
+	// advisoryURL locates an advisory by ID in the public OSV database.
+	advisoryURL = "https://osv.dev/vulnerability/"
+)
+
+type context struct {
+	stdout      io.Writer
+	stderr      io.Writer
+	rootFS      fs.FS
+	osvDir      string
+	format      string
+	product     string
+	stripPrefix []string
+	now         time.Time
+}
+
+func (ctx context) strip(installPath string) string {
+	for _, prefix := range ctx.stripPrefix {
+		if strings.HasPrefix(installPath, prefix) {
+			p := strings.TrimPrefix(installPath, prefix)
+			if 0 == len(p) {
+				continue
+			}
+			return p
+		}
+	}
+	return installPath
+}
+
+// newMultiString creates a flag that allows multiple values in an array.
+func newMultiString(flags *flag.FlagSet, name, usage string) *multiString {
+	var f multiString
+	flags.Var(&f, name, usage)
+	return &f
+}
+
+// multiString implements the flag `Value` interface for multiple strings.
+type multiString []string
+
+func (ms *multiString) String() string     { return strings.Join(*ms, ", ") }
+func (ms *multiString) Set(s string) error { *ms = append(*ms, s); return nil }
+
+func main() {
+	var expandedArgs []string
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "@") {
+			f, err := os.Open(strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			respArgs, err := response.ReadRspFile(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			expandedArgs = append(expandedArgs, respArgs...)
+		} else {
+			expandedArgs = append(expandedArgs, arg)
+		}
+	}
+
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s -osv dir {options} file.meta_lic {file.meta_lic...}
+
+Matches the projects of the shipped targets of the product rooted at
+the given license metadata files against a local dump of OSV advisories
+without network access.
+
+The -osv directory holds OSV advisories as *.json files, each with one
+advisory or a list of advisories. A project matches an advisory by the
+name and version in its METADATA file against the package name, the
+package url, or the git repository and commit or tag of the advisory.
+Names only match within the ecosystem of a project described by a
+Cargo.toml, go.mod, package.json or pom.xml file. Other projects have no
+known ecosystem, so their name matches of advisories for an ecosystem
+have low confidence.
+
+Outputs one line per affected target with the target, the colon-separated
+install paths, and the colon-separated advisory IDs with a "?" suffix
+for low confidence matches. When -format json,
+spdx or cyclonedx given, outputs the affected targets and advisories as
+JSON, as SPDX 2.3 JSON with security references and annotations, or as a
+CycloneDX 1.5 JSON vulnerability report.
+
+Options:
+`, filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+
+	osvDir := flags.String("osv", "", "Directory of OSV advisory json files. (required)")
+	format := flags.String("format", "text", "Output format: text, json, spdx or cyclonedx.")
+	outputFile := flags.String("o", "-", "Where to write the report. (default stdout)")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
+	product := flags.String("product", "", "The name of the product for spdx and cyclonedx documents.")
+	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
+
+	flags.Parse(expandedArgs)
+
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	// Must specify at least one root target.
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if len(*osvDir) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "must specify the -osv directory\n")
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "text", "json", "spdx", "cyclonedx":
+	default:
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "unknown -format %q: want text, json, spdx or cyclonedx\n", *format)
+		os.Exit(2)
+	}
+
+	if len(*outputFile) == 0 {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "must specify file for -o; use - for stdout\n")
+		os.Exit(2)
+	} else {
+		dir, err := filepath.Abs(filepath.Dir(*outputFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot determine path to %q: %s\n", *outputFile, err)
+			os.Exit(1)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot read directory %q of %q: %s\n", dir, *outputFile, err)
+			os.Exit(1)
+		}
+		if !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "parent %q of %q is not a directory\n", dir, *outputFile)
+			os.Exit(1)
+		}
+	}
+
+	var ofile io.Writer
+	ofile = os.Stdout
+	var obuf *bytes.Buffer
+	if *outputFile != "-" {
+		obuf = &bytes.Buffer{}
+		ofile = obuf
+	}
+
+	ctx := &context{ofile, os.Stderr, compliance.FS, *osvDir, *format, *product, *stripPrefix, time.Now()}
+
+	err := vulnScan(ctx, flags.Args()...)
+	if err != nil {
+		if err == failNoneRequested {
+			flags.Usage()
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
+		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write output to %q from %q: %s\n", *outputFile, os.Getenv("PWD"), err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// advisoryData describes an advisory affecting a target.
+type advisoryData struct {
+	ID       string         `json:"id"`
+	Aliases  []string       `json:"aliases,omitempty"`
+	Summary  string         `json:"summary,omitempty"`
+	Match    string         `json:"match"`
+	Severity []osv.Severity `json:"severity,omitempty"`
+
+	// LowConfidence marks a name match that may be a different package of
+	// the same name in another ecosystem.
+	LowConfidence bool `json:"low_confidence,omitempty"`
+}
+
+// findingData describes a shipped target affected by advisories.
+type findingData struct {
+	Target      string         `json:"target"`
+	Installed   []string       `json:"installed"`
+	Project     string         `json:"project"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	DownloadURL string         `json:"download_url,omitempty"`
+	Advisories  []advisoryData `json:"advisories"`
+}
+
+// vulnScan implements the vulnscan utility.
+func vulnScan(ctx *context, files ...string) error {
+	if len(files) < 1 {
+		return failNoneRequested
+	}
+
+	db, err := osv.Load(ctx.rootFS, ctx.osvDir)
+	if err != nil {
+		return err
+	}
+
+	// Read the license graph from the license metadata files (*.meta_lic).
+	licenseGraph, err := compliance.ReadLicenseGraph(ctx.rootFS, ctx.stderr, files)
+	if err != nil {
+		return fmt.Errorf("Unable to read license metadata file(s) %q: %w\n", files, err)
+	}
+	if licenseGraph == nil {
+		return failNoLicenses
+	}
+
+	shipped := make(compliance.TargetNodeList, 0)
+	for tn := range compliance.ShippedNodes(licenseGraph) {
+		shipped = append(shipped, tn)
+	}
+	sort.Sort(shipped)
+
+	pmix := projectmetadata.NewIndex(ctx.rootFS)
+	findings := []findingData{}
+	for _, tn := range shipped {
+		pms, err := pmix.MetadataForProjects(tn.Projects()...)
+		if err != nil {
+			return fmt.Errorf("Unable to read project metadata file(s) for %q: %w\n", tn.Name(), err)
+		}
+		for _, pm := range pms {
+			urls := []string{}
+			for _, u := range pm.UrlsByTypeName() {
+				urls = append(urls, u)
+			}
+			matches := db.Match(osv.Project{Name: pm.Name(), Version: pm.Version(), URLs: urls, Ecosystem: ecosystem(pm)})
+			if len(matches) == 0 {
+				continue
+			}
+			installed := []string{}
+			for _, p := range tn.Installed() {
+				installed = append(installed, ctx.strip(p))
+			}
+			f := findingData{
+				Target:      ctx.strip(tn.Name()),
+				Installed:   installed,
+				Project:     ctx.strip(pm.Project()),
+				Name:        pm.Name(),
+				Version:     pm.Version(),
+				DownloadURL: pm.UrlsByTypeName().DownloadUrl(),
+			}
+			for _, m := range matches {
+				f.Advisories = append(f.Advisories, advisoryData{
+					ID:            m.Vulnerability.ID,
+					Aliases:       m.Vulnerability.Aliases,
+					Summary:       m.Vulnerability.Summary,
+					Match:         m.Kind,
+					Severity:      m.Vulnerability.Severity,
+					LowConfidence: m.LowConfidence,
+				})
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	switch ctx.format {
+	case "json":
+		return writeJSON(ctx.stdout, findings)
+	case "spdx":
+		return writeJSON(ctx.stdout, spdxDocument(ctx, files, findings))
+	case "cyclonedx":
+		return writeJSON(ctx.stdout, cycloneDXDocument(ctx, findings))
+	}
+	for _, f := range findings {
+		ids := make([]string, 0, len(f.Advisories))
+		for _, a := range f.Advisories {
+			if a.LowConfidence {
+				ids = append(ids, a.ID+"?")
+			} else {
+				ids = append(ids, a.ID)
+			}
+		}
+		fmt.Fprintf(ctx.stdout, "%s %s %s\n", f.Target, strings.Join(f.Installed, ":"), strings.Join(ids, ":"))
+	}
+	return nil
+}
+
+// writeJSON outputs `v` as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// documentName returns the name of the product or a default.
+func documentName(ctx *context) string {
+	if len(ctx.product) > 0 {
+		return ctx.product
+	}
+	return "vulnscan"
+}
+
+// spdxDoc is the subset of an SPDX 2.3 JSON document output.
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Annotations      []spdxAnnotation  `json:"annotations,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxAnnotation struct {
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxDocument returns an SPDX document with a package for each affected
+// target referencing and annotated with its advisories.
+func spdxDocument(ctx *context, files []string, findings []findingData) spdxDoc {
+	created := ctx.now.UTC().Format(time.RFC3339)
+	hash := sha1.Sum([]byte(strings.Join(files, "") + created))
+	doc := spdxDoc{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              documentName(ctx),
+		DocumentNamespace: "SPDXRef-DOCUMENT-" + hex.EncodeToString(hash[:]),
+		CreationInfo:      spdxCreationInfo{created, []string{"Tool: vulnscan"}},
+		Packages:          []spdxPackage{},
+	}
+	for i, f := range findings {
+		pkg := spdxPackage{
+			Name:             f.Target,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			VersionInfo:      f.Version,
+			DownloadLocation: NOASSERTION,
+			Comment:          "Installed as " + strings.Join(f.Installed, ", "),
+		}
+		if len(f.DownloadURL) > 0 {
+			pkg.DownloadLocation = f.DownloadURL
+		}
+		for _, a := range f.Advisories {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{"SECURITY", "advisory", advisoryURL + a.ID})
+			match := a.Match + " match"
+			if a.LowConfidence {
+				match += ", low confidence"
+			}
+			comment := fmt.Sprintf("%s affects %s %s (%s)", a.ID, f.Name, f.Version, match)
+			if len(a.Summary) > 0 {
+				comment += ": " + a.Summary
+			}
+			pkg.Annotations = append(pkg.Annotations, spdxAnnotation{created, "REVIEW", "Tool: vulnscan", comment})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{"SPDXRef-DOCUMENT", "DESCRIBES", pkg.SPDXID})
+	}
+	return doc
+}
+
+// cdxDoc is the subset of a CycloneDX 1.5 JSON document output.
+type cdxDoc struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component,omitempty"`
+}
+
+type cdxTool struct {
+	Name string `json:"name"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxVulnerability struct {
+	ID          string         `json:"id"`
+	Source      cdxSource      `json:"source"`
+	References  []cdxReference `json:"references,omitempty"`
+	Ratings     []cdxRating    `json:"ratings,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Affects     []cdxAffects   `json:"affects"`
+}
+
+type cdxSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cdxReference struct {
+	ID     string    `json:"id"`
+	Source cdxSource `json:"source"`
+}
+
+type cdxRating struct {
+	Method string `json:"method"`
+	Vector string `json:"vector"`
+}
+
+type cdxAffects struct {
+	Ref string `json:"ref"`
+}
+
+// cdxRatingMethod maps an OSV severity type to a CycloneDX rating method.
+func cdxRatingMethod(s osv.Severity) string {
+	switch s.Type {
+	case "CVSS_V2":
+		return "CVSSv2"
+	case "CVSS_V3":
+		if strings.HasPrefix(s.Score, "CVSS:3.1/") {
+			return "CVSSv31"
+		}
+		return "CVSSv3"
+	case "CVSS_V4":
+		return "CVSSv4"
+	}
+	return "other"
+}
+
+// ecosystem returns the OSV ecosystem of the project described by `pm`, or
+// empty when unknown. Only package manifests name an ecosystem.
+func ecosystem(pm *projectmetadata.ProjectMetadata) string {
+	if !pm.IsFallback() {
+		return ""
+	}
+	return fallbackEcosystems[filepath.Base(pm.Provenance(projectmetadata.FieldName))]
+}
+
+// cycloneDXDocument returns a CycloneDX document with a component for each
+// affected project of each target and a vulnerability for each advisory
+// affecting them.
+func cycloneDXDocument(ctx *context, findings []findingData) cdxDoc {
+	doc := cdxDoc{
+		BOMFormat:       "CycloneDX",
+		SpecVersion:     "1.5",
+		Version:         1,
+		Metadata:        cdxMetadata{Timestamp: ctx.now.UTC().Format(time.RFC3339), Tools: []cdxTool{{"vulnscan"}}},
+		Components:      []cdxComponent{},
+		Vulnerabilities: []cdxVulnerability{},
+	}
+	if len(ctx.product) > 0 {
+		doc.Metadata.Component = &cdxComponent{Type: "device", Name: ctx.product}
+	}
+	vulns := make(map[string]*cdxVulnerability)
+	// affected records the components already affected by each advisory.
+	affected := make(map[string]map[string]bool)
+	var ids []string
+	for _, f := range findings {
+		// A target can have several vulnerable projects, so the bom-ref names
+		// both to stay unique.
+		c := cdxComponent{Type: "library", BOMRef: f.Target + "#" + f.Project, Name: f.Name, Version: f.Version}
+		if len(c.Name) == 0 {
+			c.Name = f.Target
+		}
+		for _, p := range f.Installed {
+			c.Properties = append(c.Properties, cdxProperty{"android:install_path", p})
+		}
+		c.Properties = append(c.Properties, cdxProperty{"android:project", f.Project})
+		for _, a := range f.Advisories {
+			if a.LowConfidence {
+				c.Properties = append(c.Properties, cdxProperty{"android:low_confidence", a.ID})
+			}
+		}
+		doc.Components = append(doc.Components, c)
+		for _, a := range f.Advisories {
+			v, ok := vulns[a.ID]
+			if !ok {
+				v = &cdxVulnerability{
+					ID:          a.ID,
+					Source:      cdxSource{"OSV", advisoryURL + a.ID},
+					Description: a.Summary,
+				}
+				for _, alias := range a.Aliases {
+					v.References = append(v.References, cdxReference{alias, cdxSource{Name: "OSV"}})
+				}
+				for _, s := range a.Severity {
+					v.Ratings = append(v.Ratings, cdxRating{cdxRatingMethod(s), s.Score})
+				}
+				vulns[a.ID] = v
+				affected[a.ID] = make(map[string]bool)
+				ids = append(ids, a.ID)
+			}
+			if !affected[a.ID][c.BOMRef] {
+				affected[a.ID][c.BOMRef] = true
+				v.Affects = append(v.Affects, cdxAffects{c.BOMRef})
+			}
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc.Vulnerabilities = append(doc.Vulnerabilities, *vulns[id])
+	}
+	return doc
+}
@@ -0,0 +1,255 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"android/soong/tools/compliance/osv"
+)
+
+// newTestFS returns a product shipping a vulnerable zlib sharing its name with
+// a vulnerable npm package, a vulnerable git checkout of libbar, a patched
+// libfoo, and a gizmo crate sharing its name with a vulnerable npm package,
+// plus an advisory database.
+func newTestFS() fstest.MapFS {
+	const license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	dep := func(file, annotation string) string {
+		return "deps: {\n  file: \"" + file + "\"\n  annotations: \"" + annotation + "\"\n}\n"
+	}
+	file := func(s string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(s)}
+	}
+	return fstest.MapFS{
+		"product.meta_lic": file(license + "is_container: true\n" +
+			dep("bin/app.meta_lic", "static") + dep("lib/libbar.so.meta_lic", "static") + dep("bin/test.meta_lic", "test")),
+		"bin/app.meta_lic": file(license + "projects: \"frameworks/app\"\n" +
+			"installed: \"out/target/product/fictional/system/bin/app\"\n" +
+			dep("lib/libz.a.meta_lic", "static") + dep("lib/libfoo.a.meta_lic", "static") + dep("lib/libgizmo.a.meta_lic", "static")),
+		"lib/libz.a.meta_lic":     file(license + "projects: \"external/zlib\"\n"),
+		"lib/libfoo.a.meta_lic":   file(license + "projects: \"external/libfoo\"\n"),
+		"lib/libgizmo.a.meta_lic": file(license + "projects: \"external/gizmo\"\n"),
+		"lib/libbar.so.meta_lic":  file(license + "projects: \"external/libbar\"\ninstalled: \"out/target/product/fictional/system/lib/libbar.so\"\n"),
+		"bin/test.meta_lic":       file(license + "projects: \"external/testonly\"\n"),
+		"frameworks/app/METADATA": file(`name: "app"`),
+		"external/zlib/METADATA": file(`name: "zlib" third_party {
+  url { type: GIT value: "https://example.com/zlib.git" }
+  version: "1.2.11"
+}`),
+		"external/libfoo/METADATA": file(`name: "libfoo" third_party { version: "2.1.0" }`),
+		"external/libbar/METADATA": file(`name: "libbar" third_party {
+  url { type: GIT value: "https://github.com/example/libbar" }
+  version: "0123456789abcdef"
+}`),
+		"external/testonly/METADATA": file(`name: "zlib" third_party { version: "1.2.11" }`),
+		"external/gizmo/Cargo.toml":  file("[package]\nname = \"gizmo\"\nversion = \"1.0.0\"\n"),
+		"osv/OSV-0001.json": file(`{
+  "id": "OSV-0001",
+  "aliases": ["CVE-2022-0001"],
+  "summary": "overflow in zlib",
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+  "affected": [{
+    "package": {"name": "zlib"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.12"}]}]
+  }]
+}`),
+		"osv/OSV-0002.json": file(`{
+  "id": "OSV-0002",
+  "affected": [{
+    "package": {"purl": "pkg:github/example/libfoo"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "2.0.0"}]}]
+  }]
+}`),
+		"osv/OSV-0004.json": file(`{
+  "id": "OSV-0004",
+  "summary": "prototype pollution in the gizmo npm package",
+  "affected": [{"package": {"ecosystem": "npm", "name": "gizmo"}, "versions": ["1.0.0"]}]
+}`),
+		"osv/OSV-0005.json": file(`{
+  "id": "OSV-0005",
+  "summary": "denial of service in the zlib npm package",
+  "affected": [{"package": {"ecosystem": "npm", "name": "zlib"}, "versions": ["1.2.11"]}]
+}`),
+		"osv/OSV-0003.json": file(`{
+  "id": "OSV-0003",
+  "summary": "use after free in libbar",
+  "affected": [{
+    "ranges": [{
+      "type": "GIT",
+      "repo": "https://github.com/example/libbar.git",
+      "events": [{"introduced": "0123456789abcdef0123456789abcdef01234567"}]
+    }]
+  }]
+}`),
+	}
+}
+
+func Test_text(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout, stderr, newTestFS(), "osv", "text", "", []string{"out/target/product/fictional/"}, time.Unix(0, 0)}
+	if err := vulnScan(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("vulnscan: error = %v, stderr = %v", err, stderr)
+	}
+	expected := []string{
+		"lib/libbar.so.meta_lic system/lib/libbar.so OSV-0003",
+		"lib/libz.a.meta_lic  OSV-0001:OSV-0005?",
+	}
+	actual := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("vulnscan: got %q, want %q", actual, expected)
+	}
+}
+
+func Test_json(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout, stderr, newTestFS(), "osv", "json", "", nil, time.Unix(0, 0)}
+	if err := vulnScan(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("vulnscan: error = %v, stderr = %v", err, stderr)
+	}
+	var findings []findingData
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		t.Fatalf("vulnscan: invalid json %s: %s", err, stdout)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("vulnscan: got %d findings, want 2: %s", len(findings), stdout)
+	}
+	f := findings[1]
+	if f.Target != "lib/libz.a.meta_lic" || f.Project != "external/zlib" || f.Version != "1.2.11" || f.DownloadURL != "https://example.com/zlib.git" {
+		t.Errorf("vulnscan: unexpected finding %+v", f)
+	}
+	if len(f.Advisories) != 2 || f.Advisories[0].ID != "OSV-0001" || f.Advisories[0].Match != "version" || f.Advisories[0].Aliases[0] != "CVE-2022-0001" || f.Advisories[0].LowConfidence {
+		t.Errorf("vulnscan: unexpected advisories %+v", f.Advisories)
+	}
+	// The npm advisory only shares the name of the METADATA project.
+	if len(f.Advisories) == 2 && (f.Advisories[1].ID != "OSV-0005" || !f.Advisories[1].LowConfidence) {
+		t.Errorf("vulnscan: got advisory %+v, want low confidence OSV-0005", f.Advisories[1])
+	}
+}
+
+func Test_spdx(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout, stderr, newTestFS(), "osv", "spdx", "fictional", nil, time.Unix(0, 0)}
+	if err := vulnScan(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("vulnscan: error = %v, stderr = %v", err, stderr)
+	}
+	var doc spdxDoc
+	if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil {
+		t.Fatalf("vulnscan: invalid json %s: %s", err, stdout)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "fictional" || doc.CreationInfo.Created != "1970-01-01T00:00:00Z" {
+		t.Errorf("vulnscan: unexpected document %+v", doc)
+	}
+	if len(doc.Packages) != 2 || len(doc.Relationships) != 2 {
+		t.Fatalf("vulnscan: got %d packages, %d relationships, want 2 each", len(doc.Packages), len(doc.Relationships))
+	}
+	pkg := doc.Packages[1]
+	if pkg.Name != "lib/libz.a.meta_lic" || pkg.DownloadLocation != "https://example.com/zlib.git" {
+		t.Errorf("vulnscan: unexpected package %+v", pkg)
+	}
+	if len(pkg.ExternalRefs) != 2 || pkg.ExternalRefs[0] != (spdxExternalRef{"SECURITY", "advisory", "https://osv.dev/vulnerability/OSV-0001"}) {
+		t.Errorf("vulnscan: unexpected external refs %+v", pkg.ExternalRefs)
+	}
+	if len(pkg.Annotations) != 2 ||
+		pkg.Annotations[0].Comment != "OSV-0001 affects zlib 1.2.11 (version match): overflow in zlib" ||
+		pkg.Annotations[1].Comment != "OSV-0005 affects zlib 1.2.11 (version match, low confidence): denial of service in the zlib npm package" {
+		t.Errorf("vulnscan: unexpected annotations %+v", pkg.Annotations)
+	}
+}
+
+func Test_cyclonedx(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout, stderr, newTestFS(), "osv", "cyclonedx", "fictional", []string{"out/target/product/fictional/"}, time.Unix(0, 0)}
+	if err := vulnScan(ctx, "product.meta_lic"); err != nil {
+		t.Fatalf("vulnscan: error = %v, stderr = %v", err, stderr)
+	}
+	var doc cdxDoc
+	if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil {
+		t.Fatalf("vulnscan: invalid json %s: %s", err, stdout)
+	}
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || doc.Metadata.Component == nil || doc.Metadata.Component.Name != "fictional" {
+		t.Errorf("vulnscan: unexpected document %+v", doc)
+	}
+	if len(doc.Components) != 2 || doc.Components[0].BOMRef != "lib/libbar.so.meta_lic#external/libbar" || doc.Components[0].Properties[0].Value != "system/lib/libbar.so" {
+		t.Errorf("vulnscan: unexpected components %+v", doc.Components)
+	}
+	checkBOMRefs(t, doc)
+	if p := doc.Components[1].Properties; len(p) != 2 || p[1] != (cdxProperty{"android:low_confidence", "OSV-0005"}) {
+		t.Errorf("vulnscan: got properties %+v, want low confidence OSV-0005", p)
+	}
+	if len(doc.Vulnerabilities) != 3 {
+		t.Fatalf("vulnscan: got %d vulnerabilities, want 3", len(doc.Vulnerabilities))
+	}
+	v := doc.Vulnerabilities[0]
+	if v.ID != "OSV-0001" || v.Affects[0].Ref != "lib/libz.a.meta_lic#external/zlib" || v.Ratings[0].Method != "CVSSv31" || v.References[0].ID != "CVE-2022-0001" {
+		t.Errorf("vulnscan: unexpected vulnerability %+v", v)
+	}
+}
+
+func Test_cyclonedxAffects(t *testing.T) {
+	// One target with two projects affected by the same advisory.
+	advisory := advisoryData{ID: "OSV-0001", Match: osv.MatchVersion}
+	findings := []findingData{
+		{Target: "lib/libz.a.meta_lic", Project: "external/zlib", Name: "zlib", Version: "1.2.11", Advisories: []advisoryData{advisory}},
+		{Target: "lib/libz.a.meta_lic", Project: "external/zlib-ng", Name: "zlib", Version: "1.2.11", Advisories: []advisoryData{advisory}},
+	}
+	doc := cycloneDXDocument(&context{now: time.Unix(0, 0)}, findings)
+	if len(doc.Components) != 2 {
+		t.Errorf("vulnscan: got components %+v, want one per project", doc.Components)
+	}
+	checkBOMRefs(t, doc)
+	expected := []cdxAffects{{"lib/libz.a.meta_lic#external/zlib"}, {"lib/libz.a.meta_lic#external/zlib-ng"}}
+	if len(doc.Vulnerabilities) != 1 || !reflect.DeepEqual(doc.Vulnerabilities[0].Affects, expected) {
+		t.Errorf("vulnscan: got vulnerabilities %+v, want OSV-0001 affecting %v", doc.Vulnerabilities, expected)
+	}
+}
+
+// checkBOMRefs reports duplicate component bom-refs and affects referencing
+// no component in `doc`.
+func checkBOMRefs(t *testing.T, doc cdxDoc) {
+	t.Helper()
+	refs := make(map[string]bool)
+	for _, c := range doc.Components {
+		if refs[c.BOMRef] {
+			t.Errorf("vulnscan: duplicate bom-ref %q", c.BOMRef)
+		}
+		refs[c.BOMRef] = true
+	}
+	for _, v := range doc.Vulnerabilities {
+		for _, a := range v.Affects {
+			if !refs[a.Ref] {
+				t.Errorf("vulnscan: %s affects unknown bom-ref %q", v.ID, a.Ref)
+			}
+		}
+	}
+}
+
+func Test_missingDatabase(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	ctx := &context{stdout, stderr, newTestFS(), "nosuchdir", "text", "", nil, time.Unix(0, 0)}
+	if err := vulnScan(ctx, "product.meta_lic"); err == nil {
+		t.Errorf("vulnscan: got no error for missing database, want error")
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"android/soong/response"
 	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/tracing"
 
 	"github.com/google/blueprint/deptools"
 )
@@ -46,6 +47,7 @@ type context struct {
 	stripPrefix []string
 	title       string
 	deps        *[]string
+	filter      compliance.InstallPathFilter
 }
 
 func (ctx context) strip(installPath string) string {
@@ -114,12 +116,26 @@ Options:
 
 	outputFile := flags.String("o", "-", "Where to write the NOTICE xml or xml.gz file. (default stdout)")
 	depsFile := flags.String("d", "", "Where to write the deps file")
+	traceFile := flags.String("trace", "", "Where to write a Chrome trace-event JSON file of the timing. (default none)")
 	product := flags.String("product", "", "The name of the product for which the notice is generated.")
 	stripPrefix := newMultiString(flags, "strip_prefix", "Prefix to remove from paths. i.e. path to root (multiple allowed)")
 	title := flags.String("title", "", "The title of the notice file.")
+	includeInstalled := newMultiString(flags, "include_installed", "Glob matching install paths to include. i.e. only notices for targets installed there (multiple allowed)")
+	excludeInstalled := newMultiString(flags, "exclude_installed", "Glob matching install paths to exclude. (multiple allowed)")
 
 	flags.Parse(expandedArgs)
 
+	if len(*traceFile) > 0 {
+		tracing.Enable(filepath.Base(os.Args[0]))
+	}
+
+	filter, err := compliance.NewInstallPathFilter(*includeInstalled, *excludeInstalled)
+	if err != nil {
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(2)
+	}
+
 	// Must specify at least one root target.
 	if flags.NArg() == 0 {
 		flags.Usage()
@@ -162,9 +178,9 @@ Options:
 
 	var deps []string
 
-	ctx := &context{ofile, os.Stderr, compliance.FS, *product, *stripPrefix, *title, &deps}
+	ctx := &context{ofile, os.Stderr, compliance.FS, *product, *stripPrefix, *title, &deps, filter}
 
-	err := xmlNotice(ctx, flags.Args()...)
+	err = xmlNotice(ctx, flags.Args()...)
 	if err != nil {
 		if err == failNoneRequested {
 			flags.Usage()
@@ -177,7 +193,9 @@ Options:
 	}
 
 	if *outputFile != "-" {
+		span := tracing.Begin(tracing.CategoryOutput, "write output").Arg("file", *outputFile).Arg("bytes", obuf.Len())
 		err := os.WriteFile(*outputFile, obuf.Bytes(), 0666)
+		span.End()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "could not write output to %q: %s\n", *outputFile, err)
 			os.Exit(1)
@@ -190,6 +208,10 @@ Options:
 			os.Exit(1)
 		}
 	}
+	if err := tracing.WriteFile(*traceFile); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write trace to %q: %s\n", *traceFile, err)
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -212,11 +234,13 @@ func xmlNotice(ctx *context, files ...string) error {
 	// rs contains all notice resolutions.
 	rs := compliance.ResolveNotices(licenseGraph)
 
-	ni, err := compliance.IndexLicenseTexts(ctx.rootFS, licenseGraph, rs)
+	ni, err := compliance.IndexLicenseTextsMatching(ctx.rootFS, licenseGraph, rs, ctx.filter)
 	if err != nil {
 		return fmt.Errorf("Unable to read license text file(s) for %q: %v\n", files, err)
 	}
 
+	defer tracing.Begin(tracing.CategoryOutput, "render xml").End()
+
 	fmt.Fprintln(ctx.stdout, "<?xml version=\"1.0\" encoding=\"utf-8\"?>")
 	fmt.Fprintln(ctx.stdout, "<licenses>")
 
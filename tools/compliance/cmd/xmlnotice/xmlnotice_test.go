@@ -590,7 +590,7 @@ func Test(t *testing.T) {
 
 			var deps []string
 
-			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), "", []string{tt.stripPrefix}, "", &deps}
+			ctx := context{stdout, stderr, compliance.GetFS(tt.outDir), "", []string{tt.stripPrefix}, "", &deps, compliance.InstallPathFilter{}}
 
 			err := xmlNotice(&ctx, rootFiles...)
 			if err != nil {
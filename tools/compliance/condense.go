@@ -0,0 +1,359 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GroupBy identifies how CondenseGraph groups target nodes.
+type GroupBy int
+
+const (
+	// GroupByProject groups target nodes by their projects.
+	GroupByProject GroupBy = iota + 1
+
+	// GroupByPackage groups target nodes by their package names.
+	GroupByPackage
+)
+
+var (
+	// RecognizedGroupBy maps the names of the ways to group target nodes to GroupBy.
+	RecognizedGroupBy = map[string]GroupBy{
+		"project": GroupByProject,
+		"package": GroupByPackage,
+	}
+)
+
+// ParseGroupBy returns the GroupBy named `name`.
+func ParseGroupBy(name string) (GroupBy, error) {
+	if g, ok := RecognizedGroupBy[name]; ok {
+		return g, nil
+	}
+	return 0, fmt.Errorf("unknown group_by %q: want project or package", name)
+}
+
+// String returns the name of the GroupBy.
+func (g GroupBy) String() string {
+	switch g {
+	case GroupByProject:
+		return "project"
+	case GroupByPackage:
+		return "package"
+	}
+	return fmt.Sprintf("GroupBy(%d)", int(g))
+}
+
+// groupName returns the name of the group for `tn`.
+//
+// Target nodes without a project or package name form a group of one named
+// for the target.
+func (g GroupBy) groupName(tn *TargetNode) string {
+	var name string
+	switch g {
+	case GroupByProject:
+		projects := tn.Projects()
+		sort.Strings(projects)
+		name = strings.Join(projects, ",")
+	case GroupByPackage:
+		name = tn.PackageName()
+	}
+	if len(name) == 0 {
+		return tn.Name()
+	}
+	return name
+}
+
+// CondensedNode describes a group of target nodes in a condensed graph.
+type CondensedNode struct {
+	// name identifies the group.
+	name string
+
+	// targets lists the target nodes in the group.
+	targets TargetNodeList
+
+	// conditions is the union of the license conditions of the target nodes.
+	conditions LicenseConditionSet
+}
+
+// Name returns the project or package name identifying the group.
+func (cn *CondensedNode) Name() string {
+	return cn.name
+}
+
+// Targets returns the target nodes in the group ordered by name.
+func (cn *CondensedNode) Targets() TargetNodeList {
+	return append(TargetNodeList{}, cn.targets...)
+}
+
+// LicenseConditions returns the union of the license conditions of the
+// target nodes in the group.
+func (cn *CondensedNode) LicenseConditions() LicenseConditionSet {
+	return cn.conditions
+}
+
+// CondensedNodeList orders lists of condensed nodes by name.
+type CondensedNodeList []*CondensedNode
+
+// Len returns the count of elements in the list.
+func (l CondensedNodeList) Len() int { return len(l) }
+
+// Swap rearranges 2 elements so that each occupies the other's former position.
+func (l CondensedNodeList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// Less returns true when the `i`th element is lexicographically less than the `j`th.
+func (l CondensedNodeList) Less(i, j int) bool {
+	return l[i].name < l[j].name
+}
+
+// Names returns the names of the nodes in the list.
+func (l CondensedNodeList) Names() []string {
+	result := make([]string, 0, len(l))
+	for _, cn := range l {
+		result = append(result, cn.name)
+	}
+	return result
+}
+
+// CondensedEdge describes the edges between the target nodes of 2 different
+// groups in a condensed graph.
+type CondensedEdge struct {
+	// target is the group depending on `dependency`.
+	target *CondensedNode
+
+	// dependency is the group depended on by `target`.
+	dependency *CondensedNode
+
+	// annotations is the union of the annotations of the condensed edges.
+	annotations TargetEdgeAnnotations
+}
+
+// Target returns the group depending on the dependency.
+func (ce *CondensedEdge) Target() *CondensedNode {
+	return ce.target
+}
+
+// Dependency returns the group depended on by the target.
+func (ce *CondensedEdge) Dependency() *CondensedNode {
+	return ce.dependency
+}
+
+// Annotations returns the union of the annotations of the edges condensed
+// into `ce`.
+func (ce *CondensedEdge) Annotations() TargetEdgeAnnotations {
+	return ce.annotations
+}
+
+// String returns a human-readable string representation of the edge.
+func (ce *CondensedEdge) String() string {
+	annotations := ce.annotations.AsList()
+	sort.Strings(annotations)
+	return fmt.Sprintf("%s -[%s]> %s", ce.target.name, strings.Join(annotations, ", "), ce.dependency.name)
+}
+
+// CondensedEdgeList orders lists of condensed edges by target then dependency.
+type CondensedEdgeList []*CondensedEdge
+
+// Len returns the count of elements in the list.
+func (l CondensedEdgeList) Len() int { return len(l) }
+
+// Swap rearranges 2 elements so that each occupies the other's former position.
+func (l CondensedEdgeList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// Less returns true when the `i`th element is lexicographically less than the `j`th.
+func (l CondensedEdgeList) Less(i, j int) bool {
+	if l[i].target.name == l[j].target.name {
+		return l[i].dependency.name < l[j].dependency.name
+	}
+	return l[i].target.name < l[j].target.name
+}
+
+// CondensedResolution describes the resolutions attaching to the target nodes
+// of one group and acting on the target nodes of another or the same group.
+type CondensedResolution struct {
+	// attachesTo is the group the resolutions attach to.
+	attachesTo *CondensedNode
+
+	// actsOn is the group the resolutions act on.
+	actsOn *CondensedNode
+
+	// cs is the union of the conditions resolved.
+	cs LicenseConditionSet
+}
+
+// AttachesTo returns the group the resolutions attach to.
+func (cr CondensedResolution) AttachesTo() *CondensedNode {
+	return cr.attachesTo
+}
+
+// ActsOn returns the group the resolutions act on.
+func (cr CondensedResolution) ActsOn() *CondensedNode {
+	return cr.actsOn
+}
+
+// Resolves returns the union of the conditions resolved.
+func (cr CondensedResolution) Resolves() LicenseConditionSet {
+	return cr.cs
+}
+
+// CondensedResolutionList orders lists of condensed resolutions by
+// attachesTo then actsOn.
+type CondensedResolutionList []CondensedResolution
+
+// Len returns the count of elements in the list.
+func (l CondensedResolutionList) Len() int { return len(l) }
+
+// Swap rearranges 2 elements so that each occupies the other's former position.
+func (l CondensedResolutionList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// Less returns true when the `i`th element is lexicographically less than the `j`th.
+func (l CondensedResolutionList) Less(i, j int) bool {
+	if l[i].attachesTo.name == l[j].attachesTo.name {
+		return l[i].actsOn.name < l[j].actsOn.name
+	}
+	return l[i].attachesTo.name < l[j].attachesTo.name
+}
+
+// CondensedGraph describes a license graph with the target nodes condensed
+// into groups by project or by package name.
+//
+// The license conditions of each group are the union of the conditions of
+// its target nodes, and the annotations of each edge between groups are the
+// union of the annotations of the edges between their target nodes. Edges
+// between target nodes in the same group disappear.
+type CondensedGraph struct {
+	// groupBy identifies how the target nodes are grouped.
+	groupBy GroupBy
+
+	// nodes lists the groups ordered by name.
+	nodes CondensedNodeList
+
+	// edges lists the edges between groups ordered by target then dependency.
+	edges CondensedEdgeList
+
+	// groups maps target nodes to their groups.
+	groups map[*TargetNode]*CondensedNode
+
+	// roots lists the groups of the root target nodes ordered by name.
+	roots CondensedNodeList
+}
+
+// CondenseGraph returns the license graph `lg` condensed by `groupBy`.
+func CondenseGraph(lg *LicenseGraph, groupBy GroupBy) *CondensedGraph {
+	cg := &CondensedGraph{
+		groupBy: groupBy,
+		groups:  make(map[*TargetNode]*CondensedNode),
+	}
+
+	targets := lg.Targets()
+	sort.Sort(targets)
+
+	byName := make(map[string]*CondensedNode)
+	for _, tn := range targets {
+		name := groupBy.groupName(tn)
+		cn, ok := byName[name]
+		if !ok {
+			cn = &CondensedNode{name: name}
+			byName[name] = cn
+			cg.nodes = append(cg.nodes, cn)
+		}
+		cn.targets = append(cn.targets, tn)
+		cn.conditions = cn.conditions.Union(tn.LicenseConditions())
+		cg.groups[tn] = cn
+	}
+	sort.Sort(cg.nodes)
+
+	type edgeKey struct {
+		target, dependency *CondensedNode
+	}
+	edges := make(map[edgeKey]*CondensedEdge)
+	for _, e := range lg.Edges() {
+		key := edgeKey{cg.groups[e.target], cg.groups[e.dependency]}
+		if key.target == key.dependency {
+			continue
+		}
+		ce, ok := edges[key]
+		if !ok {
+			ce = &CondensedEdge{key.target, key.dependency, newEdgeAnnotations()}
+			edges[key] = ce
+			cg.edges = append(cg.edges, ce)
+		}
+		for ann := range e.annotations.annotations {
+			ce.annotations.annotations[ann] = struct{}{}
+		}
+	}
+	sort.Sort(cg.edges)
+
+	seen := make(map[*CondensedNode]struct{})
+	for _, tn := range lg.Roots() {
+		cn := cg.groups[tn]
+		if _, ok := seen[cn]; ok {
+			continue
+		}
+		seen[cn] = struct{}{}
+		cg.roots = append(cg.roots, cn)
+	}
+	sort.Sort(cg.roots)
+
+	return cg
+}
+
+// GroupBy returns how the target nodes are grouped.
+func (cg *CondensedGraph) GroupBy() GroupBy {
+	return cg.groupBy
+}
+
+// Nodes returns the groups ordered by name.
+func (cg *CondensedGraph) Nodes() CondensedNodeList {
+	return append(CondensedNodeList{}, cg.nodes...)
+}
+
+// Edges returns the edges between groups ordered by target then dependency.
+func (cg *CondensedGraph) Edges() CondensedEdgeList {
+	return append(CondensedEdgeList{}, cg.edges...)
+}
+
+// Roots returns the groups containing the root target nodes ordered by name.
+func (cg *CondensedGraph) Roots() CondensedNodeList {
+	return append(CondensedNodeList{}, cg.roots...)
+}
+
+// Group returns the group containing `tn` or nil if `tn` is not in the graph.
+func (cg *CondensedGraph) Group(tn *TargetNode) *CondensedNode {
+	return cg.groups[tn]
+}
+
+// CondenseResolutions returns the resolutions in `rs` condensed into the
+// groups of the graph ordered by attachesTo then actsOn.
+func (cg *CondensedGraph) CondenseResolutions(rs ResolutionSet) CondensedResolutionList {
+	type resolutionKey struct {
+		attachesTo, actsOn *CondensedNode
+	}
+	cs := make(map[resolutionKey]LicenseConditionSet)
+	for attachesTo, as := range rs {
+		for actsOn, resolves := range as {
+			key := resolutionKey{cg.groups[attachesTo], cg.groups[actsOn]}
+			cs[key] = cs[key].Union(resolves)
+		}
+	}
+	result := make(CondensedResolutionList, 0, len(cs))
+	for key, resolves := range cs {
+		result = append(result, CondensedResolution{key.attachesTo, key.actsOn, resolves})
+	}
+	sort.Sort(result)
+	return result
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name          string
+		expected      GroupBy
+		expectedError string
+	}{
+		{name: "project", expected: GroupByProject},
+		{name: "package", expected: GroupByPackage},
+		{name: "target", expectedError: `unknown group_by "target"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseGroupBy(tt.name)
+			if len(tt.expectedError) > 0 {
+				if err == nil {
+					t.Fatalf("ParseGroupBy(%q): got %s, want error containing %q", tt.name, actual, tt.expectedError)
+				}
+				if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("ParseGroupBy(%q): got error %q, want error containing %q", tt.name, err.Error(), tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGroupBy(%q): unexpected error %s", tt.name, err)
+			}
+			if actual != tt.expected {
+				t.Errorf("ParseGroupBy(%q): got %s, want %s", tt.name, actual, tt.expected)
+			}
+			if actual.String() != tt.name {
+				t.Errorf("%s.String(): got %q, want %q", actual, actual.String(), tt.name)
+			}
+		})
+	}
+}
+
+func TestCondenseGraph(t *testing.T) {
+	tests := []struct {
+		name          string
+		groupBy       GroupBy
+		roots         []string
+		edges         []annotated
+		expectedNodes []string
+		expectedEdges []string
+		expectedRoots []string
+	}{
+		{
+			name:    "package",
+			groupBy: GroupByPackage,
+			roots:   []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+				{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"dynamic"}},
+				{"apacheLib.meta_lic", "gplLib.meta_lic", []string{"static"}},
+				{"apacheBin.meta_lic", "lgplLib.meta_lic", []string{"static"}},
+			},
+			expectedNodes: []string{
+				"Android:notice",
+				"Free Library:restricted_if_statically_linked",
+				"Free Software:restricted",
+			},
+			expectedEdges: []string{
+				"Android -[static]> Free Library",
+				"Android -[dynamic, static]> Free Software",
+			},
+			expectedRoots: []string{"Android"},
+		},
+		{
+			name:    "samepackage",
+			groupBy: GroupByPackage,
+			roots:   []string{"apacheBin.meta_lic", "mitBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+				{"mitBin.meta_lic", "mitLib.meta_lic", []string{"static"}},
+			},
+			expectedNodes: []string{"Android:notice"},
+			expectedEdges: []string{},
+			expectedRoots: []string{"Android"},
+		},
+		{
+			name:    "noproject",
+			groupBy: GroupByProject,
+			roots:   []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			},
+			expectedNodes: []string{
+				"apacheBin.meta_lic:notice",
+				"apacheLib.meta_lic:notice",
+			},
+			expectedEdges: []string{
+				"apacheBin.meta_lic -[static]> apacheLib.meta_lic",
+			},
+			expectedRoots: []string{"apacheBin.meta_lic"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := &bytes.Buffer{}
+			lg, err := toGraph(stderr, tt.roots, tt.edges)
+			if err != nil {
+				t.Fatalf("unexpected test data error: got %s, want no error", err)
+			}
+			cg := CondenseGraph(lg, tt.groupBy)
+			if cg.GroupBy() != tt.groupBy {
+				t.Errorf("unexpected GroupBy(): got %s, want %s", cg.GroupBy(), tt.groupBy)
+			}
+
+			actualNodes := []string{}
+			for _, cn := range cg.Nodes() {
+				actualNodes = append(actualNodes, cn.Name()+":"+strings.Join(cn.LicenseConditions().Names(), ":"))
+			}
+			checkStrings(t, "nodes", actualNodes, tt.expectedNodes)
+
+			actualEdges := []string{}
+			for _, ce := range cg.Edges() {
+				actualEdges = append(actualEdges, ce.String())
+			}
+			checkStrings(t, "edges", actualEdges, tt.expectedEdges)
+
+			checkStrings(t, "roots", cg.Roots().Names(), tt.expectedRoots)
+
+			for _, tn := range lg.Targets() {
+				if cg.Group(tn) == nil {
+					t.Errorf("missing group for %s", tn.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestCondenseResolutions(t *testing.T) {
+	lg, err := toGraph(&bytes.Buffer{}, []string{"apacheBin.meta_lic"}, []annotated{
+		{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+		{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"static"}},
+		{"apacheLib.meta_lic", "lgplLib.meta_lic", []string{"static"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected test data error: got %s, want no error", err)
+	}
+	rs := toResolutionSet(lg, []res{
+		{"apacheBin.meta_lic", "apacheBin.meta_lic", "notice|restricted"},
+		{"apacheBin.meta_lic", "apacheLib.meta_lic", "notice"},
+		{"apacheBin.meta_lic", "gplLib.meta_lic", "restricted"},
+		{"apacheLib.meta_lic", "apacheLib.meta_lic", "restricted_if_statically_linked"},
+		{"apacheLib.meta_lic", "lgplLib.meta_lic", "restricted_if_statically_linked"},
+	})
+	expected := []string{
+		"Android Android notice:restricted:restricted_if_statically_linked",
+		"Android Free Library restricted_if_statically_linked",
+		"Android Free Software restricted",
+	}
+	actual := []string{}
+	for _, cr := range CondenseGraph(lg, GroupByPackage).CondenseResolutions(rs) {
+		actual = append(actual, fmt.Sprintf("%s %s %s", cr.AttachesTo().Name(), cr.ActsOn().Name(), strings.Join(cr.Resolves().Names(), ":")))
+	}
+	checkStrings(t, "resolutions", actual, expected)
+}
+
+// checkStrings reports differences between `actual` and `expected`.
+func checkStrings(t *testing.T, what string, actual, expected []string) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Errorf("unexpected number of %s: got %d, want %d: got %q, want %q", what, len(actual), len(expected), actual, expected)
+		return
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("unexpected %s[%d]: got %q, want %q", what, i, actual[i], expected[i])
+		}
+	}
+}
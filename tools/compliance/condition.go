@@ -23,7 +23,7 @@ import (
 type LicenseCondition uint16
 
 // LicenseConditionMask is a bitmask for the recognized license conditions.
-const LicenseConditionMask = LicenseCondition(0x1ff)
+const LicenseConditionMask = LicenseCondition(0x7ff)
 
 const (
 	// UnencumberedCondition identifies public domain or public domain-
@@ -53,6 +53,12 @@ const (
 	// NotAllowedCondition identifies a license with onerous conditions
 	// where policy prohibits use.
 	NotAllowedCondition = LicenseCondition(0x0100)
+	// NetworkUseCondition identifies a license with requirement to share
+	// the source with users interacting with the module over a network.
+	NetworkUseCondition = LicenseCondition(0x0200)
+	// PatentRetaliationCondition identifies a license terminating the
+	// patent grant for licensees asserting patent claims.
+	PatentRetaliationCondition = LicenseCondition(0x0400)
 )
 
 var (
@@ -67,6 +73,8 @@ var (
 		"proprietary":                     ProprietaryCondition,
 		"by_exception_only":               ByExceptionOnlyCondition,
 		"not_allowed":                     NotAllowedCondition,
+		"network_use":                     NetworkUseCondition,
+		"patent_retaliation":              PatentRetaliationCondition,
 	}
 )
 
@@ -91,6 +99,10 @@ func (lc LicenseCondition) Name() string {
 		return "by_exception_only"
 	case NotAllowedCondition:
 		return "not_allowed"
+	case NetworkUseCondition:
+		return "network_use"
+	case PatentRetaliationCondition:
+		return "patent_retaliation"
 	}
 	panic(fmt.Errorf("unrecognized license condition: %#v", lc))
 }
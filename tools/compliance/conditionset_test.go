@@ -96,7 +96,7 @@ func TestConditionSet(t *testing.T) {
 		{
 			name:       "everything",
 			conditions: []string{"unencumbered", "permissive", "notice", "reciprocal", "restricted", "proprietary"},
-			plus:       &[]string{"restricted_if_statically_linked", "by_exception_only", "not_allowed"},
+			plus:       &[]string{"restricted_if_statically_linked", "by_exception_only", "not_allowed", "network_use", "patent_retaliation"},
 			matchingAny: map[string][]string{
 				"unencumbered":                    []string{"unencumbered"},
 				"permissive":                      []string{"permissive"},
@@ -107,6 +107,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary":                     []string{"proprietary"},
 				"by_exception_only":               []string{"by_exception_only"},
 				"not_allowed":                     []string{"not_allowed"},
+				"network_use":                     []string{"network_use"},
+				"patent_retaliation":              []string{"patent_retaliation"},
 				"notice|proprietary":              []string{"notice", "proprietary"},
 			},
 			expected: []string{
@@ -119,6 +121,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 		},
 		{
@@ -133,6 +137,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 			plus:  &[]string{},
 			minus: &[]string{},
@@ -141,6 +147,8 @@ func TestConditionSet(t *testing.T) {
 				"restricted|reciprocal":          []string{"reciprocal", "restricted"},
 				"proprietary|by_exception_only":  []string{"proprietary", "by_exception_only"},
 				"not_allowed":                    []string{"not_allowed"},
+				"network_use":                    []string{"network_use"},
+				"patent_retaliation":             []string{"patent_retaliation"},
 			},
 			expected: []string{
 				"unencumbered",
@@ -152,12 +160,14 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 		},
 		{
 			name:       "allbutone",
 			conditions: []string{"unencumbered", "permissive", "notice", "reciprocal", "restricted", "proprietary"},
-			plus:       &[]string{"restricted_if_statically_linked", "by_exception_only", "not_allowed"},
+			plus:       &[]string{"restricted_if_statically_linked", "by_exception_only", "not_allowed", "network_use", "patent_retaliation"},
 			matchingAny: map[string][]string{
 				"unencumbered":                    []string{"unencumbered"},
 				"permissive":                      []string{"permissive"},
@@ -168,6 +178,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary":                     []string{"proprietary"},
 				"by_exception_only":               []string{"by_exception_only"},
 				"not_allowed":                     []string{"not_allowed"},
+				"network_use":                     []string{"network_use"},
+				"patent_retaliation":              []string{"patent_retaliation"},
 				"notice|proprietary":              []string{"notice", "proprietary"},
 			},
 			expected: []string{
@@ -180,6 +192,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 		},
 		{
@@ -194,6 +208,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 			minus: &[]string{"restricted_if_statically_linked"},
 			matchingAny: map[string][]string{
@@ -206,6 +222,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary":                     []string{"proprietary"},
 				"by_exception_only":               []string{"by_exception_only"},
 				"not_allowed":                     []string{"not_allowed"},
+				"network_use":                     []string{"network_use"},
+				"patent_retaliation":              []string{"patent_retaliation"},
 				"restricted|proprietary":          []string{"restricted", "proprietary"},
 			},
 			expected: []string{
@@ -217,6 +235,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 		},
 		{
@@ -231,6 +251,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 			minus: &[]string{
 				"unencumbered",
@@ -242,6 +264,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary",
 				"by_exception_only",
 				"not_allowed",
+				"network_use",
+				"patent_retaliation",
 			},
 			matchingAny: map[string][]string{
 				"unencumbered":                    []string{},
@@ -253,6 +277,8 @@ func TestConditionSet(t *testing.T) {
 				"proprietary":                     []string{},
 				"by_exception_only":               []string{},
 				"not_allowed":                     []string{},
+				"network_use":                     []string{},
+				"patent_retaliation":              []string{},
 				"restricted|proprietary":          []string{},
 			},
 			expected: []string{},
@@ -274,6 +300,23 @@ func TestConditionSet(t *testing.T) {
 			},
 			expected: []string{"permissive", "notice", "restricted", "restricted_if_statically_linked", "proprietary"},
 		},
+		{
+			name:       "networkuseplus",
+			conditions: []string{"restricted", "network_use"},
+			plus:       &[]string{"notice", "patent_retaliation"},
+			minus:      &[]string{"restricted"},
+			matchingAny: map[string][]string{
+				"notice":                         []string{"notice"},
+				"restricted":                     []string{},
+				"network_use":                    []string{"network_use"},
+				"patent_retaliation":             []string{"patent_retaliation"},
+				"restricted|network_use":         []string{"network_use"},
+				"notice|patent_retaliation":      []string{"notice", "patent_retaliation"},
+				"reciprocal|proprietary":         []string{},
+				"network_use|patent_retaliation": []string{"network_use", "patent_retaliation"},
+			},
+			expected: []string{"notice", "network_use", "patent_retaliation"},
+		},
 	}
 	for _, tt := range tests {
 		toConditions := func(names []string) []LicenseCondition {
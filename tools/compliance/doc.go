@@ -40,7 +40,8 @@ representation is a set of directed edges. Another is a set of nodes with
 directed edges to their dependencies.
 
 The edges have annotations, which can distinguish between build tools, runtime
-dependencies, and dependencies like 'contains' that make a derivative work.
+dependencies, test-only dependencies, data files installed alongside a target,
+and dependencies like 'contains' that make a derivative work.
 
 LicenseCondition
 ----------------
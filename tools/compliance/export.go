@@ -0,0 +1,296 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExportOptions controls the content of graphs exported by WriteGraphML and
+// WriteNeo4jCSV.
+type ExportOptions struct {
+	// StripPrefix lists prefixes to remove from target names and install
+	// paths. The first matching prefix is removed.
+	StripPrefix []string
+
+	// Resolutions, when not nil, adds a resolution edge from each target
+	// the resolution attaches to, to each target it acts on, with the
+	// resolved conditions.
+	Resolutions ResolutionSet
+}
+
+// strip removes the first matching prefix from `name`.
+func (opts ExportOptions) strip(name string) string {
+	for _, prefix := range opts.StripPrefix {
+		if strings.HasPrefix(name, prefix) {
+			p := strings.TrimPrefix(name, prefix)
+			if len(p) == 0 {
+				continue
+			}
+			return p
+		}
+	}
+	return name
+}
+
+// exportArrayDelimiter separates the values of multi-valued properties in
+// exported graphs. Neo4j admin import uses the same default delimiter for
+// array properties.
+const exportArrayDelimiter = ";"
+
+// exportNode describes a target node for export.
+type exportNode struct {
+	id           string
+	packageName  string
+	conditions   []string
+	licenseKinds []string
+	projects     []string
+	installed    []string
+}
+
+// exportEdge describes a dependency or resolution edge for export.
+type exportEdge struct {
+	source      string
+	target      string
+	edgeType    string
+	annotations []string
+	conditions  []string
+}
+
+// Edge types of exported graphs.
+const (
+	// ExportDependencyEdge identifies an edge from a target to its dependency.
+	ExportDependencyEdge = "DEPENDS_ON"
+
+	// ExportResolutionEdge identifies an edge from the target a resolution
+	// attaches to, to the target it acts on.
+	ExportResolutionEdge = "RESOLVES"
+)
+
+// exportGraph converts `lg` into lists of nodes and edges in a stable order.
+func exportGraph(lg *LicenseGraph, opts ExportOptions) ([]exportNode, []exportEdge) {
+	targets := lg.Targets()
+	sort.Sort(targets)
+
+	nodes := make([]exportNode, 0, len(targets))
+	for _, tn := range targets {
+		installed := make([]string, 0, len(tn.Installed()))
+		for _, p := range tn.Installed() {
+			installed = append(installed, opts.strip(p))
+		}
+		nodes = append(nodes, exportNode{
+			id:           opts.strip(tn.Name()),
+			packageName:  tn.PackageName(),
+			conditions:   sortedCopy(tn.LicenseConditions().Names()),
+			licenseKinds: sortedCopy(tn.LicenseKinds()),
+			projects:     sortedCopy(tn.Projects()),
+			installed:    installed,
+		})
+	}
+
+	lgEdges := lg.Edges()
+	sort.Sort(lgEdges)
+
+	edges := make([]exportEdge, 0, len(lgEdges))
+	for _, e := range lgEdges {
+		edges = append(edges, exportEdge{
+			source:      opts.strip(e.Target().Name()),
+			target:      opts.strip(e.Dependency().Name()),
+			edgeType:    ExportDependencyEdge,
+			annotations: sortedCopy(e.Annotations().AsList()),
+		})
+	}
+
+	if opts.Resolutions != nil {
+		attachesTo := opts.Resolutions.AttachesTo()
+		sort.Sort(attachesTo)
+		for _, tn := range attachesTo {
+			rl := opts.Resolutions.Resolutions(tn)
+			sort.Sort(rl)
+			for _, r := range rl {
+				edges = append(edges, exportEdge{
+					source:     opts.strip(r.AttachesTo().Name()),
+					target:     opts.strip(r.ActsOn().Name()),
+					edgeType:   ExportResolutionEdge,
+					conditions: sortedCopy(r.Resolves().Names()),
+				})
+			}
+		}
+	}
+
+	return nodes, edges
+}
+
+// sortedCopy returns a sorted copy of `values`.
+func sortedCopy(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+// graphML is the root element of a GraphML document.
+type graphML struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// graphMLKey declares a node or edge property.
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// graphMLGraph holds the nodes and edges of a GraphML document.
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+// graphMLNode is a GraphML node with its properties.
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// graphMLEdge is a GraphML edge with its properties.
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// graphMLData is the value of a property.
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLKeys declares the properties of the nodes and edges written by WriteGraphML.
+var graphMLKeys = []graphMLKey{
+	{"package_name", "node", "package_name", "string"},
+	{"conditions", "node", "conditions", "string"},
+	{"license_kinds", "node", "license_kinds", "string"},
+	{"projects", "node", "projects", "string"},
+	{"installed", "node", "installed", "string"},
+	{"type", "edge", "type", "string"},
+	{"annotations", "edge", "annotations", "string"},
+	{"resolves", "edge", "resolves", "string"},
+}
+
+// WriteGraphML writes the license graph `lg` to `w` as a GraphML document.
+//
+// Each target node has an id equal to its license metadata file name and
+// package_name, conditions, license_kinds, projects and installed
+// properties. Each edge goes from a target to its dependency with type
+// DEPENDS_ON and an annotations property, or from the target a resolution
+// attaches to, to the target it acts on with type RESOLVES and a resolves
+// property. Multiple values are separated by semicolons.
+func WriteGraphML(w io.Writer, lg *LicenseGraph, opts ExportOptions) error {
+	nodes, edges := exportGraph(lg, opts)
+
+	doc := graphML{
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "G", EdgeDefault: "directed"},
+	}
+	for _, n := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.id,
+			Data: []graphMLData{
+				{"package_name", n.packageName},
+				{"conditions", strings.Join(n.conditions, exportArrayDelimiter)},
+				{"license_kinds", strings.Join(n.licenseKinds, exportArrayDelimiter)},
+				{"projects", strings.Join(n.projects, exportArrayDelimiter)},
+				{"installed", strings.Join(n.installed, exportArrayDelimiter)},
+			},
+		})
+	}
+	for _, e := range edges {
+		data := []graphMLData{{"type", e.edgeType}}
+		if e.edgeType == ExportResolutionEdge {
+			data = append(data, graphMLData{"resolves", strings.Join(e.conditions, exportArrayDelimiter)})
+		} else {
+			data = append(data, graphMLData{"annotations", strings.Join(e.annotations, exportArrayDelimiter)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{e.source, e.target, data})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("cannot write graphml: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteNeo4jCSV writes the license graph `lg` as the pair of node and
+// relationship files accepted by `neo4j-admin database import`.
+//
+// The node file has a header of
+// `name:ID,:LABEL,package_name,conditions:string[],license_kinds:string[],projects:string[],installed:string[]`
+// with every node labelled Target. The relationship file has a header of
+// `:START_ID,:END_ID,:TYPE,annotations:string[],resolves:string[]` with
+// DEPENDS_ON and RESOLVES relationships as described for WriteGraphML.
+func WriteNeo4jCSV(nodesOut, edgesOut io.Writer, lg *LicenseGraph, opts ExportOptions) error {
+	nodes, edges := exportGraph(lg, opts)
+
+	nw := csv.NewWriter(nodesOut)
+	nw.Write([]string{"name:ID", ":LABEL", "package_name", "conditions:string[]", "license_kinds:string[]", "projects:string[]", "installed:string[]"})
+	for _, n := range nodes {
+		nw.Write([]string{
+			n.id,
+			"Target",
+			n.packageName,
+			strings.Join(n.conditions, exportArrayDelimiter),
+			strings.Join(n.licenseKinds, exportArrayDelimiter),
+			strings.Join(n.projects, exportArrayDelimiter),
+			strings.Join(n.installed, exportArrayDelimiter),
+		})
+	}
+	nw.Flush()
+	if err := nw.Error(); err != nil {
+		return fmt.Errorf("cannot write neo4j nodes: %w", err)
+	}
+
+	ew := csv.NewWriter(edgesOut)
+	ew.Write([]string{":START_ID", ":END_ID", ":TYPE", "annotations:string[]", "resolves:string[]"})
+	for _, e := range edges {
+		ew.Write([]string{
+			e.source,
+			e.target,
+			e.edgeType,
+			strings.Join(e.annotations, exportArrayDelimiter),
+			strings.Join(e.conditions, exportArrayDelimiter),
+		})
+	}
+	ew.Flush()
+	if err := ew.Error(); err != nil {
+		return fmt.Errorf("cannot write neo4j relationships: %w", err)
+	}
+	return nil
+}
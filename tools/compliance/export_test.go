@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteGraphML(t *testing.T) {
+	lg, err := toGraph(&bytes.Buffer{}, []string{"apacheBin.meta_lic"}, []annotated{
+		{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"dynamic"}},
+		{"apacheBin.meta_lic", "mitLib.meta_lic", []string{"static"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected test data error: got %s, want no error", err)
+	}
+	rs := toResolutionSet(lg, []res{
+		{"apacheBin.meta_lic", "apacheBin.meta_lic", "notice"},
+		{"apacheBin.meta_lic", "mitLib.meta_lic", "notice"},
+	})
+
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, lg, ExportOptions{Resolutions: rs}); err != nil {
+		t.Fatalf("WriteGraphML: unexpected error %s", err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteGraphML: invalid xml %s: %s", err, buf.String())
+	}
+
+	if len(doc.Graph.Nodes) != 3 {
+		t.Fatalf("WriteGraphML: got %d nodes, want 3", len(doc.Graph.Nodes))
+	}
+	gpl := doc.Graph.Nodes[1]
+	if gpl.ID != "gplLib.meta_lic" {
+		t.Errorf("WriteGraphML: got node %q, want %q", gpl.ID, "gplLib.meta_lic")
+	}
+	expectedData := map[string]string{
+		"package_name":  "Free Software",
+		"conditions":    "restricted",
+		"license_kinds": "SPDX-license-identifier-GPL-2.0",
+	}
+	for _, d := range gpl.Data {
+		if expected, ok := expectedData[d.Key]; ok && d.Value != expected {
+			t.Errorf("WriteGraphML: got %s %q, want %q", d.Key, d.Value, expected)
+		}
+	}
+
+	expectedEdges := []string{
+		"apacheBin.meta_lic gplLib.meta_lic DEPENDS_ON dynamic",
+		"apacheBin.meta_lic mitLib.meta_lic DEPENDS_ON static",
+		"apacheBin.meta_lic apacheBin.meta_lic RESOLVES notice",
+		"apacheBin.meta_lic mitLib.meta_lic RESOLVES notice",
+	}
+	actualEdges := []string{}
+	for _, e := range doc.Graph.Edges {
+		actualEdges = append(actualEdges, e.Source+" "+e.Target+" "+e.Data[0].Value+" "+e.Data[1].Value)
+	}
+	checkStrings(t, "edges", actualEdges, expectedEdges)
+}
+
+func TestWriteNeo4jCSV(t *testing.T) {
+	lg, err := toGraph(&bytes.Buffer{}, []string{"testdata/apacheBin.meta_lic"}, []annotated{
+		{"testdata/apacheBin.meta_lic", "testdata/lgplLib.meta_lic", []string{"static", "toolchain"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected test data error: got %s, want no error", err)
+	}
+
+	var nodes, edges bytes.Buffer
+	err = WriteNeo4jCSV(&nodes, &edges, lg, ExportOptions{StripPrefix: []string{"testdata/"}})
+	if err != nil {
+		t.Fatalf("WriteNeo4jCSV: unexpected error %s", err)
+	}
+
+	nodeRecords, err := csv.NewReader(&nodes).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteNeo4jCSV: invalid nodes csv %s", err)
+	}
+	if len(nodeRecords) != 3 {
+		t.Fatalf("WriteNeo4jCSV: got %d node records, want 3: %q", len(nodeRecords), nodeRecords)
+	}
+	if nodeRecords[0][0] != "name:ID" || nodeRecords[0][1] != ":LABEL" {
+		t.Errorf("WriteNeo4jCSV: unexpected nodes header %q", nodeRecords[0])
+	}
+	if nodeRecords[1][0] != "apacheBin.meta_lic" || nodeRecords[1][1] != "Target" {
+		t.Errorf("WriteNeo4jCSV: unexpected node %q", nodeRecords[1])
+	}
+
+	edgeRecords, err := csv.NewReader(&edges).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteNeo4jCSV: invalid edges csv %s", err)
+	}
+	if len(edgeRecords) != 2 {
+		t.Fatalf("WriteNeo4jCSV: got %d edge records, want 2: %q", len(edgeRecords), edgeRecords)
+	}
+	expected := []string{"apacheBin.meta_lic", "lgplLib.meta_lic", "DEPENDS_ON", "static;toolchain", ""}
+	checkStrings(t, "edge fields", edgeRecords[1], expected)
+}
@@ -12,6 +12,10 @@ require (
 
 replace github.com/spdx/tools-golang v0.0.0 => ../../../../external/spdx-tools
 
+require go.starlark.net v0.0.0-20201006213952-227f4aabceb5
+
+replace go.starlark.net => ../../../../external/starlark-go
+
 require golang.org/x/xerrors v0.0.0-20220609144429-65e65417b02f // indirect
 
 replace android/soong v0.0.0 => ../../../soong
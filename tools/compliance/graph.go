@@ -79,6 +79,16 @@ func (lg *LicenseGraph) Edges() TargetEdgeList {
 	return edges
 }
 
+// Roots returns the list of root target nodes in the graph in the order the
+// root files were given.
+func (lg *LicenseGraph) Roots() TargetNodeList {
+	roots := make(TargetNodeList, 0, len(lg.rootFiles))
+	for _, r := range lg.rootFiles {
+		roots = append(roots, lg.targets[r])
+	}
+	return roots
+}
+
 // Targets returns the list of target nodes in the graph. (unordered)
 func (lg *LicenseGraph) Targets() TargetNodeList {
 	targets := make(TargetNodeList, 0, len(lg.targets))
@@ -161,7 +171,25 @@ func (e *TargetEdge) IsDerivation() bool {
 // IsBuildTool returns true for edges where the target is built
 // by dependency.
 func (e *TargetEdge) IsBuildTool() bool {
-	return !edgeIsDerivation(e) && !edgeIsDynamicLink(e)
+	return !edgeIsDerivation(e) && !edgeIsDynamicLink(e) && !edgeIsTestOnly(e) && !edgeIsData(e)
+}
+
+// IsTestDependency returns true for edges where the dependency is used only
+// to test the target.
+func (e *TargetEdge) IsTestDependency() bool {
+	return edgeIsTestOnly(e)
+}
+
+// IsDataDependency returns true for edges where the dependency is a data
+// file installed alongside the target.
+func (e *TargetEdge) IsDataDependency() bool {
+	return edgeIsData(e)
+}
+
+// IsHeaderOnly returns true for derivation edges where the target includes
+// only the headers of the dependency.
+func (e *TargetEdge) IsHeaderOnly() bool {
+	return edgeIsHeaderOnly(e)
 }
 
 // String returns a human-readable string representation of the edge.
@@ -353,6 +381,14 @@ func (tn *TargetNode) LicenseConditions() LicenseConditionSet {
 	return tn.licenseConditions
 }
 
+// LicenseKinds returns the names of the license kinds applying to the target.
+// (unordered)
+//
+// e.g. SPDX-license-identifier-Apache-2.0 or legacy_proprietary
+func (tn *TargetNode) LicenseKinds() []string {
+	return append([]string{}, tn.proto.LicenseKinds...)
+}
+
 // LicenseTexts returns the paths to the files containing the license texts for
 // the target. (unordered)
 func (tn *TargetNode) LicenseTexts() []string {
@@ -0,0 +1,162 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// InstallPathFilter selects target nodes by the paths where they get
+// installed.
+//
+// Patterns use the syntax of path.Match applied to each `/`-separated
+// segment of the install path. A `**` segment matches any number of
+// segments, and a pattern matching a leading directory of an install path
+// matches the path too. e.g. "out/target/product/*/vendor" and
+// "**/vendor/**/*.so" both select files installed on /vendor.
+//
+// The zero value selects every target node.
+type InstallPathFilter struct {
+	// include lists the patterns selecting target nodes. Empty selects all.
+	include []string
+
+	// exclude lists the patterns rejecting target nodes.
+	exclude []string
+}
+
+// NewInstallPathFilter returns a filter selecting the target nodes with an
+// install path matching any of the `include` patterns, and with no install
+// path left after removing paths matching any of the `exclude` patterns.
+func NewInstallPathFilter(include, exclude []string) (InstallPathFilter, error) {
+	for _, patterns := range [][]string{include, exclude} {
+		for _, pattern := range patterns {
+			for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+				if _, err := path.Match(segment, ""); err != nil {
+					return InstallPathFilter{}, fmt.Errorf("invalid install path pattern %q: %w", pattern, err)
+				}
+			}
+		}
+	}
+	return InstallPathFilter{
+		include: append([]string{}, include...),
+		exclude: append([]string{}, exclude...),
+	}, nil
+}
+
+// IsEmpty returns true when the filter selects every target node.
+func (f InstallPathFilter) IsEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// String returns a human-readable string representation of the filter.
+func (f InstallPathFilter) String() string {
+	return fmt.Sprintf("include[%s] exclude[%s]", strings.Join(f.include, ", "), strings.Join(f.exclude, ", "))
+}
+
+// Matches returns true when the filter selects `tn` by its own install paths
+// ignoring any container installing it.
+func (f InstallPathFilter) Matches(tn *TargetNode) bool {
+	paths := tn.Installed()
+	return f.includes(paths) && !f.excludes(paths)
+}
+
+// includes returns true when any of `installPaths` matches an include
+// pattern, or when there are no include patterns.
+func (f InstallPathFilter) includes(installPaths []string) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range installPaths {
+		if matchesAnyInstallPattern(f.include, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes returns true when every one of a non-empty `installPaths`
+// matches an exclude pattern.
+func (f InstallPathFilter) excludes(installPaths []string) bool {
+	if len(f.exclude) == 0 || len(installPaths) == 0 {
+		return false
+	}
+	for _, p := range installPaths {
+		if !matchesAnyInstallPattern(f.exclude, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// installPaths returns the paths where `tn` gets installed including the
+// paths inside the container `parent` per its install map. `parent` may be
+// nil for root nodes.
+func installPaths(tn *TargetNode, parent *TargetNode) []string {
+	result := tn.Installed()
+	if parent == nil {
+		return result
+	}
+	prefixes := parent.Installed()
+	for _, im := range parent.InstallMap() {
+		for _, f := range tn.TargetFiles() {
+			if !strings.HasPrefix(f, im.FromPath) {
+				continue
+			}
+			for _, prefix := range prefixes {
+				result = append(result, strings.TrimSuffix(prefix, "/")+"/"+
+					strings.TrimPrefix(im.ContainerPath+f[len(im.FromPath):], "/"))
+			}
+		}
+	}
+	return result
+}
+
+// matchesAnyInstallPattern returns true when `installPath` matches any of
+// `patterns`.
+func matchesAnyInstallPattern(patterns []string, installPath string) bool {
+	segments := strings.Split(strings.Trim(installPath, "/"), "/")
+	for _, pattern := range patterns {
+		if matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments returns true when the pattern segments match all of
+// `segments` or a leading directory prefix of `segments`.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		// pattern matched a leading directory or the entire path
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInstallPathFilter_match(t *testing.T) {
+	tests := []struct {
+		name        string
+		patterns    []string
+		installPath string
+		expected    bool
+	}{
+		{"exact", []string{"out/target/product/fictional/vendor/bin/bin1"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"wildcard", []string{"out/target/product/*/vendor/bin/*"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"directory", []string{"out/target/product/*/vendor"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"leadingslash", []string{"/out/target/product/*/vendor/"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"anydirectory", []string{"**/vendor"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"anydepth", []string{"**/vendor/**/*1"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"zerodepth", []string{"**/bin/**/bin1"}, "out/target/product/fictional/vendor/bin/bin1", true},
+		{"nomatch", []string{"**/system"}, "out/target/product/fictional/vendor/bin/bin1", false},
+		{"partialsegment", []string{"out/target/product/fictional/vend"}, "out/target/product/fictional/vendor/bin/bin1", false},
+		{"toolong", []string{"out/target/product/fictional/vendor/bin/bin1/x"}, "out/target/product/fictional/vendor/bin/bin1", false},
+		{"any", []string{"**/system", "**/vendor"}, "out/target/product/fictional/vendor/bin/bin1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := matchesAnyInstallPattern(tt.patterns, tt.installPath)
+			if actual != tt.expected {
+				t.Errorf("unexpected match for %q on %q: got %t, want %t", tt.patterns, tt.installPath, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewInstallPathFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		expectedEmpty bool
+		expectedError string
+	}{
+		{name: "empty", expectedEmpty: true},
+		{name: "include", include: []string{"**/vendor"}},
+		{name: "exclude", exclude: []string{"**/vendor"}},
+		{name: "badinclude", include: []string{"**/[vendor"}, expectedError: `invalid install path pattern "**/[vendor"`},
+		{name: "badexclude", exclude: []string{"a/b\\"}, expectedError: `invalid install path pattern "a/b\\"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewInstallPathFilter(tt.include, tt.exclude)
+			if err != nil {
+				if len(tt.expectedError) == 0 {
+					t.Fatalf("unexpected error: got %s, want no error", err)
+				} else if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("unexpected error: got %s, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if len(tt.expectedError) > 0 {
+				t.Fatalf("unexpected success: got no error, want %q err", tt.expectedError)
+			}
+			if f.IsEmpty() != tt.expectedEmpty {
+				t.Errorf("unexpected IsEmpty(): got %t, want %t", f.IsEmpty(), tt.expectedEmpty)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/tracing"
 )
 
 var (
@@ -66,14 +67,23 @@ type NoticeIndex struct {
 // IndexLicenseTexts creates a hashed index of license texts for `lg` and `rs`
 // using the files rooted at `rootFS`.
 func IndexLicenseTexts(rootFS fs.FS, lg *LicenseGraph, rs ResolutionSet) (*NoticeIndex, error) {
+	return IndexLicenseTextsMatching(rootFS, lg, rs, InstallPathFilter{})
+}
+
+// IndexLicenseTextsMatching creates a hashed index of license texts for `lg`
+// and `rs` using the files rooted at `rootFS` limited to the shipped nodes
+// selected by `filter`.
+func IndexLicenseTextsMatching(rootFS fs.FS, lg *LicenseGraph, rs ResolutionSet, filter InstallPathFilter) (*NoticeIndex, error) {
 	if rs == nil {
 		rs = ResolveNotices(lg)
 	}
+	defer tracing.Begin(tracing.CategoryIndex, "IndexLicenseTexts").End()
+
 	ni := &NoticeIndex{
 		lg:             lg,
 		pmix:           projectmetadata.NewIndex(rootFS),
 		rs:             rs,
-		shipped:        ShippedNodes(lg),
+		shipped:        ShippedNodesMatching(lg, filter),
 		rootFS:         rootFS,
 		hash:           make(map[string]hash),
 		text:           make(map[hash][]byte),
@@ -155,12 +165,16 @@ func IndexLicenseTexts(rootFS fs.FS, lg *LicenseGraph, rs ResolutionSet) (*Notic
 	// returns error from walk below.
 	var err error
 
+	// shipped identifies all shipped nodes regardless of filter.
+	shipped := ShippedNodes(lg)
+
 	WalkTopDown(NoEdgeContext{}, lg, func(lg *LicenseGraph, tn *TargetNode, path TargetEdgePath) bool {
 		if err != nil {
 			return false
 		}
 		if !ni.shipped.Contains(tn) {
-			return false
+			// keep looking for selected nodes beneath unselected shipped nodes.
+			return !filter.IsEmpty() && shipped.Contains(tn)
 		}
 		go cacheMetadata(tn)
 		installPaths := getInstallPaths(tn, path)
@@ -178,6 +192,9 @@ func IndexLicenseTexts(rootFS fs.FS, lg *LicenseGraph, rs ResolutionSet) (*Notic
 		}
 
 		for _, r := range rs.Resolutions(tn) {
+			if !filter.IsEmpty() && !ni.shipped.Contains(r.actsOn) {
+				continue
+			}
 			hashes, err = index(r.actsOn)
 			if err != nil {
 				return false
@@ -496,6 +513,8 @@ func (ni *NoticeIndex) addText(file string) error {
 	if err != nil {
 		return fmt.Errorf("error reading license text file %q: %w", file, err)
 	}
+	tracing.Count("license_text_files", 1)
+	tracing.Count("license_text_bytes", int64(len(text)))
 
 	hash := hash{fmt.Sprintf("%x", md5.Sum(text))}
 	ni.hash[file] = hash
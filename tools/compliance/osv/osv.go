@@ -0,0 +1,482 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package osv reads a local dump of Open Source Vulnerability (OSV)
+// advisories and matches projects against them without network access.
+//
+// An OSV dump is a directory tree of JSON files, each holding one advisory
+// or a list of advisories in the format described at
+// https://ossf.github.io/osv-schema/. Only the fields needed for matching
+// and reporting are read; unknown fields are ignored.
+package osv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Vulnerability is an OSV advisory.
+type Vulnerability struct {
+	ID        string     `json:"id"`
+	Aliases   []string   `json:"aliases,omitempty"`
+	Summary   string     `json:"summary,omitempty"`
+	Details   string     `json:"details,omitempty"`
+	Modified  string     `json:"modified,omitempty"`
+	Withdrawn string     `json:"withdrawn,omitempty"`
+	Affected  []Affected `json:"affected,omitempty"`
+	Severity  []Severity `json:"severity,omitempty"`
+}
+
+// Affected describes a package and the versions of it an advisory affects.
+type Affected struct {
+	Package  Package  `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Package identifies an affected package.
+type Package struct {
+	Ecosystem string `json:"ecosystem,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Purl      string `json:"purl,omitempty"`
+}
+
+// Range describes a range of affected versions or commits.
+type Range struct {
+	// Type is one of SEMVER, ECOSYSTEM or GIT.
+	Type string `json:"type"`
+
+	// Repo is the url of the git repository for GIT ranges.
+	Repo string `json:"repo,omitempty"`
+
+	Events []Event `json:"events"`
+}
+
+// Event marks a change in whether versions are affected. Exactly one field
+// is set.
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Severity describes the severity of an advisory. e.g. a CVSS vector.
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// Database holds the advisories read from a local OSV dump.
+type Database struct {
+	// vulns lists the advisories ordered by ID.
+	vulns []*Vulnerability
+}
+
+// Load reads every *.json file under `dir` in `rootFS` into a Database.
+// Withdrawn advisories are skipped.
+func Load(rootFS fs.FS, dir string) (*Database, error) {
+	db := &Database{}
+	err := fs.WalkDir(rootFS, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".json" {
+			return nil
+		}
+		data, err := fs.ReadFile(rootFS, p)
+		if err != nil {
+			return err
+		}
+		vulns, err := parse(data)
+		if err != nil {
+			return fmt.Errorf("error parsing osv advisory %q: %w", p, err)
+		}
+		for _, v := range vulns {
+			if len(v.Withdrawn) == 0 {
+				db.vulns = append(db.vulns, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading osv database %q: %w", dir, err)
+	}
+	sort.SliceStable(db.vulns, func(i, j int) bool {
+		return db.vulns[i].ID < db.vulns[j].ID
+	})
+	return db, nil
+}
+
+// parse returns the advisory or list of advisories in `data`.
+func parse(data []byte) ([]*Vulnerability, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var vulns []*Vulnerability
+		if err := json.Unmarshal(data, &vulns); err != nil {
+			return nil, err
+		}
+		return vulns, nil
+	}
+	v := &Vulnerability{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	if len(v.ID) == 0 {
+		return nil, fmt.Errorf("missing id")
+	}
+	return []*Vulnerability{v}, nil
+}
+
+// Len returns the number of advisories in the database.
+func (db *Database) Len() int {
+	return len(db.vulns)
+}
+
+// Project identifies a project to match against the advisories.
+type Project struct {
+	// Name is the name of the project. e.g. from METADATA.
+	Name string
+
+	// Version is the version of the project, which may be a git commit.
+	Version string
+
+	// URLs lists the urls of the project. e.g. git repositories.
+	URLs []string
+
+	// Ecosystem is the OSV ecosystem of the project, e.g. "npm" or "Go", or
+	// empty when unknown.
+	Ecosystem string
+}
+
+// Kinds of matches.
+const (
+	// MatchPurl identifies a match on the name and version in a package url.
+	MatchPurl = "purl"
+
+	// MatchGit identifies a match on a git repository and commit or tag.
+	MatchGit = "git"
+
+	// MatchVersion identifies a match on a package name and version range.
+	MatchVersion = "version"
+)
+
+// Match describes an advisory affecting a project.
+type Match struct {
+	Vulnerability *Vulnerability
+
+	// Kind is one of MatchPurl, MatchGit or MatchVersion.
+	Kind string
+
+	// LowConfidence is true when the advisory names an ecosystem but the
+	// project's ecosystem is unknown, so a name or purl match may be a
+	// different package with the same name.
+	LowConfidence bool
+}
+
+// Match returns the advisories affecting `p` ordered by ID with at most one
+// match per advisory.
+//
+// Advisories match by the name in the package or in the package url
+// (ignoring case) when the ecosystems agree, or by a git repository in
+// `p.URLs`, and then by `p.Version` appearing in the affected versions or
+// falling within an affected range. Git ranges match only the commits named
+// by introduced or last_affected events since commit history is not available
+// offline. Name matches of advisories naming an ecosystem have low confidence
+// when `p.Ecosystem` is empty.
+func (db *Database) Match(p Project) []Match {
+	if len(p.Version) == 0 {
+		return nil
+	}
+	repos := make(map[string]bool)
+	for _, u := range p.URLs {
+		repos[normalizeRepo(u)] = true
+	}
+	var result []Match
+	for _, v := range db.vulns {
+		if m, ok := matchVulnerability(v, p, repos); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// matchVulnerability returns the match when `v` affects `p`, preferring any
+// match over a low confidence one.
+func matchVulnerability(v *Vulnerability, p Project, repos map[string]bool) (Match, bool) {
+	var weak *Match
+	for _, a := range v.Affected {
+		sameEcosystem := ecosystemMatches(a.Package.Ecosystem, p.Ecosystem)
+		// A name alone cannot tell apart packages of different ecosystems.
+		lowConfidence := len(a.Package.Ecosystem) > 0 && len(p.Ecosystem) == 0
+		kind := ""
+		if purlName, purlVersion := parsePurl(a.Package.Purl); sameEcosystem && len(purlName) > 0 && strings.EqualFold(purlName, p.Name) {
+			if len(purlVersion) > 0 {
+				if compareVersions(purlVersion, p.Version) == 0 {
+					kind = MatchPurl
+				}
+			} else if versionAffected(a, p.Version) {
+				kind = MatchPurl
+			}
+		}
+		if len(kind) > 0 && !lowConfidence {
+			return Match{v, kind, false}, true
+		}
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" && len(r.Repo) > 0 && repos[normalizeRepo(r.Repo)] {
+				if containsVersion(a.Versions, p.Version) || commitAffected(r, p.Version) {
+					return Match{v, MatchGit, false}, true
+				}
+			}
+		}
+		if len(kind) == 0 && sameEcosystem && len(a.Package.Name) > 0 && strings.EqualFold(a.Package.Name, p.Name) && versionAffected(a, p.Version) {
+			kind = MatchVersion
+		}
+		switch {
+		case len(kind) == 0:
+		case !lowConfidence:
+			return Match{v, kind, false}, true
+		case weak == nil:
+			weak = &Match{v, kind, true}
+		}
+	}
+	if weak != nil {
+		return *weak, true
+	}
+	return Match{}, false
+}
+
+// ecosystemMatches returns true unless `affected` and `project` both name an
+// ecosystem and they differ, ignoring case and any ":release" suffix such as
+// in "Debian:11".
+func ecosystemMatches(affected, project string) bool {
+	if len(affected) == 0 || len(project) == 0 {
+		return true
+	}
+	affected, _, _ = strings.Cut(affected, ":")
+	project, _, _ = strings.Cut(project, ":")
+	return strings.EqualFold(affected, project)
+}
+
+// versionAffected returns true when `version` is listed in `a.Versions` or
+// falls within a SEMVER or ECOSYSTEM range of `a`.
+func versionAffected(a Affected, version string) bool {
+	if containsVersion(a.Versions, version) {
+		return true
+	}
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+		if rangeAffected(r, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeAffected returns true when `version` falls between an introduced
+// event and the next fixed or last_affected event of `r`.
+func rangeAffected(r Range, version string) bool {
+	for _, introduced := range r.Events {
+		if len(introduced.Introduced) == 0 {
+			continue
+		}
+		if introduced.Introduced != "0" && compareVersions(version, introduced.Introduced) < 0 {
+			continue
+		}
+		// Find the closest end of the range at or after `introduced`.
+		var end Event
+		found := false
+		for _, e := range r.Events {
+			v := e.Fixed
+			if len(v) == 0 {
+				v = e.LastAffected
+			}
+			if len(v) == 0 {
+				continue
+			}
+			if introduced.Introduced != "0" && compareVersions(v, introduced.Introduced) < 0 {
+				continue
+			}
+			endVersion := end.Fixed + end.LastAffected
+			if !found || compareVersions(v, endVersion) < 0 {
+				end = e
+				found = true
+			}
+		}
+		switch {
+		case !found:
+			return true
+		case len(end.Fixed) > 0 && compareVersions(version, end.Fixed) < 0:
+			return true
+		case len(end.LastAffected) > 0 && compareVersions(version, end.LastAffected) <= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// commitAffected returns true when `commit` is named by an introduced or
+// last_affected event of the GIT range `r`, or when `r` affects every
+// commit.
+func commitAffected(r Range, commit string) bool {
+	unbounded := false
+	for _, e := range r.Events {
+		if e.Introduced == "0" {
+			unbounded = true
+		}
+		if len(e.Fixed) > 0 || len(e.LastAffected) > 0 || len(e.Limit) > 0 {
+			unbounded = false
+		}
+	}
+	if unbounded {
+		return true
+	}
+	for _, e := range r.Events {
+		for _, c := range []string{e.Introduced, e.LastAffected} {
+			if len(c) >= 7 && len(commit) >= 7 && (strings.HasPrefix(c, commit) || strings.HasPrefix(commit, c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsVersion returns true when `versions` contains `version`.
+func containsVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if compareVersions(v, version) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePurl returns the name and version of the package url `purl`. e.g.
+// "pkg:github/madler/zlib@1.2.11" returns "zlib" and "1.2.11".
+func parsePurl(purl string) (string, string) {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return "", ""
+	}
+	p := strings.TrimPrefix(purl, "pkg:")
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	version := ""
+	if i := strings.LastIndex(p, "@"); i >= 0 {
+		version = p[i+1:]
+		p = p[:i]
+	}
+	name := p[strings.LastIndex(p, "/")+1:]
+	return name, version
+}
+
+// normalizeRepo removes the scheme, any trailing ".git" and slashes, and
+// case from a repository url so equivalent urls compare equal.
+func normalizeRepo(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	u = strings.TrimSuffix(u, "/")
+	u = strings.TrimSuffix(u, ".git")
+	return strings.TrimSuffix(u, "/")
+}
+
+// compareVersions compares 2 versions returning -1, 0 or 1.
+//
+// The versions split into runs of digits compared numerically and runs of
+// other characters compared lexically, ignoring a leading "v" and the
+// separators ".", "-", "_" and "+". Following semver precedence, anything
+// after the first "-" is a pre-release ranking below the same version without
+// one. e.g. "1.0.0-rc1" < "1.0.0". This approximates the ordering of most
+// ecosystems without knowing the ecosystem.
+func compareVersions(a, b string) int {
+	a, preA, hasPreA := strings.Cut(a, "-")
+	b, preB, hasPreB := strings.Cut(b, "-")
+	if c := compareTokenLists(versionTokens(a), versionTokens(b)); c != 0 {
+		return c
+	}
+	switch {
+	case hasPreA && !hasPreB:
+		return -1
+	case !hasPreA && hasPreB:
+		return 1
+	}
+	return compareTokenLists(versionTokens(preA), versionTokens(preB))
+}
+
+// compareTokenLists compares the tokens of 2 versions pairwise, ranking a
+// prefix below the longer list.
+func compareTokenLists(ta, tb []string) int {
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if c := compareTokens(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(ta) < len(tb):
+		return -1
+	case len(ta) > len(tb):
+		return 1
+	}
+	return 0
+}
+
+// versionTokens splits `v` into runs of digits and runs of other characters.
+func versionTokens(v string) []string {
+	v = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(v), "v"), "V")
+	var tokens []string
+	start := -1
+	digits := false
+	for i, r := range v {
+		isSep := r == '.' || r == '-' || r == '_' || r == '+'
+		isDigit := r >= '0' && r <= '9'
+		if start >= 0 && (isSep || isDigit != digits) {
+			tokens = append(tokens, v[start:i])
+			start = -1
+		}
+		if !isSep && start < 0 {
+			start = i
+			digits = isDigit
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, v[start:])
+	}
+	return tokens
+}
+
+// compareTokens compares numeric tokens numerically, and other tokens lexically.
+func compareTokens(a, b string) int {
+	aDigit := len(a) > 0 && a[0] >= '0' && a[0] <= '9'
+	bDigit := len(b) > 0 && b[0] >= '0' && b[0] <= '9'
+	if aDigit && bDigit {
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
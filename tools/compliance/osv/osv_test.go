@@ -0,0 +1,196 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package osv
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.10", -1},
+		{"1.10", "1.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2.0-beta", "2.0-alpha", 1},
+		{"1.02", "1.2", 0},
+		{"1_2_3", "1.2.3", 0},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc1", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-beta", -1},
+		{"1.0.0-rc1", "1.0.0-rc1", 0},
+		{"1.0.0-rc1", "0.9.9", 1},
+		{"1.0.1-beta", "1.0.0", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if actual := compareVersions(tt.a, tt.b); actual != tt.expected {
+				t.Errorf("compareVersions(%q, %q): got %d, want %d", tt.a, tt.b, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRangeAffected(t *testing.T) {
+	introduced := Range{Type: "SEMVER", Events: []Event{{Introduced: "1.0.0"}}}
+	fixed := Range{Type: "SEMVER", Events: []Event{{Introduced: "0"}, {Fixed: "2.0.0"}}}
+	lastAffected := Range{Type: "SEMVER", Events: []Event{{Introduced: "0"}, {LastAffected: "2.0.0"}}}
+	tests := []struct {
+		name     string
+		r        Range
+		version  string
+		expected bool
+	}{
+		{"introduced rc", introduced, "1.0.0-rc1", false},
+		{"introduced beta", introduced, "1.0.0-beta", false},
+		{"introduced alpha", introduced, "1.0.0-alpha.1", false},
+		{"introduced release", introduced, "1.0.0", true},
+		{"introduced later rc", introduced, "1.0.1-rc1", true},
+		{"fixed rc", fixed, "2.0.0-rc1", true},
+		{"fixed beta", fixed, "2.0.0-beta", true},
+		{"fixed alpha", fixed, "2.0.0-alpha.1", true},
+		{"fixed release", fixed, "2.0.0", false},
+		{"fixed later rc", fixed, "2.0.1-rc1", false},
+		{"last affected beta", lastAffected, "2.0.0-beta", true},
+		{"last affected later alpha", lastAffected, "2.0.1-alpha.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := rangeAffected(tt.r, tt.version); actual != tt.expected {
+				t.Errorf("rangeAffected(%v, %q): got %v, want %v", tt.r.Events, tt.version, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePurl(t *testing.T) {
+	tests := []struct {
+		purl, name, version string
+	}{
+		{"pkg:github/madler/zlib@1.2.11", "zlib", "1.2.11"},
+		{"pkg:maven/org.apache/commons-text", "commons-text", ""},
+		{"pkg:npm/%40scope/name@1.0?arch=x86#sub", "name", "1.0"},
+		{"https://example.com/zlib", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.purl, func(t *testing.T) {
+			name, version := parsePurl(tt.purl)
+			if name != tt.name || version != tt.version {
+				t.Errorf("parsePurl(%q): got %q, %q, want %q, %q", tt.purl, name, version, tt.name, tt.version)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	fs := fstest.MapFS{
+		"osv/ranges.json": {Data: []byte(`{
+  "id": "OSV-0001",
+  "summary": "overflow in zlib",
+  "affected": [{
+    "package": {"ecosystem": "OSS-Fuzz", "name": "zlib"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "1.2.0"}, {"fixed": "1.2.12"}]}]
+  }]
+}`)},
+		"osv/purl.json": {Data: []byte(`{
+  "id": "OSV-0002",
+  "affected": [{
+    "package": {"purl": "pkg:github/example/libfoo"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "2.0.0"}]}]
+  }]
+}`)},
+		"osv/git/list.json": {Data: []byte(`[{
+  "id": "OSV-0003",
+  "affected": [{
+    "ranges": [{
+      "type": "GIT",
+      "repo": "https://github.com/example/libbar",
+      "events": [{"introduced": "0123456789abcdef0123456789abcdef01234567"}, {"fixed": "fedcba9876543210fedcba9876543210fedcba98"}]
+    }],
+    "versions": ["v3.1"]
+  }]
+}, {
+  "id": "OSV-0004",
+  "withdrawn": "2024-01-01T00:00:00Z",
+  "affected": [{"package": {"name": "zlib"}, "versions": ["1.2.11"]}]
+}]`)},
+		"osv/npm.json": {Data: []byte(`{
+  "id": "OSV-0005",
+  "affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}, "versions": ["1.0.0"]}]
+}`)},
+		"osv/npmzlib.json": {Data: []byte(`{
+  "id": "OSV-0006",
+  "affected": [{"package": {"ecosystem": "npm", "name": "zlib"}, "versions": ["1.2.11"]}]
+}`)},
+		"osv/README": {Data: []byte("not json")},
+	}
+	db, err := Load(fs, "osv")
+	if err != nil {
+		t.Fatalf("Load: unexpected error %s", err)
+	}
+	if db.Len() != 5 {
+		t.Errorf("Load: got %d advisories, want 5", db.Len())
+	}
+
+	tests := []struct {
+		name     string
+		project  Project
+		expected []string
+	}{
+		{"inrange", Project{Name: "ZLIB", Version: "1.2.11"}, []string{"OSV-0001 version low", "OSV-0006 version low"}},
+		{"fixed", Project{Name: "zlib", Version: "1.2.12"}, []string{}},
+		{"beforerange", Project{Name: "zlib", Version: "1.1.4"}, []string{}},
+		{"noversion", Project{Name: "zlib"}, []string{}},
+		{"purl", Project{Name: "libfoo", Version: "2.0.0"}, []string{"OSV-0002 purl"}},
+		{"purlafter", Project{Name: "libfoo", Version: "2.0.1"}, []string{}},
+		{"gittag", Project{Name: "bar", Version: "3.1", URLs: []string{"https://GitHub.com/example/libbar.git/"}}, []string{"OSV-0003 git"}},
+		{"gitcommit", Project{Name: "bar", Version: "0123456789ab", URLs: []string{"https://github.com/example/libbar"}}, []string{"OSV-0003 git"}},
+		{"ecosystem", Project{Name: "left-pad", Version: "1.0.0", Ecosystem: "npm"}, []string{"OSV-0005 version"}},
+		{"otherecosystem", Project{Name: "left-pad", Version: "1.0.0", Ecosystem: "Go"}, []string{}},
+		{"unknownecosystem", Project{Name: "left-pad", Version: "1.0.0"}, []string{"OSV-0005 version low"}},
+		{"ecosystemrelease", Project{Name: "zlib", Version: "1.2.11", Ecosystem: "oss-fuzz:2024"}, []string{"OSV-0001 version"}},
+		{"sharedname", Project{Name: "zlib", Version: "1.2.11", Ecosystem: "npm"}, []string{"OSV-0006 version"}},
+		{"gitotherrepo", Project{Name: "bar", Version: "3.1", URLs: []string{"https://github.com/other/libbar"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := []string{}
+			for _, m := range db.Match(tt.project) {
+				s := m.Vulnerability.ID + " " + m.Kind
+				if m.LowConfidence {
+					s += " low"
+				}
+				actual = append(actual, s)
+			}
+			if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Match(%+v): got %q, want %q", tt.project, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	fs := fstest.MapFS{"osv/bad.json": {Data: []byte(`{"summary": "no id"}`)}}
+	if _, err := Load(fs, "osv"); err == nil || !strings.Contains(err.Error(), "osv/bad.json") {
+		t.Errorf("Load: got error %v, want error naming osv/bad.json", err)
+	}
+}
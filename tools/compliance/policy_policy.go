@@ -23,10 +23,13 @@ var (
 	// RecognizedAnnotations identifies the set of annotations that have
 	// meaning for compliance policy.
 	RecognizedAnnotations = map[string]string{
-		// used in readgraph.go to avoid creating 1000's of copies of the below 3 strings.
-		"static":    "static",
-		"dynamic":   "dynamic",
-		"toolchain": "toolchain",
+		// used in readgraph.go to avoid creating 1000's of copies of the below strings.
+		"static":      "static",
+		"dynamic":     "dynamic",
+		"toolchain":   "toolchain",
+		"test":        "test",
+		"data":        "data",
+		"header_only": "header_only",
 	}
 
 	// safePathPrefixes maps the path prefixes presumed not to contain any
@@ -63,7 +66,8 @@ var (
 
 	// ImpliesNotice lists the condition names implying a notice or attribution policy.
 	ImpliesNotice = LicenseConditionSet(UnencumberedCondition | PermissiveCondition | NoticeCondition | ReciprocalCondition |
-		RestrictedCondition | WeaklyRestrictedCondition | ProprietaryCondition | ByExceptionOnlyCondition)
+		RestrictedCondition | WeaklyRestrictedCondition | ProprietaryCondition | ByExceptionOnlyCondition |
+		NetworkUseCondition | PatentRetaliationCondition)
 
 	// ImpliesReciprocal lists the condition names implying a local source-sharing policy.
 	ImpliesReciprocal = LicenseConditionSet(ReciprocalCondition)
@@ -71,6 +75,12 @@ var (
 	// Restricted lists the condition names implying an infectious source-sharing policy.
 	ImpliesRestricted = LicenseConditionSet(RestrictedCondition | WeaklyRestrictedCondition)
 
+	// ImpliesNetworkUse lists the condition names implying an infectious source-sharing policy for network use.
+	ImpliesNetworkUse = LicenseConditionSet(NetworkUseCondition)
+
+	// ImpliesPatentRetaliation lists the condition names implying a policy for "patent grant terminates on patent claims".
+	ImpliesPatentRetaliation = LicenseConditionSet(PatentRetaliationCondition)
+
 	// ImpliesProprietary lists the condition names implying a confidentiality policy.
 	ImpliesProprietary = LicenseConditionSet(ProprietaryCondition)
 
@@ -81,7 +91,7 @@ var (
 	ImpliesPrivate = LicenseConditionSet(ProprietaryCondition)
 
 	// ImpliesShared lists the condition names implying a source-code sharing policy.
-	ImpliesShared = LicenseConditionSet(ReciprocalCondition | RestrictedCondition | WeaklyRestrictedCondition)
+	ImpliesShared = LicenseConditionSet(ReciprocalCondition | RestrictedCondition | WeaklyRestrictedCondition | NetworkUseCondition)
 )
 
 type safePathPrefixesType struct {
@@ -146,7 +156,8 @@ func LicenseConditionSetFromNames(names ...string) LicenseConditionSet {
 // The first function controls what happens during the bottom-up propagation.
 // Restricted conditions propagate up all non-toolchain dependencies; except,
 // some do not propagate up dynamic links, which may depend on whether the
-// modules are independent.
+// modules are independent. Network use conditions propagate like restricted
+// conditions. Patent retaliation conditions, like notice, do not propagate.
 //
 // The second function controls what happens during the top-down propagation.
 // Restricted conditions propagate down as above with the added caveat that
@@ -174,15 +185,20 @@ func LicenseConditionSetFromNames(names ...string) LicenseConditionSet {
 // non-aggregates and for aggregates in non-aggregate contexts.
 func depConditionsPropagatingToTarget(lg *LicenseGraph, e *TargetEdge, depConditions LicenseConditionSet, treatAsAggregate bool) LicenseConditionSet {
 	result := LicenseConditionSet(0x0000)
+	if edgeIsHeaderOnly(e) {
+		// including headers does not trigger the linking rules of weakly restricted licenses.
+		result |= depConditions & LicenseConditionSet(RestrictedCondition|NetworkUseCondition)
+		return result
+	}
 	if edgeIsDerivation(e) {
-		result |= depConditions & ImpliesRestricted
+		result |= depConditions & ImpliesRestricted.Union(ImpliesNetworkUse)
 		return result
 	}
 	if !edgeIsDynamicLink(e) {
 		return result
 	}
 
-	result |= depConditions & LicenseConditionSet(RestrictedCondition)
+	result |= depConditions & LicenseConditionSet(RestrictedCondition|NetworkUseCondition)
 	return result
 }
 
@@ -200,11 +216,11 @@ func targetConditionsPropagatingToDep(lg *LicenseGraph, e *TargetEdge, targetCon
 	result := targetConditions
 
 	// reverse direction -- none of these apply to things depended-on, only to targets depending-on.
-	result = result.Minus(UnencumberedCondition, PermissiveCondition, NoticeCondition, ReciprocalCondition, ProprietaryCondition, ByExceptionOnlyCondition)
+	result = result.Minus(UnencumberedCondition, PermissiveCondition, NoticeCondition, ReciprocalCondition, ProprietaryCondition, ByExceptionOnlyCondition, PatentRetaliationCondition)
 
 	if !edgeIsDerivation(e) && !edgeIsDynamicLink(e) {
 		// target is not a derivative work of dependency and is not linked to dependency
-		result = result.Difference(ImpliesRestricted)
+		result = result.Difference(ImpliesRestricted, ImpliesNetworkUse)
 		return result
 	}
 	if treatAsAggregate {
@@ -213,9 +229,13 @@ func targetConditionsPropagatingToDep(lg *LicenseGraph, e *TargetEdge, targetCon
 		if !conditionsFn(e.target).MatchesAnySet(ImpliesRestricted) {
 			result = result.Difference(ImpliesRestricted)
 		}
+		// Likewise for network use.
+		if !conditionsFn(e.target).MatchesAnySet(ImpliesNetworkUse) {
+			result = result.Difference(ImpliesNetworkUse)
+		}
 		return result
 	}
-	if edgeIsDerivation(e) {
+	if edgeIsDerivation(e) && !edgeIsHeaderOnly(e) {
 		return result
 	}
 	result = result.Minus(WeaklyRestrictedCondition)
@@ -229,21 +249,28 @@ func targetConditionsPropagatingToDep(lg *LicenseGraph, e *TargetEdge, targetCon
 // final resolution walk.
 func conditionsAttachingAcrossEdge(lg *LicenseGraph, e *TargetEdge, universe LicenseConditionSet) LicenseConditionSet {
 	result := universe
+	if edgeIsHeaderOnly(e) {
+		return result.Minus(WeaklyRestrictedCondition)
+	}
 	if edgeIsDerivation(e) {
 		return result
 	}
+	if edgeIsData(e) {
+		// data files ship with the target so their conditions apply to the target.
+		return result
+	}
 	if !edgeIsDynamicLink(e) {
 		return NewLicenseConditionSet()
 	}
 
-	result &= LicenseConditionSet(RestrictedCondition)
+	result &= LicenseConditionSet(RestrictedCondition | NetworkUseCondition)
 	return result
 }
 
 // edgeIsDynamicLink returns true for edges representing shared libraries
 // linked dynamically at runtime.
 func edgeIsDynamicLink(e *TargetEdge) bool {
-	return e.annotations.HasAnnotation("dynamic")
+	return e.annotations.HasAnnotation("dynamic") && !edgeIsTestOnly(e) && !edgeIsData(e)
 }
 
 // edgeIsDerivation returns true for edges where the target is a derivative
@@ -251,5 +278,25 @@ func edgeIsDynamicLink(e *TargetEdge) bool {
 func edgeIsDerivation(e *TargetEdge) bool {
 	isDynamic := e.annotations.HasAnnotation("dynamic")
 	isToolchain := e.annotations.HasAnnotation("toolchain")
-	return !isDynamic && !isToolchain
+	return !isDynamic && !isToolchain && !edgeIsTestOnly(e) && !edgeIsData(e)
+}
+
+// edgeIsTestOnly returns true for edges where the dependency is used only to
+// test the target and never ships with it.
+//
+// A test-only edge overrides any other annotation on the edge.
+func edgeIsTestOnly(e *TargetEdge) bool {
+	return e.annotations.HasAnnotation("test")
+}
+
+// edgeIsData returns true for edges where the dependency is a data file
+// installed alongside the target without being compiled or linked into it.
+func edgeIsData(e *TargetEdge) bool {
+	return e.annotations.HasAnnotation("data") && !edgeIsTestOnly(e)
+}
+
+// edgeIsHeaderOnly returns true for derivation edges where the target
+// includes only the headers of the dependency.
+func edgeIsHeaderOnly(e *TargetEdge) bool {
+	return e.annotations.HasAnnotation("header_only") && edgeIsDerivation(e)
 }
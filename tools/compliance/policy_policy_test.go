@@ -78,6 +78,69 @@ func TestPolicy_edgeConditions(t *testing.T) {
 			},
 			expectedTargetConditions: []string{},
 		},
+		{
+			name: "fponagpl",
+			edge: annotated{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"static"}},
+			expectedDepActions: []string{
+				"apacheBin.meta_lic:agplLib.meta_lic:restricted:network_use",
+				"agplLib.meta_lic:agplLib.meta_lic:restricted:network_use",
+			},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name: "fponagpldynamic",
+			edge: annotated{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"dynamic"}},
+			expectedDepActions: []string{
+				"apacheBin.meta_lic:agplLib.meta_lic:restricted:network_use",
+				"agplLib.meta_lic:agplLib.meta_lic:restricted:network_use",
+			},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fponagpltoolchain",
+			edge:                     annotated{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"toolchain"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fpongpltest",
+			edge:                     annotated{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"test"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fpongplstatictest",
+			edge:                     annotated{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"static", "test"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fpongpldata",
+			edge:                     annotated{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"data"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fponlgplheaderonly",
+			edge:                     annotated{"apacheBin.meta_lic", "lgplLib.meta_lic", []string{"static", "header_only"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name: "fpongplheaderonly",
+			edge: annotated{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"static", "header_only"}},
+			expectedDepActions: []string{
+				"apacheBin.meta_lic:gplLib.meta_lic:restricted",
+				"gplLib.meta_lic:gplLib.meta_lic:restricted",
+			},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "fponpatent",
+			edge:                     annotated{"apacheBin.meta_lic", "patentLib.meta_lic", []string{"static"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
 		{
 			name:                     "independentmodule",
 			edge:                     annotated{"apacheBin.meta_lic", "gplWithClasspathException.meta_lic", []string{"dynamic"}},
@@ -152,6 +215,61 @@ func TestPolicy_edgeConditions(t *testing.T) {
 			expectedDepActions:       []string{},
 			expectedTargetConditions: []string{"gplBin.meta_lic:restricted"},
 		},
+		{
+			name:                     "agplonfp",
+			edge:                     annotated{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{"agplBin.meta_lic:restricted", "agplBin.meta_lic:network_use"},
+		},
+		{
+			name:                     "agplonfpdynamic",
+			edge:                     annotated{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"dynamic"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{"agplBin.meta_lic:restricted", "agplBin.meta_lic:network_use"},
+		},
+		{
+			name:                     "agplonfptoolchain",
+			edge:                     annotated{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"toolchain"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "agplcontainer",
+			edge:                     annotated{"agplContainer.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			treatAsAggregate:         true,
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{"agplContainer.meta_lic:restricted", "agplContainer.meta_lic:network_use"},
+		},
+		{
+			name:             "networkuseoncontainer",
+			edge:             annotated{"apacheContainer.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			treatAsAggregate: true,
+			otherCondition:   "agplLib.meta_lic:network_use",
+			expectedDepActions: []string{
+				"apacheContainer.meta_lic:agplLib.meta_lic:network_use",
+				"apacheLib.meta_lic:agplLib.meta_lic:network_use",
+				"agplLib.meta_lic:agplLib.meta_lic:network_use",
+			},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:             "networkuseonbin",
+			edge:             annotated{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			treatAsAggregate: false,
+			otherCondition:   "agplLib.meta_lic:network_use",
+			expectedDepActions: []string{
+				"apacheBin.meta_lic:agplLib.meta_lic:network_use",
+				"apacheLib.meta_lic:agplLib.meta_lic:network_use",
+				"agplLib.meta_lic:agplLib.meta_lic:network_use",
+			},
+			expectedTargetConditions: []string{"agplLib.meta_lic:network_use"},
+		},
+		{
+			name:                     "patentonfp",
+			edge:                     annotated{"patentBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
 		{
 			name:                     "independentmodulereverse",
 			edge:                     annotated{"gplWithClasspathException.meta_lic", "apacheBin.meta_lic", []string{"dynamic"}},
@@ -179,6 +297,33 @@ func TestPolicy_edgeConditions(t *testing.T) {
 			},
 			expectedTargetConditions: []string{},
 		},
+		{
+			name: "ponagpl",
+			edge: annotated{"proprietary.meta_lic", "agplLib.meta_lic", []string{"static"}},
+			expectedDepActions: []string{
+				"proprietary.meta_lic:agplLib.meta_lic:restricted:network_use",
+				"agplLib.meta_lic:agplLib.meta_lic:restricted:network_use",
+			},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "rontest",
+			edge:                     annotated{"gplBin.meta_lic", "proprietary.meta_lic", []string{"test"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "rondata",
+			edge:                     annotated{"gplBin.meta_lic", "proprietary.meta_lic", []string{"data"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
+		{
+			name:                     "lgplonpheaderonly",
+			edge:                     annotated{"lgplBin.meta_lic", "proprietary.meta_lic", []string{"static", "header_only"}},
+			expectedDepActions:       []string{},
+			expectedTargetConditions: []string{},
+		},
 		{
 			name:                     "ronp",
 			edge:                     annotated{"gplBin.meta_lic", "proprietary.meta_lic", []string{"static"}},
@@ -14,6 +14,10 @@
 
 package compliance
 
+import (
+	"android/soong/tools/compliance/tracing"
+)
+
 var (
 	// AllResolutions is a TraceConditions function that resolves all
 	// unfiltered license conditions.
@@ -46,6 +50,8 @@ func TraceBottomUpConditions(lg *LicenseGraph, conditionsFn TraceConditions) {
 
 	// short-cut if already walked and cached
 	lg.onceBottomUp.Do(func() {
+		defer tracing.Begin(tracing.CategoryResolve, "ResolveBottomUpConditions").End()
+
 		// amap identifes targets previously walked. (guarded by mu)
 		amap := make(map[*TargetNode]struct{})
 
@@ -118,6 +124,8 @@ func TraceTopDownConditions(lg *LicenseGraph, conditionsFn TraceConditions) {
 		// start with the conditions propagated up the graph
 		TraceBottomUpConditions(lg, conditionsFn)
 
+		defer tracing.Begin(tracing.CategoryResolve, "ResolveTopDownConditions").End()
+
 		// amap contains the set of targets already walked. (guarded by mu)
 		amap := make(map[*TargetNode]struct{})
 
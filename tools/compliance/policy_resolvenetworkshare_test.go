@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compliance
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestResolveNetworkSourceSharing(t *testing.T) {
+	tests := []struct {
+		name                string
+		roots               []string
+		edges               []annotated
+		expectedResolutions []res
+	}{
+		{
+			name:  "firstparty",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			},
+			expectedResolutions: []res{},
+		},
+		{
+			name:  "restricted",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"static"}},
+			},
+			expectedResolutions: []res{},
+		},
+		{
+			name:  "agplonfp",
+			roots: []string{"agplBin.meta_lic"},
+			edges: []annotated{
+				{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+			},
+			expectedResolutions: []res{
+				{"agplBin.meta_lic", "agplBin.meta_lic", "network_use"},
+				{"agplBin.meta_lic", "apacheLib.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "agplonfpdynamic",
+			roots: []string{"agplBin.meta_lic"},
+			edges: []annotated{
+				{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"dynamic"}},
+			},
+			expectedResolutions: []res{
+				{"agplBin.meta_lic", "agplBin.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "agplonfpdynamicshiplib",
+			roots: []string{"agplBin.meta_lic", "apacheLib.meta_lic"},
+			edges: []annotated{
+				{"agplBin.meta_lic", "apacheLib.meta_lic", []string{"dynamic"}},
+			},
+			expectedResolutions: []res{
+				{"agplBin.meta_lic", "agplBin.meta_lic", "network_use"},
+				{"agplBin.meta_lic", "apacheLib.meta_lic", "network_use"},
+				{"apacheLib.meta_lic", "apacheLib.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "fponagpl",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"static"}},
+			},
+			expectedResolutions: []res{
+				{"apacheBin.meta_lic", "apacheBin.meta_lic", "network_use"},
+				{"apacheBin.meta_lic", "agplLib.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "fponagpldynamic",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"dynamic"}},
+			},
+			expectedResolutions: []res{
+				{"apacheBin.meta_lic", "apacheBin.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "fponagpldynamicshiplib",
+			roots: []string{"apacheBin.meta_lic", "agplLib.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "agplLib.meta_lic", []string{"dynamic"}},
+			},
+			expectedResolutions: []res{
+				{"apacheBin.meta_lic", "apacheBin.meta_lic", "network_use"},
+				{"apacheBin.meta_lic", "agplLib.meta_lic", "network_use"},
+				{"agplLib.meta_lic", "agplLib.meta_lic", "network_use"},
+			},
+		},
+		{
+			name:  "fponagpltoolchain",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "agplBin.meta_lic", []string{"toolchain"}},
+			},
+			expectedResolutions: []res{},
+		},
+		{
+			name:  "containeronagpl",
+			roots: []string{"apacheContainer.meta_lic"},
+			edges: []annotated{
+				{"apacheContainer.meta_lic", "apacheBin.meta_lic", []string{"static"}},
+				{"apacheContainer.meta_lic", "agplLib.meta_lic", []string{"static"}},
+			},
+			expectedResolutions: []res{
+				{"apacheContainer.meta_lic", "apacheContainer.meta_lic", "network_use"},
+				{"apacheContainer.meta_lic", "agplLib.meta_lic", "network_use"},
+				{"agplLib.meta_lic", "agplLib.meta_lic", "network_use"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := &bytes.Buffer{}
+			lg, err := toGraph(stderr, tt.roots, tt.edges)
+			if err != nil {
+				t.Errorf("unexpected test data error: got %s, want no error", err)
+				return
+			}
+			expectedRs := toResolutionSet(lg, tt.expectedResolutions)
+			actualRs := ResolveNetworkSourceSharing(lg)
+			checkResolves(actualRs, expectedRs, t)
+		})
+	}
+}
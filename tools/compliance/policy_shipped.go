@@ -15,7 +15,8 @@
 package compliance
 
 // ShippedNodes returns the set of nodes in a license graph where the target or
-// a derivative work gets distributed. (caches result)
+// a derivative work gets distributed. Data files installed alongside a shipped
+// target also ship, but test-only dependencies do not. (caches result)
 func ShippedNodes(lg *LicenseGraph) TargetNodeSet {
 	lg.mu.Lock()
 	shipped := lg.shippedNodes
@@ -31,7 +32,8 @@ func ShippedNodes(lg *LicenseGraph) TargetNodeSet {
 			return false
 		}
 		if len(path) > 0 {
-			if !edgeIsDerivation(path[len(path)-1].edge) {
+			edge := path[len(path)-1].edge
+			if !edgeIsDerivation(edge) && !edgeIsData(edge) {
 				return false
 			}
 		}
@@ -52,3 +54,55 @@ func ShippedNodes(lg *LicenseGraph) TargetNodeSet {
 
 	return *shipped
 }
+
+// ShippedNodesMatching returns the subset of shipped nodes in a license graph
+// selected by the install paths in `filter`.
+//
+// A shipped node is selected when it or any target shipping it as part of a
+// derivative work or as a data file matches `filter`. e.g. The static
+// libraries linked into a binary installed on /vendor get selected along with
+// the binary even though the libraries do not get installed themselves.
+// Excluding a target also excludes everything shipped only as part of it.
+func ShippedNodesMatching(lg *LicenseGraph, filter InstallPathFilter) TargetNodeSet {
+	shipped := ShippedNodes(lg)
+	if filter.IsEmpty() {
+		return shipped
+	}
+
+	tset := make(TargetNodeSet)
+
+	// outside identifies the nodes already walked outside the scope of the filter.
+	outside := make(TargetNodeSet)
+
+	WalkTopDown(NoEdgeContext{}, lg, func(lg *LicenseGraph, tn *TargetNode, path TargetEdgePath) bool {
+		if !shipped.Contains(tn) {
+			return false
+		}
+		var parent *TargetNode
+		if len(path) > 0 {
+			edge := path[len(path)-1].edge
+			if !edgeIsDerivation(edge) && !edgeIsData(edge) {
+				return false
+			}
+			parent = edge.target
+		}
+		paths := installPaths(tn, parent)
+		if filter.excludes(paths) {
+			return false
+		}
+		if tset.Contains(parent) || filter.includes(paths) {
+			if _, alreadyWalked := tset[tn]; alreadyWalked {
+				return false
+			}
+			tset[tn] = struct{}{}
+			return true
+		}
+		if _, alreadyWalked := outside[tn]; alreadyWalked {
+			return false
+		}
+		outside[tn] = struct{}{}
+		return true
+	})
+
+	return tset
+}
@@ -19,6 +19,8 @@ import (
 	"sort"
 	"strings"
 	"testing"
+
+	"android/soong/tools/compliance/testfs"
 )
 
 func TestShippedNodes(t *testing.T) {
@@ -88,6 +90,31 @@ func TestShippedNodes(t *testing.T) {
 				"apacheLib.meta_lic",
 			},
 		},
+		{
+			name:  "binarytest",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"static"}},
+				{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"static", "test"}},
+			},
+			expectedNodes: []string{
+				"apacheBin.meta_lic",
+				"apacheLib.meta_lic",
+			},
+		},
+		{
+			name:  "binarydata",
+			roots: []string{"apacheBin.meta_lic"},
+			edges: []annotated{
+				{"apacheBin.meta_lic", "apacheLib.meta_lic", []string{"header_only"}},
+				{"apacheBin.meta_lic", "gplLib.meta_lic", []string{"data"}},
+			},
+			expectedNodes: []string{
+				"apacheBin.meta_lic",
+				"apacheLib.meta_lic",
+				"gplLib.meta_lic",
+			},
+		},
 		{
 			name:  "containerdeep",
 			roots: []string{"apacheContainer.meta_lic"},
@@ -141,3 +168,105 @@ func TestShippedNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestShippedNodesMatching(t *testing.T) {
+	const (
+		product = "out/target/product/fictional/"
+		license = "license_kinds: \"SPDX-license-identifier-Apache-2.0\"\nlicense_conditions: \"notice\"\n"
+	)
+	dep := func(file, annotation string) string {
+		return "deps: {\n  file: \"" + file + "\"\n  annotations: \"" + annotation + "\"\n}\n"
+	}
+	fs := &testfs.TestFS{
+		"image.meta_lic": []byte(license + "is_container: true\ninstalled: \"" + product + "image.zip\"\n" +
+			dep("vbin.meta_lic", "static") + dep("sbin.meta_lic", "static") + dep("apex.meta_lic", "static")),
+		"vbin.meta_lic": []byte(license + "installed: \"" + product + "vendor/bin/vbin\"\n" +
+			dep("vlib.meta_lic", "static") + dep("dlib.meta_lic", "dynamic")),
+		"vlib.meta_lic": []byte(license),
+		"dlib.meta_lic": []byte(license + "installed: \"" + product + "vendor/lib/dlib.so\"\n"),
+		"sbin.meta_lic": []byte(license + "installed: \"" + product + "system/bin/sbin\"\n" +
+			dep("slib.meta_lic", "static") + dep("sdata.meta_lic", "data")),
+		"slib.meta_lic":  []byte(license),
+		"sdata.meta_lic": []byte(license + "installed: \"" + product + "system/etc/sdata\"\n"),
+		"apex.meta_lic": []byte(license + "is_container: true\ninstalled: \"" + product + "system/apex/x.apex\"\n" +
+			"install_map {\n  from_path: \"" + product + "obj/\"\n  container_path: \"/lib/\"\n}\n" +
+			dep("alib.meta_lic", "static")),
+		"alib.meta_lic": []byte(license + "built: \"" + product + "obj/alib.so\"\n"),
+	}
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		expectedNodes []string
+	}{
+		{
+			name: "nofilter",
+			expectedNodes: []string{
+				"alib.meta_lic",
+				"apex.meta_lic",
+				"image.meta_lic",
+				"sbin.meta_lic",
+				"sdata.meta_lic",
+				"slib.meta_lic",
+				"vbin.meta_lic",
+				"vlib.meta_lic",
+			},
+		},
+		{
+			name:          "vendor",
+			include:       []string{"**/vendor"},
+			expectedNodes: []string{"vbin.meta_lic", "vlib.meta_lic"},
+		},
+		{
+			name:          "apex",
+			include:       []string{product + "system/apex/x.apex"},
+			expectedNodes: []string{"alib.meta_lic", "apex.meta_lic"},
+		},
+		{
+			name:          "containerpath",
+			include:       []string{"**/x.apex/lib/*.so"},
+			expectedNodes: []string{"alib.meta_lic"},
+		},
+		{
+			name:    "excludevendor",
+			exclude: []string{"**/vendor/**"},
+			expectedNodes: []string{
+				"alib.meta_lic",
+				"apex.meta_lic",
+				"image.meta_lic",
+				"sbin.meta_lic",
+				"sdata.meta_lic",
+				"slib.meta_lic",
+			},
+		},
+		{
+			name:          "systemexceptapex",
+			include:       []string{"**/system"},
+			exclude:       []string{"**/apex"},
+			expectedNodes: []string{"sbin.meta_lic", "sdata.meta_lic", "slib.meta_lic"},
+		},
+		{
+			name:          "data",
+			include:       []string{"**/etc"},
+			expectedNodes: []string{"sdata.meta_lic"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg, err := ReadLicenseGraph(fs, &bytes.Buffer{}, []string{"image.meta_lic"})
+			if err != nil {
+				t.Fatalf("unexpected test data error: got %s, want no error", err)
+			}
+			filter, err := NewInstallPathFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("unexpected filter error: got %s, want no error", err)
+			}
+			actualNodes := ShippedNodesMatching(lg, filter).Names()
+			sort.Strings(actualNodes)
+			if strings.Join(actualNodes, ", ") != strings.Join(tt.expectedNodes, ", ") {
+				t.Errorf("unexpected shipped nodes: got [%s], want [%s]",
+					strings.Join(actualNodes, ", "), strings.Join(tt.expectedNodes, ", "))
+			}
+		})
+	}
+}
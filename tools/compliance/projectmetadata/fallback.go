@@ -0,0 +1,279 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectmetadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+
+	"android/soong/compliance/project_metadata_proto"
+)
+
+// Metadata fields with provenance.
+const (
+	// FieldName identifies the name of the project.
+	FieldName = "name"
+
+	// FieldVersion identifies the version of the project.
+	FieldVersion = "version"
+
+	// FieldURL identifies the url of the project.
+	FieldURL = "url"
+)
+
+// MetadataFields are the fields a MetadataReader may find. Empty strings
+// mean not found.
+type MetadataFields struct {
+	Name    string
+	Version string
+	URL     string
+}
+
+// MetadataReader reads project metadata from a file other than METADATA for
+// projects without a METADATA.android or METADATA file.
+type MetadataReader struct {
+	// FileName is the name of the file in the root of the project.
+	FileName string
+
+	// Read returns the fields found in the content of the file.
+	Read func(data []byte) (MetadataFields, error)
+}
+
+// DefaultMetadataReaders lists the readers used when IndexOptions.Readers
+// is nil in the order their fields take precedence.
+var DefaultMetadataReaders = []MetadataReader{
+	{"README.chromium", ReadReadmeChromium},
+	{"Cargo.toml", ReadCargoToml},
+	{"go.mod", ReadGoMod},
+	{"package.json", ReadPackageJSON},
+	{"pom.xml", ReadPomXML},
+}
+
+// ReadReadmeChromium reads the `Name:`, `Version:` (or `Revision:`) and
+// `URL:` lines of a README.chromium file.
+func ReadReadmeChromium(data []byte) (MetadataFields, error) {
+	var fields MetadataFields
+	var revision string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(value, "N/A") || value == "0" {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			setOnce(&fields.Name, value)
+		case "Version":
+			setOnce(&fields.Version, value)
+		case "Revision":
+			setOnce(&revision, value)
+		case "URL":
+			setOnce(&fields.URL, strings.Fields(value + " ")[0])
+		}
+	}
+	if len(fields.Version) == 0 {
+		fields.Version = revision
+	}
+	return fields, scanner.Err()
+}
+
+// ReadCargoToml reads the name, version and repository (or homepage) of
+// the [package] table of a Cargo.toml file.
+func ReadCargoToml(data []byte) (MetadataFields, error) {
+	var fields MetadataFields
+	var homepage string
+	inPackage := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inPackage = line == "[package]"
+			continue
+		}
+		if !inPackage {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) < 2 || value[0] != '"' || !strings.HasSuffix(value, "\"") {
+			// e.g. `version.workspace = true`
+			continue
+		}
+		value = value[1 : len(value)-1]
+		switch strings.TrimSpace(key) {
+		case "name":
+			fields.Name = value
+		case "version":
+			fields.Version = value
+		case "repository":
+			fields.URL = value
+		case "homepage":
+			homepage = value
+		}
+	}
+	if len(fields.URL) == 0 {
+		fields.URL = homepage
+	}
+	return fields, scanner.Err()
+}
+
+// ReadGoMod reads the module path of a go.mod file as the name, and as the
+// url when the first path element looks like a host name.
+func ReadGoMod(data []byte) (MetadataFields, error) {
+	var fields MetadataFields
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "module ") && !strings.HasPrefix(line, "module\t") {
+			continue
+		}
+		module := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), "\"`")
+		fields.Name = module
+		if host, _, _ := strings.Cut(module, "/"); strings.Contains(host, ".") {
+			fields.URL = "https://" + module
+		}
+		break
+	}
+	return fields, scanner.Err()
+}
+
+// ReadPackageJSON reads the name, version and repository (or homepage) of a
+// package.json file.
+func ReadPackageJSON(data []byte) (MetadataFields, error) {
+	var pkg struct {
+		Name       string          `json:"name"`
+		Version    string          `json:"version"`
+		Homepage   string          `json:"homepage"`
+		Repository json.RawMessage `json:"repository"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return MetadataFields{}, err
+	}
+	fields := MetadataFields{Name: pkg.Name, Version: pkg.Version}
+	if len(pkg.Repository) > 0 {
+		// The repository is either a string or an object with a url.
+		var repo struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(pkg.Repository, &fields.URL); err != nil {
+			if json.Unmarshal(pkg.Repository, &repo) == nil {
+				fields.URL = repo.URL
+			}
+		}
+	}
+	if len(fields.URL) == 0 {
+		fields.URL = pkg.Homepage
+	}
+	return fields, nil
+}
+
+// ReadPomXML reads the artifactId, version (or parent version) and scm url
+// (or url) of a Maven pom.xml file.
+func ReadPomXML(data []byte) (MetadataFields, error) {
+	var pom struct {
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+		URL        string `xml:"url"`
+		Parent     struct {
+			Version string `xml:"version"`
+		} `xml:"parent"`
+		SCM struct {
+			URL string `xml:"url"`
+		} `xml:"scm"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return MetadataFields{}, err
+	}
+	fields := MetadataFields{Name: pom.ArtifactID, Version: pom.Version, URL: pom.SCM.URL}
+	if len(fields.Version) == 0 {
+		fields.Version = pom.Parent.Version
+	}
+	if len(fields.URL) == 0 {
+		fields.URL = pom.URL
+	}
+	// Unresolved maven properties are not versions.
+	if strings.Contains(fields.Version, "${") {
+		fields.Version = ""
+	}
+	return fields, nil
+}
+
+// setOnce sets `*field` to `value` unless already set.
+func setOnce(field *string, value string) {
+	if len(*field) == 0 {
+		*field = value
+	}
+}
+
+// urlType guesses the type of the url `u`.
+func urlType(u string) project_metadata_proto.URL_Type {
+	lower := strings.ToLower(u)
+	if strings.HasPrefix(lower, "git") || strings.HasSuffix(lower, ".git") ||
+		strings.Contains(lower, "github.com/") || strings.Contains(lower, "gitlab.com/") ||
+		strings.Contains(lower, "googlesource.com/") {
+		return project_metadata_proto.URL_GIT
+	}
+	return project_metadata_proto.URL_HOMEPAGE
+}
+
+// fallbackMetadata combines the fields read from the fallback files into
+// project metadata. `found` holds the fields read from the corresponding
+// `paths` in order of precedence.
+func fallbackMetadata(project string, paths []string, found []MetadataFields) *ProjectMetadata {
+	pm := &ProjectMetadata{project: project, provenance: make(map[string]string)}
+	var name, version, url string
+	for i, f := range found {
+		if len(name) == 0 && len(f.Name) > 0 {
+			name = f.Name
+			pm.provenance[FieldName] = paths[i]
+		}
+		if len(version) == 0 && len(f.Version) > 0 {
+			version = f.Version
+			pm.provenance[FieldVersion] = paths[i]
+		}
+		if len(url) == 0 && len(f.URL) > 0 {
+			url = strings.TrimPrefix(f.URL, "git+")
+			pm.provenance[FieldURL] = paths[i]
+		}
+	}
+	if len(name) == 0 && len(version) == 0 && len(url) == 0 {
+		return nil
+	}
+	pm.path = paths[0]
+	pm.fallback = true
+	if len(name) > 0 {
+		pm.proto.Name = &name
+	}
+	if len(version) > 0 || len(url) > 0 {
+		pm.proto.ThirdParty = &project_metadata_proto.ThirdParty{}
+		if len(version) > 0 {
+			pm.proto.ThirdParty.Version = &version
+		}
+		if len(url) > 0 {
+			pm.proto.ThirdParty.Url = []*project_metadata_proto.URL{{Type: urlType(url).Enum(), Value: &url}}
+		}
+	}
+	return pm
+}
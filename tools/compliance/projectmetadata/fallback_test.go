@@ -0,0 +1,213 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectmetadata
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"android/soong/compliance/project_metadata_proto"
+	"android/soong/tools/compliance/testfs"
+)
+
+func TestReadFallbackFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		read     func([]byte) (MetadataFields, error)
+		data     string
+		expected MetadataFields
+	}{
+		{
+			name:     "readme.chromium",
+			read:     ReadReadmeChromium,
+			data:     "Name: zlib\nShort Name: zlib\nURL: https://zlib.net/ (see also)\nVersion: 1.2.13\nLicense: Zlib\n",
+			expected: MetadataFields{Name: "zlib", Version: "1.2.13", URL: "https://zlib.net/"},
+		},
+		{
+			name:     "readme.chromium revision",
+			read:     ReadReadmeChromium,
+			data:     "Name: libyuv\nURL: https://chromium.googlesource.com/libyuv/libyuv\nVersion: N/A\nRevision: 2a6cb74\n",
+			expected: MetadataFields{Name: "libyuv", Version: "2a6cb74", URL: "https://chromium.googlesource.com/libyuv/libyuv"},
+		},
+		{
+			name:     "cargo.toml",
+			read:     ReadCargoToml,
+			data:     "[package]\nname = \"serde\"\nversion = \"1.0.188\"\nhomepage = \"https://serde.rs\"\nrepository = \"https://github.com/serde-rs/serde\"\n\n[dependencies]\nname = \"other\"\nversion = \"2\"\n",
+			expected: MetadataFields{Name: "serde", Version: "1.0.188", URL: "https://github.com/serde-rs/serde"},
+		},
+		{
+			name:     "cargo.toml workspace",
+			read:     ReadCargoToml,
+			data:     "[package]\nname = \"tokio\"\nversion.workspace = true\nhomepage = \"https://tokio.rs\"\n",
+			expected: MetadataFields{Name: "tokio", URL: "https://tokio.rs"},
+		},
+		{
+			name:     "go.mod",
+			read:     ReadGoMod,
+			data:     "// comment\nmodule github.com/google/go-cmp\n\ngo 1.21\n",
+			expected: MetadataFields{Name: "github.com/google/go-cmp", URL: "https://github.com/google/go-cmp"},
+		},
+		{
+			name:     "go.mod local",
+			read:     ReadGoMod,
+			data:     "module example\n",
+			expected: MetadataFields{Name: "example"},
+		},
+		{
+			name:     "package.json string",
+			read:     ReadPackageJSON,
+			data:     `{"name": "left-pad", "version": "1.3.0", "repository": "git+https://github.com/stevemao/left-pad.git"}`,
+			expected: MetadataFields{Name: "left-pad", Version: "1.3.0", URL: "git+https://github.com/stevemao/left-pad.git"},
+		},
+		{
+			name:     "package.json object",
+			read:     ReadPackageJSON,
+			data:     `{"name": "lodash", "version": "4.17.21", "repository": {"type": "git", "url": "https://github.com/lodash/lodash.git"}}`,
+			expected: MetadataFields{Name: "lodash", Version: "4.17.21", URL: "https://github.com/lodash/lodash.git"},
+		},
+		{
+			name:     "package.json homepage",
+			read:     ReadPackageJSON,
+			data:     `{"name": "x", "homepage": "https://x.example/"}`,
+			expected: MetadataFields{Name: "x", URL: "https://x.example/"},
+		},
+		{
+			name:     "pom.xml",
+			read:     ReadPomXML,
+			data:     `<project><artifactId>guava</artifactId><version>32.1.2</version><url>https://github.com/google/guava</url></project>`,
+			expected: MetadataFields{Name: "guava", Version: "32.1.2", URL: "https://github.com/google/guava"},
+		},
+		{
+			name:     "pom.xml parent",
+			read:     ReadPomXML,
+			data:     `<project><parent><version>3.0</version></parent><artifactId>child</artifactId><scm><url>https://example.com/scm</url></scm><url>https://example.com/</url></project>`,
+			expected: MetadataFields{Name: "child", Version: "3.0", URL: "https://example.com/scm"},
+		},
+		{
+			name:     "pom.xml property",
+			read:     ReadPomXML,
+			data:     `<project><artifactId>a</artifactId><version>${revision}</version></project>`,
+			expected: MetadataFields{Name: "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.read([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("got error %v, want no error", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("got %+v, want %+v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadFallbackMalformed(t *testing.T) {
+	if _, err := ReadPackageJSON([]byte(`{"name": `)); err == nil {
+		t.Errorf("ReadPackageJSON: got no error, want error")
+	}
+	if _, err := ReadPomXML([]byte(`<project><artifactId>`)); err == nil {
+		t.Errorf("ReadPomXML: got no error, want error")
+	}
+}
+
+func TestFallbackForProjects(t *testing.T) {
+	fs := &testfs.TestFS{
+		"metadata/METADATA":        []byte(`name: "meta" third_party { version: "1.0" }`),
+		"metadata/Cargo.toml":      []byte("[package]\nname = \"ignored\"\n"),
+		"cargo/Cargo.toml":         []byte("[package]\nname = \"crate\"\nversion = \"0.3.1\"\nrepository = \"https://github.com/example/crate\"\n"),
+		"combined/README.chromium": []byte("Name: combined\nURL: https://combined.example/\n"),
+		"combined/package.json":    []byte(`{"name": "ignored", "version": "2.0.0"}`),
+		"malformed/package.json":   []byte(`{"name": `),
+		"malformed/go.mod":         []byte("module example.com/malformed\n"),
+		"broken/package.json":      []byte(`{"name": `),
+		"nothing/README.md":        []byte("# nothing\n"),
+	}
+	ix := NewIndex(fs)
+	pms, err := ix.MetadataForProjects("metadata", "cargo", "combined", "malformed", "broken", "nothing")
+	if err != nil {
+		t.Fatalf("got error %v, want no error", err)
+	}
+	type result struct {
+		project, name, version, url string
+		urlType                     project_metadata_proto.URL_Type
+		fallback                    bool
+		provenance                  map[string]string
+	}
+	expected := []result{
+		{"metadata", "meta", "1.0", "", 0, false, map[string]string{FieldName: "metadata/METADATA", FieldVersion: "metadata/METADATA"}},
+		{"cargo", "crate", "0.3.1", "https://github.com/example/crate", project_metadata_proto.URL_GIT, true, map[string]string{
+			FieldName: "cargo/Cargo.toml", FieldVersion: "cargo/Cargo.toml", FieldURL: "cargo/Cargo.toml"}},
+		{"combined", "combined", "2.0.0", "https://combined.example/", project_metadata_proto.URL_HOMEPAGE, true, map[string]string{
+			FieldName: "combined/README.chromium", FieldVersion: "combined/package.json", FieldURL: "combined/README.chromium"}},
+		{"malformed", "example.com/malformed", "", "https://example.com/malformed", project_metadata_proto.URL_HOMEPAGE, true, map[string]string{
+			FieldName: "malformed/go.mod", FieldURL: "malformed/go.mod"}},
+	}
+	if len(pms) != len(expected) {
+		t.Fatalf("got %d project metadata, want %d: %v", len(pms), len(expected), pms)
+	}
+	for i, pm := range pms {
+		actual := result{project: pm.Project(), name: pm.Name(), version: pm.Version(), fallback: pm.IsFallback()}
+		if tp := pm.proto.GetThirdParty(); tp != nil && len(tp.Url) > 0 {
+			actual.url = tp.Url[0].GetValue()
+			actual.urlType = tp.Url[0].GetType()
+		}
+		actual.provenance = make(map[string]string)
+		for _, field := range []string{FieldName, FieldVersion, FieldURL} {
+			if p := pm.Provenance(field); p != "" {
+				actual.provenance[field] = p
+			}
+		}
+		if !reflect.DeepEqual(actual, expected[i]) {
+			t.Errorf("pms[%d]: got %+v, want %+v", i, actual, expected[i])
+		}
+		if pm.IsThirdParty() == pm.IsFallback() {
+			t.Errorf("pms[%d]: got IsThirdParty() %v for fallback %v, want only METADATA third party", i, pm.IsThirdParty(), pm.IsFallback())
+		}
+		if pm.IsFallback() && pm.Lint(LintOptions{}) != nil {
+			t.Errorf("pms[%d]: got lint findings for fallback metadata, want none", i)
+		}
+	}
+
+	files := ix.AllMetadataFiles()
+	sort.Strings(files)
+	expectedFiles := []string{
+		"cargo/Cargo.toml",
+		"combined/README.chromium",
+		"combined/package.json",
+		"malformed/go.mod",
+		"metadata/METADATA",
+	}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("AllMetadataFiles(): got %q, want %q", files, expectedFiles)
+	}
+}
+
+func TestFallbackDisabled(t *testing.T) {
+	fs := &testfs.TestFS{
+		"cargo/Cargo.toml": []byte("[package]\nname = \"crate\"\n"),
+	}
+	ix := NewIndexWithOptions(context.Background(), fs, IndexOptions{Readers: []MetadataReader{}})
+	pms, err := ix.MetadataForProjects("cargo")
+	if err != nil {
+		t.Fatalf("got error %v, want no error", err)
+	}
+	if pms != nil {
+		t.Errorf("got %v, want no project metadata", pms)
+	}
+}
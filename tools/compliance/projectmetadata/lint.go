@@ -0,0 +1,224 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectmetadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"android/soong/compliance/project_metadata_proto"
+)
+
+// Severity describes how serious a lint finding is.
+type Severity string
+
+const (
+	// SeverityError identifies findings that fail the check.
+	SeverityError Severity = "error"
+
+	// SeverityWarning identifies findings reported without failing the check.
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rule IDs. The IDs are stable so CI systems and suppressions can refer
+// to them.
+const (
+	// RuleThirdPartyVersion requires a version for third-party projects.
+	RuleThirdPartyVersion = "third_party_version"
+
+	// RuleThirdPartyURL requires at least one non-empty URL for third-party
+	// projects.
+	RuleThirdPartyURL = "third_party_url"
+
+	// RuleURLType requires every URL to have a known type.
+	RuleURLType = "url_type"
+
+	// RuleLicenseType requires the license_type to agree with the
+	// restricted and reciprocal license conditions of the project's targets.
+	RuleLicenseType = "license_type"
+
+	// RuleLastUpgradeDate requires a valid last_upgrade_date not in the future.
+	RuleLastUpgradeDate = "last_upgrade_date"
+)
+
+// LintRules lists the IDs of all of the lint rules.
+var LintRules = []string{
+	RuleThirdPartyVersion,
+	RuleThirdPartyURL,
+	RuleURLType,
+	RuleLicenseType,
+	RuleLastUpgradeDate,
+}
+
+// Finding describes a problem found in a METADATA file.
+type Finding struct {
+	// Project is the path to the directory containing the METADATA file.
+	Project string `json:"project"`
+
+	// File is the path to the METADATA file.
+	File string `json:"file"`
+
+	// Rule is the stable ID of the rule reporting the finding.
+	Rule string `json:"rule"`
+
+	// Severity is how serious the finding is.
+	Severity Severity `json:"severity"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// String returns a human-readable string representation of the finding.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: [%s] %s", f.File, f.Severity, f.Rule, f.Message)
+}
+
+// LintOptions configures ProjectMetadata.Lint.
+type LintOptions struct {
+	// Conditions lists the names of the license conditions of the targets
+	// in the project. When nil, the license_type rule is skipped because the
+	// conditions are unknown.
+	Conditions []string
+
+	// Now is the time after which a last_upgrade_date is in the future. The
+	// zero value means the current time.
+	Now time.Time
+}
+
+// sharingLicenseTypes maps the license conditions that require sharing
+// source, strongest first, to the license_type consistent with each.
+var sharingLicenseTypes = []struct {
+	condition   string
+	licenseType project_metadata_proto.LicenseType
+}{
+	{"restricted", project_metadata_proto.LicenseType_RESTRICTED},
+	{"restricted_if_statically_linked", project_metadata_proto.LicenseType_RESTRICTED_IF_STATICALLY_LINKED},
+	{"reciprocal", project_metadata_proto.LicenseType_RECIPROCAL},
+}
+
+// Lint returns the problems found in the METADATA of `pm` ordered by rule.
+// Projects without a third_party section are first party and have no
+// findings. Fallback metadata is not a METADATA file and has no findings.
+func (pm *ProjectMetadata) Lint(opts LintOptions) []Finding {
+	tp := pm.proto.GetThirdParty()
+	if tp == nil || pm.fallback {
+		return nil
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var findings []Finding
+	report := func(rule string, severity Severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{pm.project, pm.path, rule, severity, fmt.Sprintf(format, args...)})
+	}
+
+	if len(tp.GetVersion()) == 0 {
+		report(RuleThirdPartyVersion, SeverityError, "third_party must have a version")
+	}
+
+	hasURL := false
+	for i, url := range tp.GetUrl() {
+		if len(url.GetValue()) > 0 {
+			hasURL = true
+		}
+		if url.Type == nil {
+			report(RuleURLType, SeverityError, "url %d (%q) must have a type", i+1, url.GetValue())
+		} else if _, ok := project_metadata_proto.URL_Type_name[int32(url.GetType())]; !ok {
+			report(RuleURLType, SeverityError, "url %d (%q) has unknown type %d", i+1, url.GetValue(), int32(url.GetType()))
+		}
+	}
+	if !hasURL {
+		report(RuleThirdPartyURL, SeverityError, "third_party must have at least one url with a value")
+	}
+
+	if opts.Conditions != nil {
+		conditions := make(map[string]bool)
+		for _, c := range opts.Conditions {
+			conditions[c] = true
+		}
+		var expected *project_metadata_proto.LicenseType
+		var because string
+		for _, s := range sharingLicenseTypes {
+			if conditions[s.condition] {
+				expected = s.licenseType.Enum()
+				because = s.condition
+				break
+			}
+		}
+		switch {
+		case expected != nil && tp.LicenseType == nil:
+			report(RuleLicenseType, SeverityError, "license_type missing; want %s for %s license condition", expected, because)
+		case expected != nil && tp.GetLicenseType() != *expected:
+			report(RuleLicenseType, SeverityError, "license_type %s inconsistent with %s license condition; want %s", tp.GetLicenseType(), because, expected)
+		case expected == nil && tp.LicenseType != nil && isSharingLicenseType(tp.GetLicenseType()):
+			report(RuleLicenseType, SeverityError, "license_type %s but no target has the %s license condition", tp.GetLicenseType(), conditionForLicenseType(tp.GetLicenseType()))
+		}
+	}
+
+	if d := tp.GetLastUpgradeDate(); d != nil {
+		if t, err := parseDate(d); err != nil {
+			report(RuleLastUpgradeDate, SeverityError, "last_upgrade_date %s", err)
+		} else if t.After(now) {
+			report(RuleLastUpgradeDate, SeverityError, "last_upgrade_date %s is in the future", t.Format("2006-01-02"))
+		}
+	} else {
+		report(RuleLastUpgradeDate, SeverityWarning, "third_party should have a last_upgrade_date")
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return ruleIndex(findings[i].Rule) < ruleIndex(findings[j].Rule)
+	})
+	return findings
+}
+
+// isSharingLicenseType returns true when `lt` requires sharing source.
+func isSharingLicenseType(lt project_metadata_proto.LicenseType) bool {
+	return len(conditionForLicenseType(lt)) > 0
+}
+
+// conditionForLicenseType returns the name of the source-sharing license
+// condition corresponding to `lt` or the empty string if none.
+func conditionForLicenseType(lt project_metadata_proto.LicenseType) string {
+	for _, s := range sharingLicenseTypes {
+		if s.licenseType == lt {
+			return s.condition
+		}
+	}
+	return ""
+}
+
+// ruleIndex returns the position of `rule` in LintRules.
+func ruleIndex(rule string) int {
+	for i, r := range LintRules {
+		if r == rule {
+			return i
+		}
+	}
+	return len(LintRules)
+}
+
+// ParseLintRule returns `name` if it is a lint rule ID or an error otherwise.
+func ParseLintRule(name string) (string, error) {
+	for _, r := range LintRules {
+		if r == name {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown lint rule %q: want one of %s", name, strings.Join(LintRules, ", "))
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectmetadata
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/tools/compliance/testfs"
+)
+
+func TestLint(t *testing.T) {
+	const (
+		url  = `url { type: GIT value: "https://example.com/lib.git" } `
+		date = `last_upgrade_date { year: 2023 month: 2 day: 28 } `
+	)
+	now := time.Date(2024, time.May, 22, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		metadata   string
+		conditions []string
+		expected   []string
+	}{
+		{
+			name:     "firstparty",
+			metadata: `name: "mylib"`,
+			expected: []string{},
+		},
+		{
+			name:     "clean",
+			metadata: `third_party { version: "1.0" ` + url + date + `}`,
+			expected: []string{},
+		},
+		{
+			name:     "noversion",
+			metadata: `third_party { ` + url + date + `}`,
+			expected: []string{"error third_party_version"},
+		},
+		{
+			name:     "nourl",
+			metadata: `third_party { version: "1.0" ` + date + `}`,
+			expected: []string{"error third_party_url"},
+		},
+		{
+			name:     "emptyurl",
+			metadata: `third_party { version: "1.0" url { type: GIT } ` + date + `}`,
+			expected: []string{"error third_party_url"},
+		},
+		{
+			name:     "untypedurl",
+			metadata: `third_party { version: "1.0" url { value: "https://example.com" } ` + date + `}`,
+			expected: []string{"error url_type"},
+		},
+		{
+			name:     "nodate",
+			metadata: `third_party { version: "1.0" ` + url + `}`,
+			expected: []string{"warning last_upgrade_date"},
+		},
+		{
+			name:     "invaliddate",
+			metadata: `third_party { version: "1.0" ` + url + `last_upgrade_date { year: 2023 month: 13 day: 1 } }`,
+			expected: []string{"error last_upgrade_date"},
+		},
+		{
+			name:     "partialdate",
+			metadata: `third_party { version: "1.0" ` + url + `last_upgrade_date { year: 2023 } }`,
+			expected: []string{"error last_upgrade_date"},
+		},
+		{
+			name:     "futuredate",
+			metadata: `third_party { version: "1.0" ` + url + `last_upgrade_date { year: 2024 month: 5 day: 23 } }`,
+			expected: []string{"error last_upgrade_date"},
+		},
+		{
+			name:       "licensetypematches",
+			metadata:   `third_party { version: "1.0" license_type: RESTRICTED ` + url + date + `}`,
+			conditions: []string{"notice", "restricted"},
+			expected:   []string{},
+		},
+		{
+			name:       "licensetypeweaker",
+			metadata:   `third_party { version: "1.0" license_type: RECIPROCAL ` + url + date + `}`,
+			conditions: []string{"reciprocal", "restricted_if_statically_linked"},
+			expected:   []string{"error license_type"},
+		},
+		{
+			name:       "licensetypemissing",
+			metadata:   `third_party { version: "1.0" ` + url + date + `}`,
+			conditions: []string{"reciprocal"},
+			expected:   []string{"error license_type"},
+		},
+		{
+			name:       "licensetypestronger",
+			metadata:   `third_party { version: "1.0" license_type: RESTRICTED ` + url + date + `}`,
+			conditions: []string{"notice"},
+			expected:   []string{"error license_type"},
+		},
+		{
+			name:       "licensetypenotice",
+			metadata:   `third_party { version: "1.0" license_type: NOTICE ` + url + date + `}`,
+			conditions: []string{"notice"},
+			expected:   []string{},
+		},
+		{
+			name:     "licensetypeunknownconditions",
+			metadata: `third_party { version: "1.0" license_type: RESTRICTED ` + url + date + `}`,
+			expected: []string{},
+		},
+		{
+			name:     "ordered",
+			metadata: `third_party { url { value: "https://example.com" } }`,
+			expected: []string{
+				"error third_party_version",
+				"error url_type",
+				"warning last_upgrade_date",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &testfs.TestFS{"lib/METADATA": []byte(tt.metadata)}
+			pms, err := NewIndex(fs).MetadataForProjects("lib")
+			if err != nil {
+				t.Fatalf("unexpected error reading test data: %s", err)
+			}
+			findings := pms[0].Lint(LintOptions{Conditions: tt.conditions, Now: now})
+			actual := make([]string, 0, len(findings))
+			for _, f := range findings {
+				actual = append(actual, string(f.Severity)+" "+f.Rule)
+				if f.File != "lib/METADATA" || f.Project != "lib" {
+					t.Errorf("unexpected location: got %q in %q, want %q in %q", f.File, f.Project, "lib/METADATA", "lib")
+				}
+			}
+			if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("unexpected findings: got %q, want %q: %v", actual, tt.expected, findings)
+			}
+		})
+	}
+}
+
+func TestParseLintRule(t *testing.T) {
+	for _, rule := range LintRules {
+		if r, err := ParseLintRule(rule); err != nil || r != rule {
+			t.Errorf("ParseLintRule(%q): got %q, %v, want %q, no error", rule, r, err, rule)
+		}
+	}
+	if _, err := ParseLintRule("no_such_rule"); err == nil {
+		t.Errorf("ParseLintRule(%q): got no error, want error", "no_such_rule")
+	}
+}
@@ -15,14 +15,17 @@
 package projectmetadata
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"android/soong/compliance/project_metadata_proto"
+	"android/soong/tools/compliance/tracing"
 
 	"google.golang.org/protobuf/encoding/prototext"
 )
@@ -38,6 +41,16 @@ type ProjectMetadata struct {
 
 	// project is the path to the directory containing the METADATA file.
 	project string
+
+	// path is the path to the METADATA file.
+	path string
+
+	// fallback is true when the metadata comes from fallback files instead of
+	// a METADATA file.
+	fallback bool
+
+	// provenance maps field name to the path of the file supplying the field.
+	provenance map[string]string
 }
 
 // ProjectUrlMap maps url type name to url value
@@ -63,6 +76,23 @@ func (pm *ProjectMetadata) Project() string {
 	return pm.project
 }
 
+// MetadataFile returns the path to the METADATA file.
+func (pm *ProjectMetadata) MetadataFile() string {
+	return pm.path
+}
+
+// IsFallback returns true when the metadata comes from files other than
+// METADATA e.g. README.chromium or Cargo.toml.
+func (pm *ProjectMetadata) IsFallback() bool {
+	return pm.fallback
+}
+
+// Provenance returns the path to the file supplying `field` i.e. FieldName,
+// FieldVersion or FieldURL, or the empty string if the field has no value.
+func (pm *ProjectMetadata) Provenance(field string) string {
+	return pm.provenance[field]
+}
+
 // Name returns the name of the project.
 func (pm *ProjectMetadata) Name() string {
 	return pm.proto.GetName()
@@ -119,11 +149,52 @@ func (pm *ProjectMetadata) UrlsByTypeName() ProjectUrlMap {
 	return urls
 }
 
+// IsThirdParty returns true when the METADATA has a third_party section.
+// Fallback metadata is never third party: package manifests with versions and
+// urls describe first-party projects too.
+func (pm *ProjectMetadata) IsThirdParty() bool {
+	return !pm.fallback && pm.proto.GetThirdParty() != nil
+}
+
+// LicenseType returns the name of the license type of the project if available.
+func (pm *ProjectMetadata) LicenseType() string {
+	tp := pm.proto.GetThirdParty()
+	if tp == nil || tp.LicenseType == nil {
+		return ""
+	}
+	return tp.GetLicenseType().String()
+}
+
+// LastUpgradeDate returns the date of the last upgrade of the project, and
+// false if the METADATA has no complete and valid last_upgrade_date.
+func (pm *ProjectMetadata) LastUpgradeDate() (time.Time, bool) {
+	d := pm.proto.GetThirdParty().GetLastUpgradeDate()
+	if d == nil {
+		return time.Time{}, false
+	}
+	t, err := parseDate(d)
+	return t, err == nil
+}
+
+// parseDate returns the UTC midnight of `d`, or an error when `d` lacks a
+// year, month or day or names a day that does not exist.
+func parseDate(d *project_metadata_proto.Date) (time.Time, error) {
+	if d.Year == nil || d.Month == nil || d.Day == nil {
+		return time.Time{}, fmt.Errorf("must have a year, month and day")
+	}
+	t := time.Date(int(d.GetYear()), time.Month(d.GetMonth()), int(d.GetDay()), 0, 0, 0, 0, time.UTC)
+	if t.Year() != int(d.GetYear()) || int(t.Month()) != int(d.GetMonth()) || t.Day() != int(d.GetDay()) {
+		return time.Time{}, fmt.Errorf("%04d-%02d-%02d is not a valid date", d.GetYear(), d.GetMonth(), d.GetDay())
+	}
+	return t, nil
+}
+
 // projectIndex describes a project to be read; after `wg.Wait()`, will contain either
 // a `ProjectMetadata`, pm (can be nil even without error), or a non-nil `err`.
 type projectIndex struct {
 	project string
 	path    string
+	files   []string
 	pm      *ProjectMetadata
 	err     error
 	done    chan struct{}
@@ -139,40 +210,116 @@ func (pi *projectIndex) wait() {
 	<-pi.done
 }
 
+// IndexProgress describes how far the reads of METADATA files have advanced.
+type IndexProgress struct {
+	// FilesRead counts the METADATA files read and parsed so far.
+	FilesRead int
+
+	// ProjectsQueued counts the projects scheduled for lookup so far including
+	// the projects already looked up.
+	ProjectsQueued int
+
+	// BytesRead counts the bytes of METADATA read so far.
+	BytesRead int64
+}
+
+// IndexOptions configures NewIndexWithOptions.
+type IndexOptions struct {
+	// ConcurrentReaders is the size of the task pool for limiting resource
+	// usage e.g. open files. Zero means use the package `ConcurrentReaders`.
+	ConcurrentReaders int
+
+	// Progress, when not nil, gets called after each METADATA file is read.
+	// Calls happen one at a time, but possibly from different goroutines.
+	Progress func(IndexProgress)
+
+	// Readers lists the fallback readers for projects without METADATA.android
+	// or METADATA files. Nil means use `DefaultMetadataReaders`; an empty
+	// non-nil slice disables fallback.
+	Readers []MetadataReader
+}
+
 // Index reads and caches ProjectMetadata (thread safe)
 type Index struct {
 	// projecs maps project name to a wait group if read has already started, and
 	// to a `ProjectMetadata` or to an `error` after the read completes.
 	projects sync.Map
 
+	// ctx aborts outstanding reads when cancelled.
+	ctx context.Context
+
+	// concurrentReaders is the size of the task pool.
+	concurrentReaders int
+
 	// task provides a fixed-size task pool to limit concurrent open files etc.
 	task chan bool
 
 	// rootFS locates the root of the file system from which to read the files.
 	rootFS fs.FS
+
+	// progressFn optionally receives progress updates.
+	progressFn func(IndexProgress)
+
+	// readers lists the fallback readers for projects without METADATA.
+	readers []MetadataReader
+
+	// progress accumulates the progress reported to `progressFn`. (guarded by mu)
+	progress IndexProgress
+
+	// mu guards `progress` and serializes calls to `progressFn`.
+	mu sync.Mutex
 }
 
 // NewIndex constructs a project metadata `Index` for the given file system.
 func NewIndex(rootFS fs.FS) *Index {
-	ix := &Index{task: make(chan bool, ConcurrentReaders), rootFS: rootFS}
-	for i := 0; i < ConcurrentReaders; i++ {
-		ix.task <- true
+	return NewIndexWithOptions(context.Background(), rootFS, IndexOptions{})
+}
+
+// NewIndexWithOptions constructs a project metadata `Index` for the given file
+// system configured by `opts`.
+//
+// Cancelling `ctx` aborts the in-flight reads, and causes subsequent calls to
+// MetadataForProjects to return the context error.
+func NewIndexWithOptions(ctx context.Context, rootFS fs.FS, opts IndexOptions) *Index {
+	concurrentReaders := opts.ConcurrentReaders
+	if concurrentReaders == 0 {
+		concurrentReaders = ConcurrentReaders
+	}
+	ix := &Index{
+		ctx:               ctx,
+		concurrentReaders: concurrentReaders,
+		rootFS:            rootFS,
+		progressFn:        opts.Progress,
+		readers:           opts.Readers,
+	}
+	if ix.readers == nil {
+		ix.readers = DefaultMetadataReaders
+	}
+	if concurrentReaders > 0 {
+		ix.task = make(chan bool, concurrentReaders)
+		for i := 0; i < concurrentReaders; i++ {
+			ix.task <- true
+		}
 	}
 	return ix
 }
 
 // MetadataForProjects returns 0..n ProjectMetadata for n `projects`, or an error.
 // Each project that has a METADATA.android or a METADATA file in the root of the project will have
-// a corresponding ProjectMetadata in the result. Projects with neither file get skipped. A nil
-// result with no error indicates none of the given `projects` has a METADATA file.
+// a corresponding ProjectMetadata in the result. Projects with neither file fall back to the
+// configured `MetadataReader`s, and get skipped when none of those find anything. A nil result
+// with no error indicates none of the given `projects` has any metadata.
 // (thread safe -- can be called concurrently from multiple goroutines)
 func (ix *Index) MetadataForProjects(projects ...string) ([]*ProjectMetadata, error) {
-	if ConcurrentReaders < 1 {
+	if ix.concurrentReaders < 1 {
 		return nil, fmt.Errorf("need at least one task in project metadata pool")
 	}
 	if len(projects) == 0 {
 		return nil, nil
 	}
+	if err := ix.ctx.Err(); err != nil {
+		return nil, err
+	}
 	// Identify the projects that have never been read
 	projectsToRead := make([]*projectIndex, 0, len(projects))
 	projectIndexes := make([]*projectIndex, 0, len(projects))
@@ -183,10 +330,23 @@ func (ix *Index) MetadataForProjects(projects ...string) ([]*ProjectMetadata, er
 		}
 		projectIndexes = append(projectIndexes, pi.(*projectIndex))
 	}
+	if len(projectsToRead) > 0 {
+		ix.mu.Lock()
+		ix.progress.ProjectsQueued += len(projectsToRead)
+		ix.mu.Unlock()
+	}
 	// findMeta locates and reads the appropriate METADATA file, if any.
 	findMeta := func(pi *projectIndex) {
-		<-ix.task
+		select {
+		case <-ix.task:
+		case <-ix.ctx.Done():
+			pi.err = fmt.Errorf("reading project %q metadata: %w", pi.project, ix.ctx.Err())
+			pi.finish()
+			return
+		}
+		span := tracing.Begin(tracing.CategoryRead, "read project metadata").Arg("project", pi.project)
 		defer func() {
+			span.End()
 			ix.task <- true
 			pi.finish()
 		}()
@@ -209,7 +369,8 @@ func (ix *Index) MetadataForProjects(projects ...string) ([]*ProjectMetadata, er
 				return
 			}
 		}
-		// no METADATA file exists -- leave nil and finish
+		// No METADATA file exists -- try the fallback files.
+		ix.readFallbackFiles(pi)
 	}
 	// Look for the METADATA files to read, and record any missing.
 	for _, p := range projectsToRead {
@@ -244,6 +405,7 @@ func (ix *Index) AllMetadataFiles() []string {
 		if pi.path != "" {
 			files = append(files, pi.path)
 		}
+		files = append(files, pi.files...)
 		return true
 	})
 	return files
@@ -258,15 +420,15 @@ func (ix *Index) readMetadataFile(pi *projectIndex, path string) {
 	}
 
 	// read the file
-	data, err := io.ReadAll(f)
+	data, err := io.ReadAll(ctxReader{ix.ctx, f})
+	f.Close()
 	if err != nil {
 		pi.err = fmt.Errorf("error reading project %q metadata %q: %w", pi.project, path, err)
 		return
 	}
-	f.Close()
 
 	uo := prototext.UnmarshalOptions{DiscardUnknown: true}
-	pm := &ProjectMetadata{project: pi.project}
+	pm := &ProjectMetadata{project: pi.project, path: path, provenance: make(map[string]string)}
 	err = uo.Unmarshal(data, &pm.proto)
 	if err != nil {
 		pi.err = fmt.Errorf(`error in project %q METADATA %q: %v
@@ -287,6 +449,85 @@ a METADATA.android file beside it to parse instead
 		return
 	}
 
+	if pm.proto.Name != nil {
+		pm.provenance[FieldName] = path
+	}
+	if pm.Version() != "" {
+		pm.provenance[FieldVersion] = path
+	}
+	if len(pm.UrlsByTypeName()) > 0 {
+		pm.provenance[FieldURL] = path
+	}
+
 	pi.path = path
 	pi.pm = pm
+
+	ix.reportRead(len(data))
+}
+
+// readFallbackFiles tries each fallback reader in the root of the project,
+// and combines whatever fields they find. Fallback files are best effort:
+// files that do not parse get ignored.
+func (ix *Index) readFallbackFiles(pi *projectIndex) {
+	var paths []string
+	var found []MetadataFields
+	for _, reader := range ix.readers {
+		path := filepath.Join(pi.project, reader.FileName)
+		fi, err := fs.Stat(ix.rootFS, path)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		f, err := ix.rootFS.Open(path)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(ctxReader{ix.ctx, f})
+		f.Close()
+		if err != nil {
+			if ix.ctx.Err() != nil {
+				pi.err = fmt.Errorf("error reading project %q metadata %q: %w", pi.project, path, err)
+				return
+			}
+			continue
+		}
+		ix.reportRead(len(data))
+		fields, err := reader.Read(data)
+		if err != nil {
+			continue
+		}
+		pi.files = append(pi.files, path)
+		paths = append(paths, path)
+		found = append(found, fields)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	pi.pm = fallbackMetadata(pi.project, paths, found)
+}
+
+// reportRead records reading a file of `size` bytes and notifies `progressFn`.
+func (ix *Index) reportRead(size int) {
+	tracing.Count("metadata_files", 1)
+	tracing.Count("metadata_bytes", int64(size))
+	if ix.progressFn != nil {
+		ix.mu.Lock()
+		ix.progress.FilesRead++
+		ix.progress.BytesRead += int64(size)
+		ix.progressFn(ix.progress)
+		ix.mu.Unlock()
+	}
+}
+
+// ctxReader is an io.Reader that stops reading once its context is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read returns the context error once cancelled, or reads from the underlying reader.
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
 }
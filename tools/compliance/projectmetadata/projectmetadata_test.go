@@ -15,9 +15,12 @@
 package projectmetadata
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"android/soong/compliance/project_metadata_proto"
 	"android/soong/tools/compliance/testfs"
@@ -650,6 +653,117 @@ func TestReadMetadataForProjects(t *testing.T) {
 	}
 }
 
+func TestIndexWithOptions(t *testing.T) {
+	fs := &testfs.TestFS{
+		"/a/METADATA":         []byte("name: \"Android\"\n"),
+		"/b/METADATA.android": []byte(MY_LIB_1_0),
+		"/c/METADATA":         []byte(NO_NAME_0_1),
+	}
+
+	t.Run("progress", func(t *testing.T) {
+		var reports []IndexProgress
+		ix := NewIndexWithOptions(context.Background(), fs, IndexOptions{
+			ConcurrentReaders: 1,
+			Progress:          func(p IndexProgress) { reports = append(reports, p) },
+		})
+		pms, err := ix.MetadataForProjects("/a", "/b", "/c", "/d")
+		if err != nil {
+			t.Fatalf("unexpected error: got %s, want no error", err)
+		}
+		if len(pms) != 3 {
+			t.Errorf("len(pms): got %d, want 3", len(pms))
+		}
+		if len(reports) != 3 {
+			t.Fatalf("len(reports): got %d, want 3", len(reports))
+		}
+		expectedBytes := int64(len((*fs)["/a/METADATA"]) + len((*fs)["/b/METADATA.android"]) + len((*fs)["/c/METADATA"]))
+		last := reports[len(reports)-1]
+		if last.FilesRead != 3 || last.ProjectsQueued != 4 || last.BytesRead != expectedBytes {
+			t.Errorf("final progress: got %+v, want {FilesRead:3 ProjectsQueued:4 BytesRead:%d}", last, expectedBytes)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ix := NewIndexWithOptions(ctx, fs, IndexOptions{})
+		cancel()
+		_, err := ix.MetadataForProjects("/a")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("no readers", func(t *testing.T) {
+		ix := NewIndexWithOptions(context.Background(), fs, IndexOptions{ConcurrentReaders: -1})
+		_, err := ix.MetadataForProjects("/a")
+		if err == nil {
+			t.Errorf("unexpected success: got no error, want error")
+		}
+	})
+}
+
+func TestLastUpgradeDate(t *testing.T) {
+	const url = `url { type: GIT value: "https://example.com/lib.git" } `
+	now := time.Date(2024, time.May, 22, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		date            string
+		expectedDate    string
+		expectedMessage string
+	}{
+		{
+			name:         "valid",
+			date:         `last_upgrade_date { year: 2023 month: 2 day: 28 }`,
+			expectedDate: "2023-02-28",
+		},
+		{
+			name:            "missing",
+			expectedMessage: "third_party should have a last_upgrade_date",
+		},
+		{
+			name:            "incomplete",
+			date:            `last_upgrade_date { year: 2023 }`,
+			expectedMessage: "last_upgrade_date must have a year, month and day",
+		},
+		{
+			name:            "invalid",
+			date:            `last_upgrade_date { year: 2023 month: 2 day: 30 }`,
+			expectedMessage: "last_upgrade_date 2023-02-30 is not a valid date",
+		},
+		{
+			name:            "future",
+			date:            `last_upgrade_date { year: 2024 month: 5 day: 23 }`,
+			expectedDate:    "2024-05-23",
+			expectedMessage: "last_upgrade_date 2024-05-23 is in the future",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &testfs.TestFS{"lib/METADATA": []byte(`third_party { version: "1.0" ` + url + tt.date + `}`)}
+			pms, err := NewIndex(fs).MetadataForProjects("lib")
+			if err != nil {
+				t.Fatalf("unexpected error reading test data: %s", err)
+			}
+			var actualDate string
+			if d, ok := pms[0].LastUpgradeDate(); ok {
+				actualDate = d.Format("2006-01-02")
+			}
+			if actualDate != tt.expectedDate {
+				t.Errorf("LastUpgradeDate(): got %q, want %q", actualDate, tt.expectedDate)
+			}
+			var actualMessage string
+			for _, f := range pms[0].Lint(LintOptions{Now: now}) {
+				if f.Rule == RuleLastUpgradeDate {
+					actualMessage = f.Message
+				}
+			}
+			if actualMessage != tt.expectedMessage {
+				t.Errorf("Lint(): got %q, want %q", actualMessage, tt.expectedMessage)
+			}
+		})
+	}
+}
+
 type pmeta struct {
 	project       string
 	versionedName string
@@ -15,14 +15,17 @@
 package compliance
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"android/soong/compliance/license_metadata_proto"
+	"android/soong/tools/compliance/tracing"
 
 	"google.golang.org/protobuf/encoding/prototext"
 )
@@ -55,6 +58,40 @@ func GetFS(outDir string) fs.FS {
 	return os.DirFS(".")
 }
 
+// ReadProgress describes how far a read of license metadata files has advanced.
+type ReadProgress struct {
+	// FilesRead counts the license metadata files read and parsed so far.
+	FilesRead int
+
+	// FilesQueued counts the license metadata files scheduled for reading so
+	// far including the files already read.
+	FilesQueued int
+
+	// BytesRead counts the bytes of license metadata read so far.
+	BytesRead int64
+}
+
+// ReadOptions configures ReadLicenseGraphWithOptions.
+type ReadOptions struct {
+	// RootFS locates the root of the file system from which to read the files.
+	RootFS fs.FS
+
+	// Stderr identifies the error output writer.
+	Stderr io.Writer
+
+	// Files become the root files of the graph for top-down walks of the graph.
+	Files []string
+
+	// ConcurrentReaders is the size of the task pool for limiting resource
+	// usage e.g. open files. Zero means use the package `ConcurrentReaders`.
+	ConcurrentReaders int
+
+	// Progress, when not nil, gets called after each file is read and parsed.
+	// Calls happen one at a time from the goroutine calling
+	// ReadLicenseGraphWithOptions.
+	Progress func(ReadProgress)
+}
+
 // result describes the outcome of reading and parsing a single license metadata file.
 type result struct {
 	// file identifies the path to the license metadata file
@@ -63,6 +100,9 @@ type result struct {
 	// target contains the parsed metadata or nil if an error
 	target *TargetNode
 
+	// size is the number of bytes read from the file
+	size int64
+
 	// err is nil unless an error occurs
 	err error
 }
@@ -72,6 +112,9 @@ type receiver struct {
 	// lg accumulates the read metadata and becomes the final resulting LicenseGraph.
 	lg *LicenseGraph
 
+	// ctx aborts outstanding tasks when cancelled.
+	ctx context.Context
+
 	// rootFS locates the root of the file system from which to read the files.
 	rootFS fs.FS
 
@@ -84,6 +127,9 @@ type receiver struct {
 	// results returns one license metadata file result at a time.
 	results chan *result
 
+	// queued counts the files scheduled for reading.
+	queued atomic.Int64
+
 	// wg detects when done
 	wg sync.WaitGroup
 }
@@ -92,12 +138,40 @@ type receiver struct {
 //
 // `files` become the root files of the graph for top-down walks of the graph.
 func ReadLicenseGraph(rootFS fs.FS, stderr io.Writer, files []string) (*LicenseGraph, error) {
+	return ReadLicenseGraphWithOptions(context.Background(), ReadOptions{
+		RootFS: rootFS,
+		Stderr: stderr,
+		Files:  files,
+	})
+}
+
+// ReadLicenseGraphWithOptions reads and parses `opts.Files` and their
+// dependencies into a LicenseGraph.
+//
+// Cancelling `ctx` aborts the in-flight readers and returns the context error.
+func ReadLicenseGraphWithOptions(ctx context.Context, opts ReadOptions) (*LicenseGraph, error) {
+	files := opts.Files
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no license metadata to analyze")
 	}
-	if ConcurrentReaders < 1 {
+	concurrentReaders := opts.ConcurrentReaders
+	if concurrentReaders == 0 {
+		concurrentReaders = ConcurrentReaders
+	}
+	if concurrentReaders < 1 {
 		return nil, fmt.Errorf("need at least one task in pool")
 	}
+	stderr := opts.Stderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	span := tracing.Begin(tracing.CategoryRead, "ReadLicenseGraph").Arg("roots", len(files))
+	defer span.End()
+
+	// cancel aborts any outstanding tasks on error or once finished.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	lg := newLicenseGraph()
 	for _, f := range files {
@@ -110,26 +184,27 @@ func ReadLicenseGraph(rootFS fs.FS, stderr io.Writer, files []string) (*LicenseG
 
 	recv := &receiver{
 		lg:      lg,
-		rootFS:  rootFS,
+		ctx:     ctx,
+		rootFS:  opts.RootFS,
 		stderr:  stderr,
-		task:    make(chan bool, ConcurrentReaders),
-		results: make(chan *result, ConcurrentReaders),
+		task:    make(chan bool, concurrentReaders),
+		results: make(chan *result, concurrentReaders),
 		wg:      sync.WaitGroup{},
 	}
-	for i := 0; i < ConcurrentReaders; i++ {
+	for i := 0; i < concurrentReaders; i++ {
 		recv.task <- true
 	}
 
 	readFiles := func() {
-		lg.mu.Lock()
+		recv.lg.mu.Lock()
 		// identify the metadata files to schedule reading tasks for
-		for _, f := range lg.rootFiles {
-			lg.targets[f] = nil
+		for _, f := range recv.lg.rootFiles {
+			recv.lg.targets[f] = nil
 		}
-		lg.mu.Unlock()
+		recv.lg.mu.Unlock()
 
 		// schedule tasks to read the files
-		for _, f := range lg.rootFiles {
+		for _, f := range recv.lg.rootFiles {
 			readFile(recv, f)
 		}
 
@@ -144,16 +219,20 @@ func ReadLicenseGraph(rootFS fs.FS, stderr io.Writer, files []string) (*LicenseG
 
 	// tasks to read license metadata files are scheduled; read and process results from channel
 	var err error
-	for recv.results != nil {
+	var progress ReadProgress
+	results := recv.results
+	for results != nil {
 		select {
-		case r, ok := <-recv.results:
+		case r, ok := <-results:
 			if ok {
-				// handle errors by nil'ing ls, setting err, and clobbering results channel
+				// handle errors by nil'ing ls, setting err, cancelling outstanding tasks,
+				// and clobbering results channel
 				if r.err != nil {
 					err = r.err
 					fmt.Fprintf(recv.stderr, "%s\n", err.Error())
 					lg = nil
-					recv.results = nil
+					results = nil
+					cancel()
 					continue
 				}
 
@@ -161,16 +240,37 @@ func ReadLicenseGraph(rootFS fs.FS, stderr io.Writer, files []string) (*LicenseG
 				recv.lg.mu.Lock()
 				lg.targets[r.target.name] = r.target
 				recv.lg.mu.Unlock()
+
+				if opts.Progress != nil {
+					progress.FilesRead++
+					progress.FilesQueued = int(recv.queued.Load())
+					progress.BytesRead += r.size
+					opts.Progress(progress)
+				}
 			} else {
 				// finished -- nil the results channel
-				recv.results = nil
+				results = nil
 			}
+		case <-ctx.Done():
+			// cancelled -- abandon the results
+			err = ctx.Err()
+			lg = nil
+			results = nil
 		}
 	}
 
+	// The results channel can close after cancellation with targets unread.
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+		lg = nil
+	}
+
 	if lg != nil {
 		esize := 0
-		for _, tn := range lg.targets {
+		for name, tn := range lg.targets {
+			if tn == nil {
+				return nil, fmt.Errorf("license metadata %q never read", name)
+			}
 			esize += len(tn.proto.Deps)
 		}
 		lg.edges = make(TargetEdgeList, 0, esize)
@@ -244,33 +344,69 @@ func addDependencies(lg *LicenseGraph, tn *TargetNode) error {
 // additional tasks for reading and parsing dependencies as necessary.
 func readFile(recv *receiver, file string) {
 	recv.wg.Add(1)
-	<-recv.task
+	recv.queued.Add(1)
+	select {
+	case <-recv.task:
+	case <-recv.ctx.Done():
+		// cancelled before starting -- nothing to do
+		recv.wg.Done()
+		return
+	}
 	go func() {
+		// sendResult delivers `r` unless cancelled, and reports whether delivered.
+		sendResult := func(r *result) bool {
+			select {
+			case recv.results <- r:
+				return true
+			case <-recv.ctx.Done():
+				return false
+			}
+		}
+
+		// abort releases the task and signals done without scheduling dependencies.
+		abort := func() {
+			recv.task <- true
+			recv.wg.Done()
+		}
+
+		span := tracing.Begin(tracing.CategoryRead, "read meta_lic").Arg("file", file)
 		f, err := recv.rootFS.Open(file)
 		if err != nil {
-			recv.results <- &result{file, nil, fmt.Errorf("error opening license metadata %q: %w", file, err)}
+			span.End()
+			sendResult(&result{file, nil, 0, fmt.Errorf("error opening license metadata %q: %w", file, err)})
+			abort()
 			return
 		}
 
 		// read the file
-		data, err := io.ReadAll(f)
+		data, err := io.ReadAll(ctxReader{recv.ctx, f})
+		f.Close()
+		span.Arg("bytes", len(data)).End()
+		tracing.Count("meta_lic_files", 1)
+		tracing.Count("meta_lic_bytes", int64(len(data)))
 		if err != nil {
-			recv.results <- &result{file, nil, fmt.Errorf("error reading license metadata %q: %w", file, err)}
+			if recv.ctx.Err() == nil {
+				sendResult(&result{file, nil, 0, fmt.Errorf("error reading license metadata %q: %w", file, err)})
+			}
+			abort()
 			return
 		}
-		f.Close()
 
 		tn := &TargetNode{lg: recv.lg, name: file}
 
 		err = prototext.Unmarshal(data, &tn.proto)
 		if err != nil {
-			recv.results <- &result{file, nil, fmt.Errorf("error license metadata %q: %w", file, err)}
+			sendResult(&result{file, nil, 0, fmt.Errorf("error license metadata %q: %w", file, err)})
+			abort()
 			return
 		}
 
 		// send result for this file and release task before scheduling dependencies,
 		// but do not signal done to WaitGroup until dependencies are scheduled.
-		recv.results <- &result{file, tn, nil}
+		if !sendResult(&result{file, tn, int64(len(data)), nil}) {
+			abort()
+			return
+		}
 		recv.task <- true
 
 		// schedule tasks as necessary to read dependencies
@@ -293,3 +429,17 @@ func readFile(recv *receiver, file string) {
 		recv.wg.Done()
 	}()
 }
+
+// ctxReader is an io.Reader that stops reading once its context is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read returns the context error once cancelled, or reads from the underlying reader.
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -16,9 +16,12 @@ package compliance
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"android/soong/tools/compliance/testfs"
 )
@@ -150,3 +153,115 @@ func TestReadLicenseGraph(t *testing.T) {
 		})
 	}
 }
+
+func TestReadLicenseGraphWithOptions(t *testing.T) {
+	fs := &testfs.TestFS{
+		"apex.meta_lic": []byte(AOSP + "deps: {\n  file: \"app.meta_lic\"\n}\ndeps: {\n  file: \"bin.meta_lic\"\n}\n"),
+		"app.meta_lic":  []byte(AOSP),
+		"bin.meta_lic":  []byte(AOSP + "deps: {\n  file: \"lib.meta_lic\"\n}\n"),
+		"lib.meta_lic":  []byte(AOSP),
+	}
+	totalBytes := int64(0)
+	for _, data := range *fs {
+		totalBytes += int64(len(data))
+	}
+
+	t.Run("progress", func(t *testing.T) {
+		var reports []ReadProgress
+		lg, err := ReadLicenseGraphWithOptions(context.Background(), ReadOptions{
+			RootFS:            fs,
+			Stderr:            &bytes.Buffer{},
+			Files:             []string{"apex.meta_lic"},
+			ConcurrentReaders: 1,
+			Progress:          func(p ReadProgress) { reports = append(reports, p) },
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: got %s, want no error", err)
+		}
+		if len(lg.Targets()) != 4 {
+			t.Errorf("len(targets): got %d, want 4", len(lg.Targets()))
+		}
+		if len(reports) != 4 {
+			t.Fatalf("len(reports): got %d, want 4", len(reports))
+		}
+		last := reports[len(reports)-1]
+		if last.FilesRead != 4 || last.FilesQueued != 4 || last.BytesRead != totalBytes {
+			t.Errorf("final progress: got %+v, want {FilesRead:4 FilesQueued:4 BytesRead:%d}", last, totalBytes)
+		}
+		for i, p := range reports {
+			if p.FilesRead != i+1 {
+				t.Errorf("reports[%d].FilesRead: got %d, want %d", i, p.FilesRead, i+1)
+			}
+			if p.FilesQueued < p.FilesRead {
+				t.Errorf("reports[%d]: got %d queued < %d read, want queued >= read", i, p.FilesQueued, p.FilesRead)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		lg, err := ReadLicenseGraphWithOptions(ctx, ReadOptions{
+			RootFS: fs,
+			Files:  []string{"apex.meta_lic"},
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+		if lg != nil {
+			t.Errorf("unexpected license graph: got %d targets, want nil", len(lg.Targets()))
+		}
+	})
+
+	t.Run("cancelled during read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, err := ReadLicenseGraphWithOptions(ctx, ReadOptions{
+			RootFS:            fs,
+			Files:             []string{"apex.meta_lic"},
+			ConcurrentReaders: 1,
+			Progress:          func(p ReadProgress) { cancel() },
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("cancelled from progress", func(t *testing.T) {
+		// Waiting after cancelling lets the aborted readers close the results
+		// channel, so both can be ready at once. Neither may return a partial
+		// graph.
+		for i := 0; i < 20; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			lg, err := ReadLicenseGraphWithOptions(ctx, ReadOptions{
+				RootFS:            fs,
+				Files:             []string{"apex.meta_lic"},
+				ConcurrentReaders: 2,
+				Progress: func(p ReadProgress) {
+					if p.FilesRead == 1 {
+						cancel()
+						time.Sleep(5 * time.Millisecond)
+					}
+				},
+			})
+			cancel()
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+			}
+			if lg != nil {
+				t.Fatalf("unexpected license graph: got %d targets, want nil", len(lg.Targets()))
+			}
+		}
+	})
+
+	t.Run("no readers", func(t *testing.T) {
+		_, err := ReadLicenseGraphWithOptions(context.Background(), ReadOptions{
+			RootFS:            fs,
+			Files:             []string{"apex.meta_lic"},
+			ConcurrentReaders: -1,
+		})
+		if err == nil {
+			t.Errorf("unexpected success: got no error, want error")
+		}
+	})
+}
@@ -0,0 +1,350 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package starlarkpolicy evaluates Starlark policy files over a license graph.
+//
+// A policy file defines a `check(graph)` function returning a list of
+// findings created by the `finding(rule, message, target=None,
+// severity="error")` builtin. e.g.
+//
+//	def check(graph):
+//	    return [
+//	        finding("no-by-exception", "by_exception_only module ships", target=t)
+//	        for t in graph.shipped()
+//	        if "by_exception_only" in t.license_conditions
+//	    ]
+//
+// The `graph` exposes the targets, edges, and resolutions of the license
+// graph. Policy files may load other Starlark files relative to the loading
+// file.
+package starlarkpolicy
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/projectmetadata"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+var (
+	// Severities lists the recognized finding severities from most to least severe.
+	Severities = []string{"error", "warning", "info"}
+
+	// findingConstructor identifies structs created by the `finding` builtin.
+	findingConstructor = starlark.String("finding")
+)
+
+// Finding describes a single outcome reported by a policy file.
+type Finding struct {
+	// Policy is the path to the policy file reporting the finding.
+	Policy string `json:"policy"`
+
+	// Rule is the policy-defined identifier for the rule.
+	Rule string `json:"rule"`
+
+	// Severity is one of `Severities`.
+	Severity string `json:"severity"`
+
+	// Target is the name of the target the finding is about if any.
+	Target string `json:"target,omitempty"`
+
+	// Message describes the finding.
+	Message string `json:"message"`
+}
+
+// String returns a human-readable string representation of the finding.
+func (f Finding) String() string {
+	if f.Target == "" {
+		return fmt.Sprintf("%s: %s: %s: %s", f.Severity, f.Policy, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s: %s", f.Severity, f.Policy, f.Rule, f.Target, f.Message)
+}
+
+// FindingList orders findings by policy, rule, target, then message.
+type FindingList []Finding
+
+// Len returns the count of elements in the list.
+func (l FindingList) Len() int { return len(l) }
+
+// Swap rearranges 2 elements so that each occupies the other's former position.
+func (l FindingList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// Less returns true when the `i`th element is lexicographically less than the `j`th.
+func (l FindingList) Less(i, j int) bool {
+	if l[i].Policy != l[j].Policy {
+		return l[i].Policy < l[j].Policy
+	}
+	if l[i].Rule != l[j].Rule {
+		return l[i].Rule < l[j].Rule
+	}
+	if l[i].Target != l[j].Target {
+		return l[i].Target < l[j].Target
+	}
+	return l[i].Message < l[j].Message
+}
+
+// modentry caches the outcome of loading a Starlark module.
+type modentry struct {
+	globals starlark.StringDict
+	err     error
+}
+
+// Checker evaluates policy files over a single license graph.
+//
+// A Checker is not safe for concurrent use.
+type Checker struct {
+	// lg is the license graph to check.
+	lg *compliance.LicenseGraph
+
+	// pmix looks up the project metadata for targets.
+	pmix *projectmetadata.Index
+
+	// stderr receives the output of Starlark `print`.
+	stderr io.Writer
+
+	// targets maps each target node to its unique Starlark value.
+	targets map[*compliance.TargetNode]*targetValue
+
+	// modules caches the loaded Starlark modules by path.
+	modules map[string]*modentry
+
+	// predeclared contains the builtins available to policy files.
+	predeclared starlark.StringDict
+}
+
+// NewChecker constructs a Checker for `lg` using `pmix` to look up project
+// metadata and writing Starlark `print` output to `stderr`.
+func NewChecker(lg *compliance.LicenseGraph, pmix *projectmetadata.Index, stderr io.Writer) *Checker {
+	c := &Checker{
+		lg:      lg,
+		pmix:    pmix,
+		stderr:  stderr,
+		targets: make(map[*compliance.TargetNode]*targetValue),
+		modules: make(map[string]*modentry),
+	}
+	c.predeclared = starlark.StringDict{
+		"struct":  starlark.NewBuiltin("struct", starlarkstruct.Make),
+		"finding": starlark.NewBuiltin("finding", makeFinding),
+		"policy": starlarkstruct.FromStringDict(starlark.String("policy"), starlark.StringDict{
+			"notice":             conditionNames(compliance.ImpliesNotice),
+			"reciprocal":         conditionNames(compliance.ImpliesReciprocal),
+			"restricted":         conditionNames(compliance.ImpliesRestricted),
+			"network_use":        conditionNames(compliance.ImpliesNetworkUse),
+			"patent_retaliation": conditionNames(compliance.ImpliesPatentRetaliation),
+			"proprietary":        conditionNames(compliance.ImpliesProprietary),
+			"by_exception_only":  conditionNames(compliance.ImpliesByExceptionOnly),
+			"private":            conditionNames(compliance.ImpliesPrivate),
+			"shared":             conditionNames(compliance.ImpliesShared),
+		}),
+	}
+	return c
+}
+
+// Check executes the policy file `filename` and calls its `check` function
+// with the license graph returning the findings.
+//
+// `src` is as for starlark.ExecFile: nil to read `filename`, or the content
+// of the policy file as a string, []byte, or io.Reader.
+func (c *Checker) Check(filename string, src interface{}) (FindingList, error) {
+	thread := c.newThread(filename)
+	c.modules[filename] = nil
+	globals, err := starlark.ExecFile(thread, filename, src, c.predeclared)
+	c.modules[filename] = &modentry{globals, err}
+	if err != nil {
+		return nil, err
+	}
+	check, ok := globals["check"]
+	if !ok {
+		return nil, fmt.Errorf("policy %q does not define a check(graph) function", filename)
+	}
+	if _, ok := check.(starlark.Callable); !ok {
+		return nil, fmt.Errorf("policy %q check must be a function, got %s", filename, check.Type())
+	}
+	v, err := starlark.Call(thread, check, starlark.Tuple{&graphValue{c}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v == starlark.None {
+		return nil, nil
+	}
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("policy %q check must return a list of findings, got %s", filename, v.Type())
+	}
+	var result FindingList
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		f, err := toFinding(filename, item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// newThread constructs a Starlark thread for executing `filename`.
+func (c *Checker) newThread(filename string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: "exec " + filename,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintln(c.stderr, msg)
+		},
+		Load: c.load,
+	}
+	thread.SetLocal(callingFileKey, filename)
+	return thread
+}
+
+const callingFileKey = "callingFile"
+
+// load implements the Starlark load statement for modules relative to the
+// loading file.
+func (c *Checker) load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	callingFile := thread.Local(callingFileKey).(string)
+	modulePath := filepath.Clean(filepath.Join(filepath.Dir(callingFile), module))
+	e, ok := c.modules[modulePath]
+	if e == nil {
+		if ok {
+			return nil, fmt.Errorf("cycle in load graph")
+		}
+		// Add a placeholder to indicate "load in progress".
+		c.modules[modulePath] = nil
+		globals, err := starlark.ExecFile(c.newThread(modulePath), modulePath, nil, c.predeclared)
+		e = &modentry{globals, err}
+		c.modules[modulePath] = e
+	}
+	return e.globals, e.err
+}
+
+// makeFinding implements the `finding(rule, message, target=None, severity="error")` builtin.
+func makeFinding(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rule, message string
+	var target starlark.Value = starlark.None
+	severity := Severities[0]
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"rule", &rule, "message", &message, "target?", &target, "severity?", &severity); err != nil {
+		return nil, err
+	}
+	if !isSeverity(severity) {
+		return nil, fmt.Errorf("%s: unknown severity %q, want one of %s", b.Name(), severity, strings.Join(Severities, ", "))
+	}
+	switch t := target.(type) {
+	case starlark.NoneType:
+	case starlark.String:
+	case *targetValue:
+		target = starlark.String(t.tn.Name())
+	default:
+		return nil, fmt.Errorf("%s: target must be a target_node, string, or None, got %s", b.Name(), target.Type())
+	}
+	return starlarkstruct.FromStringDict(findingConstructor, starlark.StringDict{
+		"rule":     starlark.String(rule),
+		"message":  starlark.String(message),
+		"target":   target,
+		"severity": starlark.String(severity),
+	}), nil
+}
+
+// toFinding converts a value returned by a policy `check` function into a Finding.
+func toFinding(policy string, v starlark.Value) (Finding, error) {
+	s, ok := v.(*starlarkstruct.Struct)
+	if !ok || s.Constructor() != findingConstructor {
+		return Finding{}, fmt.Errorf("policy %q check must return findings created by finding(), got %s", policy, v.Type())
+	}
+	f := Finding{Policy: policy}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"rule", &f.Rule},
+		{"message", &f.Message},
+		{"severity", &f.Severity},
+		{"target", &f.Target},
+	} {
+		attr, err := s.Attr(field.name)
+		if err != nil {
+			return Finding{}, err
+		}
+		if str, ok := starlark.AsString(attr); ok {
+			*field.value = str
+		}
+	}
+	return f, nil
+}
+
+// isSeverity returns true when `severity` is one of `Severities`.
+func isSeverity(severity string) bool {
+	for _, s := range Severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionNames returns the names of the conditions in `cs` as a Starlark list.
+func conditionNames(cs compliance.LicenseConditionSet) *starlark.List {
+	return makeStringList(cs.Names())
+}
+
+// makeStringList converts `items` into a Starlark list of strings.
+func makeStringList(items []string) *starlark.List {
+	elems := make([]starlark.Value, len(items))
+	for i, item := range items {
+		elems[i] = starlark.String(item)
+	}
+	return starlark.NewList(elems)
+}
+
+// toConditionSet converts a condition name or an iterable of condition names
+// into a LicenseConditionSet.
+func toConditionSet(fnname string, v starlark.Value) (compliance.LicenseConditionSet, error) {
+	cs := compliance.NewLicenseConditionSet()
+	add := func(v starlark.Value) error {
+		name, ok := starlark.AsString(v)
+		if !ok {
+			return fmt.Errorf("%s: condition must be a string, got %s", fnname, v.Type())
+		}
+		lc, ok := compliance.RecognizedConditionNames[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown license condition %q", fnname, name)
+		}
+		cs = cs.Plus(lc)
+		return nil
+	}
+	if _, ok := v.(starlark.String); ok {
+		return cs, add(v)
+	}
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return cs, fmt.Errorf("%s: conditions must be a string or a list of strings, got %s", fnname, v.Type())
+	}
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		if err := add(item); err != nil {
+			return cs, err
+		}
+	}
+	return cs, nil
+}
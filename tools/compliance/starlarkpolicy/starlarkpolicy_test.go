@@ -0,0 +1,275 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starlarkpolicy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"android/soong/tools/compliance"
+	"android/soong/tools/compliance/projectmetadata"
+	"android/soong/tools/compliance/testfs"
+)
+
+const (
+	// AOSP starts a test metadata file for Android Apache-2.0 licensing.
+	AOSP = `` +
+		`package_name: "Android"
+license_kinds: "SPDX-license-identifier-Apache-2.0"
+license_conditions: "notice"
+`
+
+	// GPL starts a test metadata file for GPL 2.0 licensing.
+	GPL = `` +
+		`package_name: "Free Software"
+license_kinds: "SPDX-license-identifier-GPL-2.0"
+license_conditions: "restricted"
+`
+
+	// ByException starts a test metadata file for a module with by_exception_only licensing.
+	ByException = `` +
+		`package_name: "Special"
+license_kinds: "legacy_by_exception_only"
+license_conditions: "by_exception_only"
+`
+)
+
+// testGraph returns a license graph with a container shipping a binary
+// statically linking a GPL library and a by_exception_only library, and
+// using a by_exception_only tool.
+func testGraph(t *testing.T) (*compliance.LicenseGraph, *projectmetadata.Index) {
+	fs := &testfs.TestFS{
+		"apex.meta_lic": []byte(AOSP + "is_container: true\n" +
+			"deps: {\n  file: \"bin.meta_lic\"\n  annotations: \"static\"\n}\n"),
+		"bin.meta_lic": []byte(AOSP + "projects: \"vendor/bin\"\ninstalled: \"out/target/product/fictional/vendor/bin/bin\"\n" +
+			"deps: {\n  file: \"gpl.meta_lic\"\n  annotations: \"static\"\n}\n" +
+			"deps: {\n  file: \"special.meta_lic\"\n  annotations: \"static\"\n}\n" +
+			"deps: {\n  file: \"tool.meta_lic\"\n  annotations: \"toolchain\"\n}\n"),
+		"gpl.meta_lic":              []byte(GPL + "projects: \"external/gpl\"\n"),
+		"special.meta_lic":          []byte(ByException + "projects: \"external/special\"\n"),
+		"tool.meta_lic":             []byte(ByException + "projects: \"external/tool\"\n"),
+		"external/gpl/METADATA":     []byte("name: \"gpl\"\nthird_party { version: \"2.1\" }\n"),
+		"external/special/METADATA": []byte("name: \"special\"\n"),
+	}
+	lg, err := compliance.ReadLicenseGraph(fs, &bytes.Buffer{}, []string{"apex.meta_lic"})
+	if err != nil {
+		t.Fatalf("unexpected error reading graph: %s", err)
+	}
+	return lg, projectmetadata.NewIndex(fs)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		expected      []string
+		expectedError string
+	}{
+		{
+			name: "no findings",
+			policy: `
+def check(graph):
+    return []
+`,
+		},
+		{
+			name: "none",
+			policy: `
+def check(graph):
+    pass
+`,
+		},
+		{
+			name: "shipped by exception",
+			policy: `
+def check(graph):
+    return [
+        finding("no-by-exception", "by_exception_only module ships", target=t)
+        for t in graph.shipped()
+        if "by_exception_only" in t.license_conditions
+    ]
+`,
+			expected: []string{
+				"error: test.star: no-by-exception: special.meta_lic: by_exception_only module ships",
+			},
+		},
+		{
+			name: "vendor by exception",
+			policy: `
+def vendor(t):
+    return [p for p in t.installed if "/vendor/" in p]
+
+def check(graph):
+    result = []
+    for t in graph.shipped():
+        for e in t.dependencies:
+            if e.is_derivation or e.is_runtime_dependency:
+                if vendor(t) and "by_exception_only" in e.dependency.license_conditions:
+                    result.append(finding("vendor", "%s uses %s" % (t.name, e.dependency.name), severity="warning"))
+    return result
+`,
+			expected: []string{
+				"warning: test.star: vendor: bin.meta_lic uses special.meta_lic",
+			},
+		},
+		{
+			name: "third party version",
+			policy: `
+def check(graph):
+    result = []
+    for t in graph.targets:
+        for p in t.projects:
+            if not p.startswith("external/"):
+                continue
+            pms = t.project_metadata()
+            if not pms or not pms[0].version:
+                result.append(finding("version", "missing version for " + p, target=t.name))
+    return result
+`,
+			expected: []string{
+				"error: test.star: version: special.meta_lic: missing version for external/special",
+				"error: test.star: version: tool.meta_lic: missing version for external/tool",
+			},
+		},
+		{
+			name: "resolutions",
+			policy: `
+def check(graph):
+    return [
+        finding("restricted", "%s shares %s" % (r.attaches_to.name, r.acts_on.name), severity="info")
+        for r in graph.resolutions(policy.restricted)
+        if r.attaches_to in graph.roots
+    ]
+`,
+			expected: []string{
+				"info: test.star: restricted: apex.meta_lic shares apex.meta_lic",
+				"info: test.star: restricted: apex.meta_lic shares bin.meta_lic",
+				"info: test.star: restricted: apex.meta_lic shares gpl.meta_lic",
+				"info: test.star: restricted: apex.meta_lic shares special.meta_lic",
+			},
+		},
+		{
+			name: "target lookup",
+			policy: `
+def check(graph):
+    t = graph.target("gpl.meta_lic")
+    if graph.target("missing.meta_lic") != None:
+        fail("unexpected target")
+    return [finding("kinds", ",".join(t.license_kinds), target=t)]
+`,
+			expected: []string{
+				"error: test.star: kinds: gpl.meta_lic: SPDX-license-identifier-GPL-2.0",
+			},
+		},
+		{
+			name: "policy sets",
+			policy: `
+def check(graph):
+    return [finding("sets", ",".join(policy.network_use + policy.patent_retaliation), severity="info")]
+`,
+			expected: []string{
+				"info: test.star: sets: network_use,patent_retaliation",
+			},
+		},
+		{
+			name: "missing check",
+			policy: `
+x = 1
+`,
+			expectedError: "does not define a check(graph) function",
+		},
+		{
+			name: "bad result",
+			policy: `
+def check(graph):
+    return ["oops"]
+`,
+			expectedError: "findings created by finding()",
+		},
+		{
+			name: "bad severity",
+			policy: `
+def check(graph):
+    return [finding("rule", "message", severity="fatal")]
+`,
+			expectedError: `unknown severity "fatal"`,
+		},
+		{
+			name: "bad condition",
+			policy: `
+def check(graph):
+    return graph.resolutions(["nope"])
+`,
+			expectedError: `unknown license condition "nope"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg, pmix := testGraph(t)
+			c := NewChecker(lg, pmix, &bytes.Buffer{})
+			findings, err := c.Check("test.star", tt.policy)
+			if err != nil {
+				if len(tt.expectedError) == 0 {
+					t.Fatalf("unexpected error: got %s, want no error", err)
+				} else if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("unexpected error: got %s, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if len(tt.expectedError) > 0 {
+				t.Fatalf("unexpected success: got no error, want %q err", tt.expectedError)
+			}
+			sort.Sort(findings)
+			actual := make([]string, 0, len(findings))
+			for _, f := range findings {
+				actual = append(actual, f.String())
+			}
+			if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("unexpected findings: got %q, want %q", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckLoads(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.star"), []byte(`
+def restricted_roots(graph):
+    return [r.attaches_to.name for r in graph.resolve_source_sharing() if r.attaches_to in graph.roots]
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	policy := filepath.Join(dir, "policy.star")
+	if err := os.WriteFile(policy, []byte(`
+load("lib.star", "restricted_roots")
+
+def check(graph):
+    return [finding("shares", name) for name in {n: True for n in restricted_roots(graph)}]
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	lg, pmix := testGraph(t)
+	findings, err := NewChecker(lg, pmix, &bytes.Buffer{}).Check(policy, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: got %s, want no error", err)
+	}
+	if len(findings) != 1 || findings[0].Message != "apex.meta_lic" || findings[0].Rule != "shares" {
+		t.Errorf("unexpected findings: got %v, want 1 shares finding for apex.meta_lic", findings)
+	}
+}
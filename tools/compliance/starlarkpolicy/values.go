@@ -0,0 +1,444 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package starlarkpolicy
+
+import (
+	"fmt"
+	"sort"
+
+	"android/soong/tools/compliance"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// graphValue exposes a LicenseGraph to Starlark as a `license_graph`.
+//
+// Attributes:
+//
+//	roots: list of root target_nodes
+//	targets: list of all target_nodes ordered by name
+//	edges: list of all target_edges ordered by target then dependency
+//
+// Methods:
+//
+//	target(name): the target_node named `name` or None
+//	shipped(): list of the target_nodes distributed by the roots
+//	resolutions(conditions): list of resolutions for `conditions`
+//	actions(conditions): list of actions for `conditions`
+//	resolve_notices(): list of resolutions for notice policy
+//	resolve_source_sharing(): list of resolutions for source-sharing policy
+//	resolve_source_privacy(): list of resolutions for source privacy policy
+//	resolve_network_source_sharing(): list of resolutions for network source-sharing policy
+//	resolve_patent_retaliation(): list of resolutions for patent retaliation policy
+//	conflicts(): list of source-sharing versus source privacy conflicts
+type graphValue struct {
+	c *Checker
+}
+
+var _ starlark.HasAttrs = (*graphValue)(nil)
+
+var graphMethods = map[string]func(*graphValue, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error){
+	"target":                         (*graphValue).target,
+	"shipped":                        (*graphValue).shipped,
+	"resolutions":                    (*graphValue).resolutions,
+	"actions":                        (*graphValue).actions,
+	"resolve_notices":                (*graphValue).resolveNotices,
+	"resolve_source_sharing":         (*graphValue).resolveSourceSharing,
+	"resolve_source_privacy":         (*graphValue).resolveSourcePrivacy,
+	"resolve_network_source_sharing": (*graphValue).resolveNetworkSourceSharing,
+	"resolve_patent_retaliation":     (*graphValue).resolvePatentRetaliation,
+	"conflicts":                      (*graphValue).conflicts,
+}
+
+func (g *graphValue) String() string        { return "license_graph()" }
+func (g *graphValue) Type() string          { return "license_graph" }
+func (g *graphValue) Freeze()               {}
+func (g *graphValue) Truth() starlark.Bool  { return starlark.True }
+func (g *graphValue) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable: %s", g.Type()) }
+
+// AttrNames returns the sorted list of attribute and method names.
+func (g *graphValue) AttrNames() []string {
+	names := []string{"edges", "roots", "targets"}
+	for name := range graphMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Attr returns the attribute or method named `name`.
+func (g *graphValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "roots":
+		return g.c.targetList(g.c.lg.Roots(), false), nil
+	case "targets":
+		return g.c.targetList(g.c.lg.Targets(), true), nil
+	case "edges":
+		edges := g.c.lg.Edges()
+		sort.Sort(edges)
+		return g.c.edgeList(edges), nil
+	}
+	if method, ok := graphMethods[name]; ok {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			return method(g, b, args, kwargs)
+		}), nil
+	}
+	return nil, nil
+}
+
+func (g *graphValue) target(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+	for _, tn := range g.c.lg.Targets() {
+		if tn.Name() == name {
+			return g.c.targetValue(tn), nil
+		}
+	}
+	return starlark.None, nil
+}
+
+func (g *graphValue) shipped(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	shipped := compliance.ShippedNodes(g.c.lg)
+	targets := make(compliance.TargetNodeList, 0, len(shipped))
+	for tn := range shipped {
+		targets = append(targets, tn)
+	}
+	return g.c.targetList(targets, true), nil
+}
+
+func (g *graphValue) resolutions(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var conditions starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &conditions); err != nil {
+		return nil, err
+	}
+	cs, err := toConditionSet(b.Name(), conditions)
+	if err != nil {
+		return nil, err
+	}
+	compliance.ResolveTopDownConditions(g.c.lg)
+	return g.c.resolutionList(compliance.WalkResolutionsForCondition(g.c.lg, cs)), nil
+}
+
+func (g *graphValue) actions(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var conditions starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &conditions); err != nil {
+		return nil, err
+	}
+	cs, err := toConditionSet(b.Name(), conditions)
+	if err != nil {
+		return nil, err
+	}
+	compliance.ResolveTopDownConditions(g.c.lg)
+	as := compliance.WalkActionsForCondition(g.c.lg, cs)
+	actsOn := make(compliance.TargetNodeList, 0, len(as))
+	for tn := range as {
+		actsOn = append(actsOn, tn)
+	}
+	sort.Sort(actsOn)
+	result := make([]starlark.Value, 0, len(actsOn))
+	for _, tn := range actsOn {
+		result = append(result, starlarkstruct.FromStringDict(starlark.String("action"), starlark.StringDict{
+			"acts_on":    g.c.targetValue(tn),
+			"conditions": conditionNames(as[tn]),
+		}))
+	}
+	return starlark.NewList(result), nil
+}
+
+func (g *graphValue) resolveNotices(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return g.c.resolutionList(compliance.ResolveNotices(g.c.lg)), nil
+}
+
+func (g *graphValue) resolveSourceSharing(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return g.c.resolutionList(compliance.ResolveSourceSharing(g.c.lg)), nil
+}
+
+func (g *graphValue) resolveSourcePrivacy(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return g.c.resolutionList(compliance.ResolveSourcePrivacy(g.c.lg)), nil
+}
+
+func (g *graphValue) resolveNetworkSourceSharing(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return g.c.resolutionList(compliance.ResolveNetworkSourceSharing(g.c.lg)), nil
+}
+
+func (g *graphValue) resolvePatentRetaliation(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return g.c.resolutionList(compliance.ResolvePatentRetaliation(g.c.lg)), nil
+}
+
+func (g *graphValue) conflicts(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	conflicts := compliance.ConflictingSharedPrivateSource(g.c.lg)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Error() < conflicts[j].Error() })
+	result := make([]starlark.Value, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		result = append(result, starlarkstruct.FromStringDict(starlark.String("conflict"), starlark.StringDict{
+			"target":            g.c.targetValue(conflict.SourceNode),
+			"share_condition":   starlark.String(conflict.ShareCondition.Name()),
+			"privacy_condition": starlark.String(conflict.PrivacyCondition.Name()),
+		}))
+	}
+	return starlark.NewList(result), nil
+}
+
+// targetValue exposes a TargetNode to Starlark as a `target_node`.
+//
+// Attributes:
+//
+//	name, package_name, module_name: strings
+//	projects, license_conditions, license_kinds, license_texts: lists of strings
+//	built, installed, sources: lists of strings
+//	is_container: bool
+//	install_map: list of structs with from_path and container_path
+//	dependencies: list of target_edges from the target
+//
+// Methods:
+//
+//	project_metadata(): list of structs describing the METADATA of the projects
+type targetValue struct {
+	c  *Checker
+	tn *compliance.TargetNode
+}
+
+var _ starlark.HasAttrs = (*targetValue)(nil)
+
+var targetAttrNames = []string{
+	"built",
+	"dependencies",
+	"install_map",
+	"installed",
+	"is_container",
+	"license_conditions",
+	"license_kinds",
+	"license_texts",
+	"module_name",
+	"name",
+	"package_name",
+	"project_metadata",
+	"projects",
+	"sources",
+}
+
+func (t *targetValue) String() string        { return fmt.Sprintf("target_node(%q)", t.tn.Name()) }
+func (t *targetValue) Type() string          { return "target_node" }
+func (t *targetValue) Freeze()               {}
+func (t *targetValue) Truth() starlark.Bool  { return starlark.True }
+func (t *targetValue) Hash() (uint32, error) { return starlark.String(t.tn.Name()).Hash() }
+
+// AttrNames returns the sorted list of attribute and method names.
+func (t *targetValue) AttrNames() []string {
+	return append([]string{}, targetAttrNames...)
+}
+
+// Attr returns the attribute or method named `name`.
+func (t *targetValue) Attr(name string) (starlark.Value, error) {
+	tn := t.tn
+	switch name {
+	case "name":
+		return starlark.String(tn.Name()), nil
+	case "package_name":
+		return starlark.String(tn.PackageName()), nil
+	case "module_name":
+		return starlark.String(tn.ModuleName()), nil
+	case "projects":
+		return sortedStringList(tn.Projects()), nil
+	case "license_conditions":
+		return conditionNames(tn.LicenseConditions()), nil
+	case "license_kinds":
+		return sortedStringList(tn.LicenseKinds()), nil
+	case "license_texts":
+		return sortedStringList(tn.LicenseTexts()), nil
+	case "is_container":
+		return starlark.Bool(tn.IsContainer()), nil
+	case "built":
+		return sortedStringList(tn.Built()), nil
+	case "installed":
+		return sortedStringList(tn.Installed()), nil
+	case "sources":
+		return sortedStringList(tn.Sources()), nil
+	case "install_map":
+		result := make([]starlark.Value, 0)
+		for _, im := range tn.InstallMap() {
+			result = append(result, starlarkstruct.FromStringDict(starlark.String("install_map"), starlark.StringDict{
+				"from_path":      starlark.String(im.FromPath),
+				"container_path": starlark.String(im.ContainerPath),
+			}))
+		}
+		return starlark.NewList(result), nil
+	case "dependencies":
+		edges := tn.Dependencies()
+		sort.Sort(edges)
+		return t.c.edgeList(edges), nil
+	case "project_metadata":
+		return starlark.NewBuiltin(name, t.projectMetadata), nil
+	}
+	return nil, nil
+}
+
+func (t *targetValue) projectMetadata(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	if t.c.pmix == nil {
+		return starlark.NewList(nil), nil
+	}
+	pms, err := t.c.pmix.MetadataForProjects(t.tn.Projects()...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]starlark.Value, 0, len(pms))
+	for _, pm := range pms {
+		urls := starlark.NewDict(0)
+		for urlType, url := range pm.UrlsByTypeName() {
+			urls.SetKey(starlark.String(urlType), starlark.String(url))
+		}
+		result = append(result, starlarkstruct.FromStringDict(starlark.String("project_metadata"), starlark.StringDict{
+			"project":        starlark.String(pm.Project()),
+			"name":           starlark.String(pm.Name()),
+			"version":        starlark.String(pm.Version()),
+			"versioned_name": starlark.String(pm.VersionedName()),
+			"urls":           urls,
+		}))
+	}
+	return starlark.NewList(result), nil
+}
+
+// edgeValue exposes a TargetEdge to Starlark as a `target_edge`.
+//
+// Attributes:
+//
+//	target, dependency: target_nodes
+//	annotations: sorted list of strings
+//	is_derivation, is_runtime_dependency, is_build_tool: bools
+type edgeValue struct {
+	c *Checker
+	e *compliance.TargetEdge
+}
+
+var _ starlark.HasAttrs = (*edgeValue)(nil)
+
+func (e *edgeValue) String() string        { return fmt.Sprintf("target_edge(%q)", e.e.String()) }
+func (e *edgeValue) Type() string          { return "target_edge" }
+func (e *edgeValue) Freeze()               {}
+func (e *edgeValue) Truth() starlark.Bool  { return starlark.True }
+func (e *edgeValue) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable: %s", e.Type()) }
+
+// AttrNames returns the sorted list of attribute names.
+func (e *edgeValue) AttrNames() []string {
+	return []string{"annotations", "dependency", "is_build_tool", "is_data_dependency", "is_derivation", "is_header_only", "is_runtime_dependency", "is_test_dependency", "target"}
+}
+
+// Attr returns the attribute named `name`.
+func (e *edgeValue) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "target":
+		return e.c.targetValue(e.e.Target()), nil
+	case "dependency":
+		return e.c.targetValue(e.e.Dependency()), nil
+	case "annotations":
+		return sortedStringList(e.e.Annotations().AsList()), nil
+	case "is_derivation":
+		return starlark.Bool(e.e.IsDerivation()), nil
+	case "is_runtime_dependency":
+		return starlark.Bool(e.e.IsRuntimeDependency()), nil
+	case "is_build_tool":
+		return starlark.Bool(e.e.IsBuildTool()), nil
+	case "is_test_dependency":
+		return starlark.Bool(e.e.IsTestDependency()), nil
+	case "is_data_dependency":
+		return starlark.Bool(e.e.IsDataDependency()), nil
+	case "is_header_only":
+		return starlark.Bool(e.e.IsHeaderOnly()), nil
+	}
+	return nil, nil
+}
+
+// targetValue returns the unique Starlark value for `tn`.
+func (c *Checker) targetValue(tn *compliance.TargetNode) *targetValue {
+	if tv, ok := c.targets[tn]; ok {
+		return tv
+	}
+	tv := &targetValue{c, tn}
+	c.targets[tn] = tv
+	return tv
+}
+
+// targetList converts `targets` into a Starlark list optionally sorting by name.
+func (c *Checker) targetList(targets compliance.TargetNodeList, sortByName bool) *starlark.List {
+	if sortByName {
+		sort.Sort(targets)
+	}
+	result := make([]starlark.Value, 0, len(targets))
+	for _, tn := range targets {
+		result = append(result, c.targetValue(tn))
+	}
+	return starlark.NewList(result)
+}
+
+// edgeList converts `edges` into a Starlark list.
+func (c *Checker) edgeList(edges compliance.TargetEdgeList) *starlark.List {
+	result := make([]starlark.Value, 0, len(edges))
+	for _, e := range edges {
+		result = append(result, &edgeValue{c, e})
+	}
+	return starlark.NewList(result)
+}
+
+// resolutionList converts `rs` into a Starlark list of resolution structs
+// ordered by attachesTo then actsOn.
+func (c *Checker) resolutionList(rs compliance.ResolutionSet) *starlark.List {
+	var resolutions compliance.ResolutionList
+	for _, attachesTo := range rs.AttachesTo() {
+		resolutions = append(resolutions, rs.Resolutions(attachesTo)...)
+	}
+	sort.Sort(resolutions)
+	result := make([]starlark.Value, 0, len(resolutions))
+	for _, r := range resolutions {
+		result = append(result, starlarkstruct.FromStringDict(starlark.String("resolution"), starlark.StringDict{
+			"attaches_to": c.targetValue(r.AttachesTo()),
+			"acts_on":     c.targetValue(r.ActsOn()),
+			"resolves":    conditionNames(r.Resolves()),
+		}))
+	}
+	return starlark.NewList(result)
+}
+
+// sortedStringList converts `items` into a sorted Starlark list of strings.
+func sortedStringList(items []string) *starlark.List {
+	sort.Strings(items)
+	return makeStringList(items)
+}
@@ -37,6 +37,14 @@ license_conditions: "notice"
 		`package_name: "Free Software"
 license_kinds: "SPDX-license-identifier-GPL-2.0"
 license_conditions: "restricted"
+`
+
+	// AGPL starts a test metadata file for AGPL 3.0 licensing.
+	AGPL = `` +
+		`package_name: "Free Network Software"
+license_kinds: "SPDX-license-identifier-AGPL-3.0"
+license_conditions: "restricted"
+license_conditions: "network_use"
 `
 
 	// Classpath starts a test metadata file for GPL 2.0 with classpath exception licensing.
@@ -72,6 +80,14 @@ license_conditions: "reciprocal"
 		`package_name: "Android"
 license_kinds: "SPDX-license-identifier-MIT"
 license_conditions: "notice"
+`
+
+	// Patent starts a test metadata file for a module with notice licensing and a patent retaliation clause.
+	Patent = `` +
+		`package_name: "Patented"
+license_kinds: "SPDX-license-identifier-Apache-2.0"
+license_conditions: "notice"
+license_conditions: "patent_retaliation"
 `
 
 	// Proprietary starts a test metadata file for a module with proprietary licensing.
@@ -95,6 +111,9 @@ var (
 		"apacheBin.meta_lic":                 AOSP,
 		"apacheLib.meta_lic":                 AOSP,
 		"apacheContainer.meta_lic":           AOSP + "is_container: true\n",
+		"agplBin.meta_lic":                   AGPL,
+		"agplLib.meta_lic":                   AGPL,
+		"agplContainer.meta_lic":             AGPL + "is_container: true\n",
 		"dependentModule.meta_lic":           DependentModule,
 		"gplWithClasspathException.meta_lic": Classpath,
 		"gplBin.meta_lic":                    GPL,
@@ -106,6 +125,8 @@ var (
 		"mitLib.meta_lic":                    MIT,
 		"mplBin.meta_lic":                    MPL,
 		"mplLib.meta_lic":                    MPL,
+		"patentBin.meta_lic":                 Patent,
+		"patentLib.meta_lic":                 Patent,
 		"proprietary.meta_lic":               Proprietary,
 		"by_exception.meta_lic":              ByException,
 	}
@@ -0,0 +1,271 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records timing spans and counters for the compliance tools,
+// and writes them as Chrome trace-event JSON for chrome://tracing or Perfetto.
+//
+// Tracing is disabled until `Enable` gets called. While disabled, `Begin`
+// returns a nil `*Span` and the span methods do nothing, so instrumented code
+// costs little more than a function call.
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Categories of spans.
+const (
+	// CategoryRead identifies spans reading input files.
+	CategoryRead = "read"
+
+	// CategoryResolve identifies spans walking the license graph.
+	CategoryResolve = "resolve"
+
+	// CategoryIndex identifies spans indexing license texts or metadata.
+	CategoryIndex = "index"
+
+	// CategoryOutput identifies spans writing output.
+	CategoryOutput = "output"
+)
+
+var (
+	// mu guards `defaultTracer`.
+	mu sync.RWMutex
+
+	// defaultTracer receives the spans and counters when not nil.
+	defaultTracer *Tracer
+)
+
+// Enable starts recording spans and counters into a new `Tracer` for the
+// process named `process`, and returns the tracer.
+func Enable(process string) *Tracer {
+	t := New(process)
+	mu.Lock()
+	defaultTracer = t
+	mu.Unlock()
+	return t
+}
+
+// Disable stops recording spans and counters.
+func Disable() {
+	mu.Lock()
+	defaultTracer = nil
+	mu.Unlock()
+}
+
+// Default returns the enabled `Tracer` or nil when disabled.
+func Default() *Tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultTracer
+}
+
+// WriteFile writes the events recorded by the enabled `Tracer` to the file at
+// `path`. Does nothing when disabled.
+func WriteFile(path string) error {
+	t := Default()
+	if t == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := t.WriteJSON(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0666)
+}
+
+// Begin starts a span named `name` in category `cat` on the default tracer.
+// Returns nil when disabled.
+func Begin(cat, name string) *Span {
+	return Default().Begin(cat, name)
+}
+
+// Count adds `delta` to counter `name` on the default tracer.
+func Count(name string, delta int64) {
+	Default().Count(name, delta)
+}
+
+// event is a single Chrome trace event.
+type event struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  *float64       `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// Tracer accumulates the events of one process. (thread safe)
+type Tracer struct {
+	// process names the process in the trace viewer.
+	process string
+
+	// start is the time the trace began; timestamps are relative to start.
+	start time.Time
+
+	// now returns the current time.
+	now func() time.Time
+
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// events lists the completed spans and counter samples.
+	events []event
+
+	// lanes records which thread ids have a span in progress.
+	//
+	// Concurrent spans must not overlap within a thread id, so each span
+	// occupies the lowest free lane until it ends.
+	lanes []bool
+
+	// counters holds the running total of each counter.
+	counters map[string]int64
+}
+
+// New constructs a `Tracer` for the process named `process`.
+func New(process string) *Tracer {
+	return &Tracer{process: process, start: time.Now(), now: time.Now, counters: make(map[string]int64)}
+}
+
+// Span is a timed region of work. A nil *Span ignores all calls.
+type Span struct {
+	t     *Tracer
+	cat   string
+	name  string
+	lane  int
+	start time.Time
+	args  map[string]any
+}
+
+// Begin starts a span named `name` in category `cat`. Returns nil when `t`
+// is nil.
+func (t *Tracer) Begin(cat, name string) *Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	lane := 0
+	for lane < len(t.lanes) && t.lanes[lane] {
+		lane++
+	}
+	if lane == len(t.lanes) {
+		t.lanes = append(t.lanes, true)
+	} else {
+		t.lanes[lane] = true
+	}
+	t.mu.Unlock()
+	return &Span{t: t, cat: cat, name: name, lane: lane, start: t.now()}
+}
+
+// Arg records `value` as argument `key` of the span, and returns the span.
+func (s *Span) Arg(key string, value any) *Span {
+	if s == nil {
+		return nil
+	}
+	if s.args == nil {
+		s.args = make(map[string]any)
+	}
+	s.args[key] = value
+	return s
+}
+
+// End completes the span. Calling End more than once records only the first.
+func (s *Span) End() {
+	if s == nil || s.t == nil {
+		return
+	}
+	t := s.t
+	s.t = nil
+	end := t.now()
+	dur := micros(end.Sub(s.start))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lanes[s.lane] = false
+	t.events = append(t.events, event{
+		Name: s.name,
+		Cat:  s.cat,
+		Ph:   "X",
+		Ts:   micros(s.start.Sub(t.start)),
+		Dur:  &dur,
+		Pid:  1,
+		Tid:  s.lane + 1,
+		Args: s.args,
+	})
+}
+
+// Count adds `delta` to counter `name`, and records a sample of the new total.
+func (t *Tracer) Count(name string, delta int64) {
+	if t == nil {
+		return
+	}
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counters[name] += delta
+	t.events = append(t.events, event{
+		Name: name,
+		Ph:   "C",
+		Ts:   micros(now.Sub(t.start)),
+		Pid:  1,
+		Args: map[string]any{name: t.counters[name]},
+	})
+}
+
+// Counter returns the current total of counter `name`.
+func (t *Tracer) Counter(name string) int64 {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counters[name]
+}
+
+// WriteJSON writes the events recorded so far as a Chrome trace-event JSON
+// object to `w`.
+func (t *Tracer) WriteJSON(w io.Writer) error {
+	t.mu.Lock()
+	events := make([]event, 0, len(t.events)+1)
+	events = append(events, event{
+		Name: "process_name",
+		Ph:   "M",
+		Pid:  1,
+		Args: map[string]any{"name": t.process},
+	})
+	events = append(events, t.events...)
+	t.mu.Unlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Ts < events[j].Ts
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(struct {
+		TraceEvents     []event `json:"traceEvents"`
+		DisplayTimeUnit string  `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
+
+// micros converts `d` to fractional microseconds.
+func micros(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1000
+}
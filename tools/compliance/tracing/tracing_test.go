@@ -0,0 +1,162 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a time advancing by one millisecond per call.
+func fakeClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	Disable()
+	span := Begin(CategoryRead, "ignored")
+	if span != nil {
+		t.Errorf("Begin(): got %v, want nil span when disabled", span)
+	}
+	span.Arg("key", "value").End()
+	Count("ignored", 1)
+	if Default() != nil {
+		t.Errorf("Default(): got tracer, want nil when disabled")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	tr := New("test")
+	tr.now = fakeClock(tr.start)
+
+	outer := tr.Begin(CategoryRead, "outer")
+	inner := tr.Begin(CategoryIndex, "inner").Arg("file", "a.txt")
+	tr.Count("bytes", 10)
+	tr.Count("bytes", 5)
+	inner.End()
+	inner.End()
+	outer.End()
+	next := tr.Begin(CategoryOutput, "next")
+	next.End()
+
+	if actual := tr.Counter("bytes"); actual != 15 {
+		t.Errorf("Counter(\"bytes\"): got %d, want 15", actual)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON(): got error %v, want no error", err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string         `json:"name"`
+			Cat  string         `json:"cat"`
+			Ph   string         `json:"ph"`
+			Ts   float64        `json:"ts"`
+			Dur  float64        `json:"dur"`
+			Tid  int            `json:"tid"`
+			Args map[string]any `json:"args"`
+		} `json:"traceEvents"`
+		DisplayTimeUnit string `json:"displayTimeUnit"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("json.Unmarshal(): got error %v, want no error:\n%s", err, buf.String())
+	}
+	if trace.DisplayTimeUnit != "ms" {
+		t.Errorf("displayTimeUnit: got %q, want \"ms\"", trace.DisplayTimeUnit)
+	}
+
+	type ev struct {
+		name, ph string
+		ts, dur  float64
+		tid      int
+	}
+	expected := []ev{
+		{"process_name", "M", 0, 0, 0},
+		{"outer", "X", 1000, 5000, 1},
+		{"inner", "X", 2000, 3000, 2},
+		{"bytes", "C", 3000, 0, 0},
+		{"bytes", "C", 4000, 0, 0},
+		{"next", "X", 7000, 1000, 1},
+	}
+	if len(trace.TraceEvents) != len(expected) {
+		t.Fatalf("got %d events, want %d:\n%s", len(trace.TraceEvents), len(expected), buf.String())
+	}
+	for i, e := range trace.TraceEvents {
+		actual := ev{e.Name, e.Ph, e.Ts, e.Dur, e.Tid}
+		if actual != expected[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, actual, expected[i])
+		}
+	}
+	if file := trace.TraceEvents[2].Args["file"]; file != "a.txt" {
+		t.Errorf("inner args: got file %v, want \"a.txt\"", file)
+	}
+	if total := trace.TraceEvents[4].Args["bytes"]; total != float64(15) {
+		t.Errorf("counter args: got bytes %v, want 15", total)
+	}
+	if name := trace.TraceEvents[0].Args["name"]; name != "test" {
+		t.Errorf("process_name args: got %v, want \"test\"", name)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+
+	Disable()
+	path := filepath.Join(dir, "disabled.json")
+	if err := WriteFile(path); err != nil {
+		t.Errorf("WriteFile(): got error %v, want no error when disabled", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("WriteFile(): got file %q, want no file when disabled", path)
+	}
+
+	Enable("test")
+	defer Disable()
+	Begin(CategoryRead, "read").End()
+	path = filepath.Join(dir, "trace.json")
+	if err := WriteFile(path); err != nil {
+		t.Fatalf("WriteFile(): got error %v, want no error", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) || !bytes.Contains(data, []byte(`"read"`)) {
+		t.Errorf("WriteFile(): got %s, want trace with the read span", data)
+	}
+
+	if err := WriteFile(filepath.Join(dir, "missing", "trace.json")); err == nil {
+		t.Errorf("WriteFile(): got no error, want error for missing directory")
+	}
+
+	Begin(CategoryRead, "unencodable").Arg("value", math.NaN()).End()
+	path = filepath.Join(dir, "bad.json")
+	if err := WriteFile(path); err == nil {
+		t.Errorf("WriteFile(): got no error, want encoding error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("WriteFile(): got file %q, want no file after encoding error", path)
+	}
+}
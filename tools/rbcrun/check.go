@@ -0,0 +1,235 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Violation is a problem found by Check.
+type Violation struct {
+	Pos syntax.Position
+	Msg string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Pos, v.Msg)
+}
+
+// checker walks the modules reachable from an entrypoint file.
+type checker struct {
+	ev   *evaluation
+	root string
+
+	// done maps module path to whether checking it finished, so that a
+	// module still being checked when loaded again is a cycle.
+	done map[string]bool
+
+	violations []Violation
+}
+
+// Check parses and resolves the entrypoint file `filename` and every module
+// reachable through its load statements without executing them, and returns
+// all the violations of the restrictions enforced while running them: bad
+// load paths, symlinks, .scl files loading other files, undefined names,
+// rbc builtins used in .scl files and load cycles. Optional loads of missing
+// modules are not violations. (thread safe)
+func (in *Interpreter) Check(filename string) ([]Violation, error) {
+	root := in.RootDir
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = wd
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(root, filename)
+	}
+	filename, err = filepath.Rel(root, filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &checker{ev: newEvaluation(in), root: root, done: make(map[string]bool)}
+	pos := syntax.MakePosition(&filename, 0, 0)
+	switch {
+	case !in.AllowExternalEntrypoint && strings.HasPrefix(filename, "../"):
+		c.report(pos, "path could not be made relative to workspace root: %s", filename)
+	case in.Mode == ExecutionModeScl && !strings.HasSuffix(filename, ".scl"):
+		c.report(pos, "filename must end in .scl: %s", filename)
+	default:
+		if c.checkSymlink(pos, filename) {
+			c.checkModule(filename, in.Mode, in.AllowExternalEntrypoint)
+		}
+	}
+	return c.violations, nil
+}
+
+func (c *checker) report(pos syntax.Position, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{pos, fmt.Sprintf(format, args...)})
+}
+
+// checkSymlink reports the module at `path`, loaded at `pos`, if it is
+// a symlink or missing, and returns whether it can be checked.
+func (c *checker) checkSymlink(pos syntax.Position, path string) bool {
+	sym, err := isSymlink(filepath.Join(c.root, path))
+	if err != nil {
+		c.report(pos, "%s", err)
+		return false
+	}
+	if sym {
+		c.report(pos, "symlinks to starlark files are not allowed. Instead, load the target file and re-export its symbols: %s", path)
+		return false
+	}
+	return true
+}
+
+// checkModule checks the module at `path` executed in `mode`, then the
+// modules it loads.
+func (c *checker) checkModule(path string, mode ExecutionMode, allowExternal bool) {
+	c.done[path] = false
+	defer func() { c.done[path] = true }()
+
+	src, err := os.ReadFile(filepath.Join(c.root, path))
+	if err != nil {
+		c.report(syntax.MakePosition(&path, 0, 0), "%s", err)
+		return
+	}
+	f, err := syntax.LegacyFileOptions().Parse(path, src, 0)
+	if err != nil {
+		if serr, ok := err.(syntax.Error); ok {
+			c.report(serr.Pos, "%s", serr.Msg)
+		} else {
+			c.report(syntax.MakePosition(&path, 0, 0), "%s", err)
+		}
+		return
+	}
+
+	start := len(c.violations)
+	c.resolve(f, mode)
+	type load struct {
+		path string
+		mode ExecutionMode
+	}
+	var loads []load
+	for _, stmt := range f.Stmts {
+		stmt, ok := stmt.(*syntax.LoadStmt)
+		if !ok {
+			continue
+		}
+		pos := stmt.Module.TokenPos
+		module := stmt.Module.Value.(string)
+		optional := false
+		if mode == ExecutionModeRbc {
+			if pipePos := strings.LastIndex(module, "|"); pipePos >= 0 {
+				optional = true
+				module = module[:pipePos]
+			}
+		}
+		modulePath, err := cleanModuleName(module, filepath.Dir(path), allowExternal)
+		if err != nil {
+			c.report(pos, "%s", err)
+			continue
+		}
+		if optional {
+			if _, err := os.Stat(filepath.Join(c.root, modulePath)); err != nil {
+				continue
+			}
+		}
+		if strings.HasSuffix(path, ".scl") && !strings.HasSuffix(modulePath, ".scl") {
+			c.report(pos, ".scl files can only load other .scl files: %q loads %q", path, modulePath)
+			continue
+		}
+		if done, ok := c.done[modulePath]; ok {
+			if !done {
+				c.report(pos, "cycle in load graph: %q loads %q", path, modulePath)
+			}
+			continue
+		}
+		if !c.checkSymlink(pos, modulePath) {
+			c.done[modulePath] = true
+			continue
+		}
+		loadMode := mode
+		if strings.HasSuffix(modulePath, ".scl") {
+			loadMode = ExecutionModeScl
+		}
+		loads = append(loads, load{modulePath, loadMode})
+	}
+	own := c.violations[start:]
+	sort.SliceStable(own, func(i, j int) bool {
+		a, b := own[i].Pos, own[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+
+	// Only the entrypoint file allows external loads.
+	for _, l := range loads {
+		if _, ok := c.done[l.path]; !ok {
+			c.checkModule(l.path, l.mode, false)
+		}
+	}
+}
+
+// resolve reports the undefined names in `f` executed in `mode`, and the
+// rbc builtins it uses in scl mode.
+func (c *checker) resolve(f *syntax.File, mode ExecutionMode) {
+	builtins, err := c.ev.builtins(mode)
+	if err != nil {
+		c.report(syntax.MakePosition(&f.Path, 0, 0), "%s", err)
+		return
+	}
+	rbcBuiltins, _ := c.ev.builtins(ExecutionModeRbc)
+	rbcOnly := func(name string) bool {
+		_, ok := rbcBuiltins[name]
+		return mode == ExecutionModeScl && ok && !builtins.Has(name)
+	}
+	// rbc builtins resolve in scl mode so that they can be reported as such
+	// rather than as undefined.
+	isPredeclared := func(name string) bool { return builtins.Has(name) || rbcOnly(name) }
+	if err := resolve.File(f, isPredeclared, starlark.Universe.Has); err != nil {
+		if errs, ok := err.(resolve.ErrorList); ok {
+			for _, e := range errs {
+				c.report(e.Pos, "%s", e.Msg)
+			}
+		} else {
+			c.report(syntax.MakePosition(&f.Path, 0, 0), "%s", err)
+		}
+	}
+	if mode != ExecutionModeScl {
+		return
+	}
+	syntax.Walk(f, func(n syntax.Node) bool {
+		id, ok := n.(*syntax.Ident)
+		if !ok {
+			return true
+		}
+		if b, ok := id.Binding.(*resolve.Binding); ok && b.Scope == resolve.Predeclared && rbcOnly(id.Name) {
+			c.report(id.NamePos, "%s is only available in rbc files", id.Name)
+		}
+		return true
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"reflect"
+	"testing"
+)
+
+func checkViolations(t *testing.T, in *Interpreter, filename string) []string {
+	violations, err := in.Check(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	return got
+}
+
+func TestCheck(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeScl, "check")
+	got := checkViolations(t, in, "entry.scl")
+	expected := []string{
+		`entry.scl:2:6: .scl files can only load other .scl files: "entry.scl" loads "lib.bzl"`,
+		"entry.scl:3:6: load path must start with // or :",
+		"entry.scl:4:6: symlinks to starlark files are not allowed. Instead, load the target file and re-export its symbols: link.scl",
+		"entry.scl:5:5: rblf_shell is only available in rbc files",
+		"entry.scl:6:5: undefined: undefined_name",
+		`cycle.scl:1:6: cycle in load graph: "cycle.scl" loads "ok.scl"`,
+		"cycle.scl:2:5: rblf_log is only available in rbc files",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected violations:\n%q\nwant:\n%q", got, expected)
+	}
+}
+
+func TestCheckRbc(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "check")
+	got := checkViolations(t, in, "entry.star")
+	if len(got) != 2 || got[1] != "lib.scl:1:5: rblf_wildcard is only available in rbc files" {
+		t.Errorf("Unexpected violations %q", got)
+	}
+
+	// A clean tree has no violations.
+	if got := checkViolations(t, in, "clean.star"); got != nil {
+		t.Errorf("Unexpected violations %q", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GlobDependency records an rblf_wildcard expansion.
+type GlobDependency struct {
+	// Pattern is the expanded pattern including any top directory.
+	Pattern string `json:"pattern"`
+
+	// Dirs lists the directories whose content determines the expansion.
+	Dirs []string `json:"dirs"`
+}
+
+// TreeDependency records an rblf_find_files walk.
+type TreeDependency struct {
+	// Top is the root of the walk.
+	Top string `json:"top"`
+
+	// Pattern is the basename pattern of the files found.
+	Pattern string `json:"pattern"`
+
+	// Dirs lists every directory walked.
+	Dirs []string `json:"dirs"`
+}
+
+// Dependencies records everything an evaluation depends on, so the build can
+// decide when to evaluate again.
+type Dependencies struct {
+	// LoadedFiles lists the starlark files loaded.
+	LoadedFiles []string `json:"loaded_files"`
+
+	// Globs lists the rblf_wildcard expansions.
+	Globs []GlobDependency `json:"globs"`
+
+	// Trees lists the rblf_find_files walks.
+	Trees []TreeDependency `json:"trees"`
+
+	// ShellCommands lists the rblf_shell commands run. Their results cannot be
+	// expressed as file dependencies.
+	ShellCommands []string `json:"shell_commands"`
+
+	// EnvVars lists the rblf_env variables read. Like shell commands, they
+	// cannot be expressed as file dependencies.
+	EnvVars []string `json:"env_vars"`
+}
+
+// Paths returns the sorted, deduplicated files and directories for a
+// depfile.
+func (d *Dependencies) Paths() []string {
+	seen := make(map[string]struct{})
+	add := func(paths ...string) {
+		for _, p := range paths {
+			seen[p] = struct{}{}
+		}
+	}
+	add(d.LoadedFiles...)
+	for _, g := range d.Globs {
+		add(g.Dirs...)
+	}
+	for _, t := range d.Trees {
+		add(t.Dirs...)
+	}
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Rebase returns a copy of `d` with the relative paths, which are relative
+// to the root directory of the evaluation, made relative to `dir` instead.
+// e.g. the root directory as given relative to another working directory.
+func (d *Dependencies) Rebase(dir string) *Dependencies {
+	rebase := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	rebaseAll := func(paths []string) []string {
+		result := make([]string, 0, len(paths))
+		for _, p := range paths {
+			result = append(result, rebase(p))
+		}
+		return result
+	}
+	r := &Dependencies{
+		LoadedFiles:   rebaseAll(d.LoadedFiles),
+		Globs:         make([]GlobDependency, 0, len(d.Globs)),
+		Trees:         make([]TreeDependency, 0, len(d.Trees)),
+		ShellCommands: d.ShellCommands,
+		EnvVars:       d.EnvVars,
+	}
+	for _, g := range d.Globs {
+		r.Globs = append(r.Globs, GlobDependency{rebase(g.Pattern), rebaseAll(g.Dirs)})
+	}
+	for _, t := range d.Trees {
+		r.Trees = append(r.Trees, TreeDependency{rebase(t.Top), t.Pattern, rebaseAll(t.Dirs)})
+	}
+	return r
+}
+
+var depfileEscaper = strings.NewReplacer(" ", "\\ ", "#", "\\#", "$", "$$")
+
+// WriteDepfile writes a ninja/make depfile making `target` depend on the
+// paths of `d`.
+func (d *Dependencies) WriteDepfile(w io.Writer, target string) error {
+	if _, err := io.WriteString(w, depfileEscaper.Replace(target)+":"); err != nil {
+		return err
+	}
+	for _, p := range d.Paths() {
+		if _, err := io.WriteString(w, " \\\n  "+depfileEscaper.Replace(p)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJSON writes `d` as JSON e.g. for a depfile sidecar.
+func (d *Dependencies) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// globDirs returns the directories whose content determines the expansion of
+// `pattern` in the directory `rootPrefix`: the longest directory prefix
+// without glob metacharacters, and every directory matched by each of the
+// following directory components. Adding a matching file anywhere under them
+// changes one of them.
+func globDirs(rootPrefix, pattern string) []string {
+	dir := filepath.Dir(pattern)
+	var components []string
+	for dir != "." && dir != string(filepath.Separator) && hasMeta(dir) {
+		components = append([]string{filepath.Base(dir)}, components...)
+		dir = filepath.Dir(dir)
+	}
+	seen := map[string]struct{}{dir: {}}
+	level := []string{dir}
+	for _, component := range components {
+		var next []string
+		for _, parent := range level {
+			// The pattern was valid for the whole expansion, so are its parts.
+			matches, _ := filepath.Glob(rootPrefix + filepath.Join(parent, component))
+			for _, m := range matches {
+				if info, err := os.Stat(m); err == nil && info.IsDir() {
+					m = strings.TrimPrefix(m, rootPrefix)
+					seen[m] = struct{}{}
+					next = append(next, m)
+				}
+			}
+		}
+		level = next
+	}
+	dirs := make([]string, 0, len(seen))
+	for d := range seen {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// hasMeta returns true when `path` contains glob metacharacters.
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "deps")
+	vars, deps, err := in.RunWithDependencies("entry.star", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vars["w"].String(); got != `["a.mk"]` {
+		t.Errorf("Expected w = [\"a.mk\"], got %s", got)
+	}
+
+	expected := &Dependencies{
+		LoadedFiles: []string{"entry.star", "mod.star"},
+		Globs: []GlobDependency{
+			{"sub/*.mk", []string{"sub"}},
+			{"sub/*.mk", []string{"sub"}},
+		},
+		Trees: []TreeDependency{
+			{"tree", "*.mk", []string{"tree", "tree/x"}},
+			{"missing/dir", "*.mk", []string{"."}},
+		},
+		ShellCommands: []string{"echo hi"},
+		EnvVars:       []string{},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Unexpected dependencies:\ngot  %+v\nwant %+v", deps, expected)
+	}
+
+	var buf bytes.Buffer
+	if err := deps.WriteDepfile(&buf, "out dir/product.mk"); err != nil {
+		t.Fatal(err)
+	}
+	expectedDepfile := "out\\ dir/product.mk: \\\n  . \\\n  entry.star \\\n  mod.star \\\n  sub \\\n  tree \\\n  tree/x\n"
+	if got := buf.String(); got != expectedDepfile {
+		t.Errorf("Unexpected depfile:\ngot  %q\nwant %q", got, expectedDepfile)
+	}
+
+	buf.Reset()
+	if err := deps.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var sidecar Dependencies
+	if err := json.Unmarshal(buf.Bytes(), &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&sidecar, expected) {
+		t.Errorf("Unexpected JSON sidecar %s", buf.String())
+	}
+}
+
+func TestGlobDependencies(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "deps")
+	vars, deps, err := in.RunWithDependencies("board.star", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vars["b"].String(); got != `["device/google/redfin/BoardConfig.mk"]` {
+		t.Errorf("Unexpected b = %s", got)
+	}
+	// Every directory a new match could appear in is a dependency, including
+	// device/google/newdev without a match.
+	expected := []GlobDependency{{"device/*/*/BoardConfig.mk", []string{
+		"device", "device/google", "device/google/newdev", "device/google/redfin"}}}
+	if !reflect.DeepEqual(deps.Globs, expected) {
+		t.Errorf("Unexpected globs %+v, want %+v", deps.Globs, expected)
+	}
+}
+
+func TestRebaseDependencies(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "deps")
+	_, deps, err := in.RunWithDependencies("entry.star", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The root testdata/deps differs from the working directory of the test.
+	root := filepath.Join("testdata", "deps")
+	rebased := deps.Rebase(root)
+	for _, p := range rebased.Paths() {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("Rebased dependency %q does not exist: %s", p, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := rebased.WriteDepfile(&buf, "out"); err != nil {
+		t.Fatal(err)
+	}
+	expectedDepfile := "out: \\\n  testdata/deps \\\n  testdata/deps/entry.star \\\n  testdata/deps/mod.star \\\n  testdata/deps/sub \\\n  testdata/deps/tree \\\n  testdata/deps/tree/x\n"
+	if got := buf.String(); got != expectedDepfile {
+		t.Errorf("Unexpected depfile:\ngot  %q\nwant %q", got, expectedDepfile)
+	}
+	if rebased.Globs[0].Pattern != "testdata/deps/sub/*.mk" || rebased.Trees[0].Top != "testdata/deps/tree" {
+		t.Errorf("Unexpected rebased globs %+v and trees %+v", rebased.Globs, rebased.Trees)
+	}
+
+	// Absolute paths and an absolute root stay absolute.
+	abs := deps.Rebase(filepath.Join(dataDir(), "deps"))
+	for _, p := range abs.Paths() {
+		if !filepath.IsAbs(p) {
+			t.Errorf("Expected absolute dependency, got %q", p)
+		}
+	}
+	if !reflect.DeepEqual(deps.Rebase("."), deps) {
+		t.Errorf("Rebase(\".\") changed the dependencies")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// EnvironMap returns `environ`, as returned by os.Environ, as a map for
+// Interpreter.Env.
+func EnvironMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// cliStruct returns the frozen rblf_cli struct of `vars`.
+func cliStruct(vars map[string]string) *starlarkstruct.Struct {
+	fields := make(starlark.StringDict, len(vars))
+	for k, v := range vars {
+		fields[k] = starlark.String(v)
+	}
+	s := starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+	s.Freeze()
+	return s
+}
+
+// envStruct is the rblf_env struct. It records every variable read by its
+// evaluation, set or not. Its attribute names cannot be listed, so dir()
+// cannot reveal the environment without recording it.
+type envStruct struct {
+	env map[string]string
+	ev  *evaluation
+}
+
+var _ starlark.HasAttrs = (*envStruct)(nil)
+
+func (e *envStruct) String() string        { return "rblf_env" }
+func (e *envStruct) Type() string          { return "struct" }
+func (e *envStruct) Freeze()               {}
+func (e *envStruct) Truth() starlark.Bool  { return true }
+func (e *envStruct) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: rblf_env") }
+func (e *envStruct) AttrNames() []string   { return nil }
+
+// Attr returns the environment variable `name`, or nil if unset so that
+// getattr(rblf_env, name, default) works.
+func (e *envStruct) Attr(name string) (starlark.Value, error) {
+	e.ev.envVars[name] = struct{}{}
+	if v, ok := e.env[name]; ok {
+		return starlark.String(v), nil
+	}
+	return nil, nil
+}
+
+// fileExists(file) returns True if `file` exists.
+func fileExists(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var file string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &file); err != nil {
+		return starlark.None, err
+	}
+	_, err := os.Stat(rootedPath(thread, file))
+	if ev, ok := thread.Local(evaluationKey).(*evaluation); ok {
+		// The file appearing or disappearing changes its directory.
+		dir := existingDir(thread, filepath.Dir(filepath.Clean(file)))
+		ev.globs = append(ev.globs, GlobDependency{filepath.Clean(file), []string{dir}})
+	}
+	return starlark.Bool(err == nil), nil
+}
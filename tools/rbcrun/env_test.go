@@ -0,0 +1,89 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestCliVars(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "env")
+	in.CliVars = map[string]string{"FOO": "bar"}
+	src := "foo = rblf_cli.FOO\nmissing = getattr(rblf_cli, \"BAZ\", \"default\")\n"
+	vars, _, err := in.Run("inline.star", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["foo"] != starlark.String("bar") || vars["missing"] != starlark.String("default") {
+		t.Errorf("Unexpected foo=%v missing=%v", vars["foo"], vars["missing"])
+	}
+
+	// rblf_cli is frozen.
+	if _, _, err := in.Run("inline.star", "rblf_cli.FOO = \"x\"\n"); err == nil {
+		t.Error("Expected assigning to rblf_cli to fail")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	src := "user = rblf_env.USER\nshell = getattr(rblf_env, \"SHELL\", \"none\")\nnames = dir(rblf_env)\n"
+	in := testdataInterpreter(ExecutionModeRbc, "env")
+	if _, _, err := in.Run("inline.star", src); err == nil || !strings.Contains(err.Error(), "undefined: rblf_env") {
+		t.Errorf("Expected rblf_env to be undefined by default, got %v", err)
+	}
+
+	in.Env = EnvironMap([]string{"USER=me", "HOME=/home/me", "EMPTY="})
+	vars, deps, err := in.RunWithDependencies("inline.star", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["user"] != starlark.String("me") || vars["shell"] != starlark.String("none") {
+		t.Errorf("Unexpected user=%v shell=%v", vars["user"], vars["shell"])
+	}
+	if got := vars["names"].String(); got != "[]" {
+		t.Errorf("Expected dir(rblf_env) to be empty, got %s", got)
+	}
+	if expected := []string{"SHELL", "USER"}; !reflect.DeepEqual(deps.EnvVars, expected) {
+		t.Errorf("Unexpected env vars %v, want %v", deps.EnvVars, expected)
+	}
+
+	// scl modules stay hermetic.
+	in.Mode = ExecutionModeScl
+	if _, _, err := in.Run("inline.scl", "user = rblf_env.USER\n"); err == nil {
+		t.Error("Expected rblf_env to be undefined in scl mode")
+	}
+}
+
+func TestFileExists(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "env")
+	src := "a = rblf_file_exists(\"sub/a.mk\")\nb = rblf_file_exists(\"missing/b.mk\")\n"
+	vars, deps, err := in.RunWithDependencies("inline.star", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["a"] != starlark.True || vars["b"] != starlark.False {
+		t.Errorf("Unexpected a=%v b=%v", vars["a"], vars["b"])
+	}
+	expected := []GlobDependency{
+		{"sub/a.mk", []string{"sub"}},
+		{"missing/b.mk", []string{"."}},
+	}
+	if !reflect.DeepEqual(deps.Globs, expected) {
+		t.Errorf("Unexpected globs %+v, want %+v", deps.Globs, expected)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Variable is a named value exported from an evaluation.
+type Variable struct {
+	Name  string
+	Value starlark.Value
+}
+
+// ExportedVariables returns the entries of the `variables_to_export_to_make`
+// dict of `globals` in insertion order.
+func ExportedVariables(globals starlark.StringDict) ([]Variable, error) {
+	value, ok := globals["variables_to_export_to_make"]
+	if !ok {
+		return nil, fmt.Errorf("expected top-level starlark file to have a \"variables_to_export_to_make\" variable")
+	}
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("expected variables_to_export_to_make to be a dict, got %s", value.Type())
+	}
+	var vars []Variable
+	for _, item := range dict.Items() {
+		name, ok := item.Index(0).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("all keys in variables_to_export_to_make must be strings, but got %q", item.Index(0).Type())
+		}
+		vars = append(vars, Variable{name.GoString(), item.Index(1)})
+	}
+	return vars, nil
+}
+
+// GlobalVariables returns the data globals of `globals` sorted by name.
+// Functions, builtins and private names starting with _ are omitted.
+func GlobalVariables(globals starlark.StringDict) []Variable {
+	var vars []Variable
+	for _, name := range globals.Keys() {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		if _, ok := globals[name].(starlark.Callable); ok {
+			continue
+		}
+		vars = append(vars, Variable{name, globals[name]})
+	}
+	return vars
+}
+
+// WriteJSON writes `vars` as an indented JSON object. Dicts keep their
+// insertion order, structs become objects with sorted fields, and tuples and
+// sets become arrays.
+func WriteJSON(w io.Writer, vars []Variable) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, v := range vars {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(&buf, v.Name)
+		buf.WriteByte(':')
+		if err := writeJSONValue(&buf, v.Value); err != nil {
+			return fmt.Errorf("%s: %w", v.Name, err)
+		}
+	}
+	buf.WriteByte('}')
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func writeJSONValue(buf *bytes.Buffer, value starlark.Value) error {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		buf.WriteString("null")
+	case starlark.Bool:
+		buf.WriteString(strconv.FormatBool(bool(v)))
+	case starlark.Int:
+		buf.WriteString(v.String())
+	case starlark.Float:
+		f, err := exportFloat(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(f)
+	case starlark.String:
+		writeJSONString(buf, v.GoString())
+	case *starlark.Dict:
+		buf.WriteByte('{')
+		for i, item := range v.Items() {
+			key, ok := item.Index(0).(starlark.String)
+			if !ok {
+				return fmt.Errorf("dict keys must be strings, but got %s", item.Index(0).Type())
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key.GoString())
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, item.Index(1)); err != nil {
+				return fmt.Errorf("[%q]: %w", key.GoString(), err)
+			}
+		}
+		buf.WriteByte('}')
+	case *starlarkstruct.Struct:
+		buf.WriteByte('{')
+		for i, name := range v.AttrNames() {
+			field, err := v.Attr(name)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, field); err != nil {
+				return fmt.Errorf(".%s: %w", name, err)
+			}
+		}
+		buf.WriteByte('}')
+	case *starlark.List, starlark.Tuple, *starlark.Set:
+		buf.WriteByte('[')
+		iter := v.(starlark.Iterable).Iterate()
+		defer iter.Done()
+		var elem starlark.Value
+		for i := 0; iter.Next(&elem); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, elem); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		return fmt.Errorf("cannot export %s", value.Type())
+	}
+	return nil
+}
+
+// exportFloat formats `f` as a number both JSON and textproto parse.
+func exportFloat(f starlark.Float) (string, error) {
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return "", fmt.Errorf("cannot export non-finite float %s", f)
+	}
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s, nil
+}
+
+// maxExactInt is the largest magnitude of an int a double represents exactly.
+const maxExactInt = 1 << 53
+
+// WriteTextproto writes `vars` as a google.protobuf.Struct in text format,
+// so any consumer can parse it without a dedicated schema. Ints and floats
+// both become number_value, and dicts and structs become struct_value.
+// Ints beyond ±2^53 are an error since number_value would round them.
+func WriteTextproto(w io.Writer, vars []Variable) error {
+	var buf bytes.Buffer
+	for _, v := range vars {
+		if err := writeTextprotoField(&buf, "", v.Name, v.Value); err != nil {
+			return fmt.Errorf("%s: %w", v.Name, err)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeTextprotoField(buf *bytes.Buffer, indent, name string, value starlark.Value) error {
+	fmt.Fprintf(buf, "%sfields {\n%s  key: %s\n%s  value {\n", indent, indent, strconv.Quote(name), indent)
+	if err := writeTextprotoValue(buf, indent+"    ", value); err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "%s  }\n%s}\n", indent, indent)
+	return nil
+}
+
+func writeTextprotoValue(buf *bytes.Buffer, indent string, value starlark.Value) error {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		fmt.Fprintf(buf, "%snull_value: NULL_VALUE\n", indent)
+	case starlark.Bool:
+		fmt.Fprintf(buf, "%sbool_value: %t\n", indent, bool(v))
+	case starlark.Int:
+		// number_value is a double, so refuse ints it cannot hold exactly
+		// rather than silently round them.
+		if n, ok := v.Int64(); !ok || n > maxExactInt || n < -maxExactInt {
+			return fmt.Errorf("cannot export int %s as a textproto number", v)
+		}
+		fmt.Fprintf(buf, "%snumber_value: %s\n", indent, v.String())
+	case starlark.Float:
+		f, err := exportFloat(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%snumber_value: %s\n", indent, f)
+	case starlark.String:
+		fmt.Fprintf(buf, "%sstring_value: %s\n", indent, strconv.Quote(v.GoString()))
+	case *starlark.Dict:
+		fmt.Fprintf(buf, "%sstruct_value {\n", indent)
+		for _, item := range v.Items() {
+			key, ok := item.Index(0).(starlark.String)
+			if !ok {
+				return fmt.Errorf("dict keys must be strings, but got %s", item.Index(0).Type())
+			}
+			if err := writeTextprotoField(buf, indent+"  ", key.GoString(), item.Index(1)); err != nil {
+				return fmt.Errorf("[%q]: %w", key.GoString(), err)
+			}
+		}
+		fmt.Fprintf(buf, "%s}\n", indent)
+	case *starlarkstruct.Struct:
+		fmt.Fprintf(buf, "%sstruct_value {\n", indent)
+		for _, name := range v.AttrNames() {
+			field, err := v.Attr(name)
+			if err != nil {
+				return err
+			}
+			if err := writeTextprotoField(buf, indent+"  ", name, field); err != nil {
+				return fmt.Errorf(".%s: %w", name, err)
+			}
+		}
+		fmt.Fprintf(buf, "%s}\n", indent)
+	case *starlark.List, starlark.Tuple, *starlark.Set:
+		fmt.Fprintf(buf, "%slist_value {\n", indent)
+		iter := v.(starlark.Iterable).Iterate()
+		defer iter.Done()
+		var elem starlark.Value
+		for i := 0; iter.Next(&elem); i++ {
+			fmt.Fprintf(buf, "%s  values {\n", indent)
+			if err := writeTextprotoValue(buf, indent+"    ", elem); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+			fmt.Fprintf(buf, "%s  }\n", indent)
+		}
+		fmt.Fprintf(buf, "%s}\n", indent)
+	default:
+		return fmt.Errorf("cannot export %s", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func exportGlobals(t *testing.T) starlark.StringDict {
+	in := testdataInterpreter(ExecutionModeScl, "export")
+	globals, _, err := in.Run("entry.scl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return globals
+}
+
+func TestWriteJSON(t *testing.T) {
+	vars, err := ExportedVariables(exportGlobals(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, vars); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "Z": "last \"quoted\"",
+  "A": [
+    1,
+    2.5,
+    null,
+    [
+      "x"
+    ]
+  ],
+  "NESTED": {
+    "b": false,
+    "a": {
+      "x": "s",
+      "y": 2
+    }
+  }
+}
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Unexpected JSON:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestWriteTextproto(t *testing.T) {
+	vars, err := ExportedVariables(exportGlobals(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteTextproto(&buf, vars[2:]); err != nil {
+		t.Fatal(err)
+	}
+	expected := `fields {
+  key: "NESTED"
+  value {
+    struct_value {
+      fields {
+        key: "b"
+        value {
+          bool_value: false
+        }
+      }
+      fields {
+        key: "a"
+        value {
+          struct_value {
+            fields {
+              key: "x"
+              value {
+                string_value: "s"
+              }
+            }
+            fields {
+              key: "y"
+              value {
+                number_value: 2
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Unexpected textproto:\n%s\nwant:\n%s", got, expected)
+	}
+
+	buf.Reset()
+	if err := WriteTextproto(&buf, vars[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"list_value {", "number_value: 1\n", "number_value: 2.5\n", "null_value: NULL_VALUE\n", "string_value: \"x\"\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %q in textproto:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestGlobalVariables(t *testing.T) {
+	var names []string
+	for _, v := range GlobalVariables(exportGlobals(t)) {
+		names = append(names, v.Name)
+	}
+	if got := strings.Join(names, " "); got != "enabled variables_to_export_to_make" {
+		t.Errorf("Unexpected globals %q", got)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         starlark.Value
+		expectedError string
+	}{
+		{
+			name:          "function",
+			value:         starlark.NewList([]starlark.Value{starlark.Universe["len"]}),
+			expectedError: "V: [0]: cannot export builtin_function_or_method",
+		},
+		{
+			name: "int key",
+			value: func() starlark.Value {
+				d := starlark.NewDict(1)
+				d.SetKey(starlark.MakeInt(1), starlark.True)
+				return d
+			}(),
+			expectedError: "V: dict keys must be strings, but got int",
+		},
+		{
+			name:          "infinity",
+			value:         starlark.Float(math.Inf(1)),
+			expectedError: "V: cannot export non-finite float +inf",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vars := []Variable{{"V", test.value}}
+			if err := WriteJSON(&bytes.Buffer{}, vars); err == nil || err.Error() != test.expectedError {
+				t.Errorf("Unexpected JSON error %v, want %q", err, test.expectedError)
+			}
+			if err := WriteTextproto(&bytes.Buffer{}, vars); err == nil || err.Error() != test.expectedError {
+				t.Errorf("Unexpected textproto error %v, want %q", err, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestTextprotoIntRange(t *testing.T) {
+	tests := []struct {
+		value         string
+		expectedError string
+	}{
+		{"9007199254740992", ""},
+		{"-9007199254740992", ""},
+		{"9007199254740993", "V: cannot export int 9007199254740993 as a textproto number"},
+		{"9223372036854775809", "V: cannot export int 9223372036854775809 as a textproto number"},
+		{"-9223372036854775809", "V: cannot export int -9223372036854775809 as a textproto number"},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			n, ok := new(big.Int).SetString(test.value, 10)
+			if !ok {
+				t.Fatalf("Bad int %q", test.value)
+			}
+			vars := []Variable{{"V", starlark.MakeBigInt(n)}}
+			var buf bytes.Buffer
+			err := WriteTextproto(&buf, vars)
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error %v", err)
+				} else if want := "number_value: " + test.value + "\n"; !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected %q in textproto:\n%s", want, buf.String())
+				}
+			} else if err == nil || err.Error() != test.expectedError {
+				t.Errorf("Unexpected error %v, want %q", err, test.expectedError)
+			}
+			// JSON keeps ints exact.
+			buf.Reset()
+			if err := WriteJSON(&buf, vars); err != nil || !strings.Contains(buf.String(), test.value) {
+				t.Errorf("Unexpected JSON %q, error %v", buf.String(), err)
+			}
+		})
+	}
+}
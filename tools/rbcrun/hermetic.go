@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ShellPolicy decides what rblf_shell does.
+type ShellPolicy int
+
+const (
+	// ShellPolicyRun runs every command. (default)
+	ShellPolicyRun ShellPolicy = iota
+
+	// ShellPolicyForbid fails every rblf_shell call.
+	ShellPolicyForbid
+
+	// ShellPolicyAllowlist runs only the commands matching the allowlist, and
+	// fails the others.
+	ShellPolicyAllowlist
+
+	// ShellPolicyReplay serves the recorded output of each command without
+	// running a shell, and fails commands missing from the recording.
+	ShellPolicyReplay
+)
+
+// ParseShellPolicy returns the ShellPolicy named `name`: "run", "forbid",
+// "allowlist" or "replay".
+func ParseShellPolicy(name string) (ShellPolicy, error) {
+	switch name {
+	case "run":
+		return ShellPolicyRun, nil
+	case "forbid":
+		return ShellPolicyForbid, nil
+	case "allowlist":
+		return ShellPolicyAllowlist, nil
+	case "replay":
+		return ShellPolicyReplay, nil
+	}
+	return ShellPolicyRun, fmt.Errorf("unknown shell policy %q, expected 1 of \"run\", \"forbid\", \"allowlist\", \"replay\"", name)
+}
+
+// ShellRecord describes a single rblf_shell call.
+type ShellRecord struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// ShellLog records rblf_shell calls. (thread safe)
+type ShellLog struct {
+	mu      sync.Mutex
+	records []ShellRecord
+}
+
+// Add appends `record` to the log.
+func (l *ShellLog) Add(record ShellRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+}
+
+// Records returns the calls recorded so far in call order.
+func (l *ShellLog) Records() []ShellRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ShellRecord{}, l.records...)
+}
+
+// WriteJSON writes the recorded calls to `w` in the format read by
+// ReadShellRecords.
+func (l *ShellLog) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Commands []ShellRecord `json:"commands"`
+	}{l.Records()})
+}
+
+// ReadShellRecords reads the calls written by ShellLog.WriteJSON from `r`
+// e.g. for ShellPolicyReplay.
+func ReadShellRecords(r io.Reader) ([]ShellRecord, error) {
+	var log struct {
+		Commands []ShellRecord `json:"commands"`
+	}
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, fmt.Errorf("cannot read shell log: %w", err)
+	}
+	return log.Commands, nil
+}
+
+// ReadShellAllowlist reads one regular expression per line from `r`. Each
+// expression must match a whole command. Blank lines and lines beginning
+// with '#' are ignored.
+func ReadShellAllowlist(r io.Reader) ([]*regexp.Regexp, error) {
+	var allowlist []*regexp.Regexp
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + line + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("shell allowlist line %d: %w", lineno, err)
+		}
+		allowlist = append(allowlist, re)
+	}
+	return allowlist, scanner.Err()
+}
+
+// runShell runs `command` according to the shell policy of `in` in `dir`, and
+// returns its raw output.
+func (in *Interpreter) runShell(shellPath, dir, command string) (string, error) {
+	switch in.ShellPolicy {
+	case ShellPolicyForbid:
+		return "", fmt.Errorf("rblf_shell is forbidden in hermetic mode: %q", command)
+	case ShellPolicyAllowlist:
+		allowed := false
+		for _, re := range in.ShellAllowlist {
+			if re.MatchString(command) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "", fmt.Errorf("rblf_shell command is not in the allowlist: %q", command)
+		}
+	case ShellPolicyReplay:
+		for _, record := range in.ShellReplay {
+			if record.Command == command {
+				if in.ShellLog != nil {
+					in.ShellLog.Add(record)
+				}
+				return record.Output, nil
+			}
+		}
+		return "", fmt.Errorf("rblf_shell command is not in the replay log: %q", command)
+	}
+
+	if shellPath == "" {
+		return "", fmt.Errorf("cannot run shell, /bin/sh is missing (running on Windows?)")
+	}
+	cmd := exec.Command(shellPath, "-c", command)
+	cmd.Dir = dir
+	// The command's status gets recorded, but does not fail the call.
+	bytes, err := cmd.Output()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+	if in.ShellLog != nil {
+		in.ShellLog.Add(ShellRecord{command, exitCode, string(bytes)})
+	}
+	return string(bytes), nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestShellLog(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "hermetic")
+	in.ShellLog = &ShellLog{}
+	vars, _, err := in.Run("entry.star", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["a"] != starlark.String("hi") || vars["b"] != starlark.String("fail") {
+		t.Errorf("Unexpected outputs a=%v b=%v", vars["a"], vars["b"])
+	}
+	expected := []ShellRecord{
+		{"echo hi", 0, "hi\n"},
+		{"echo fail; exit 3", 3, "fail\n"},
+	}
+	if got := in.ShellLog.Records(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected shell log %+v, want %+v", got, expected)
+	}
+
+	var buf bytes.Buffer
+	if err := in.ShellLog.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadShellRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Unexpected shell records %+v, want %+v", records, expected)
+	}
+}
+
+func TestShellPolicies(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        ShellPolicy
+		allowlist     string
+		replay        []ShellRecord
+		expectedA     string
+		expectedError string
+	}{
+		{
+			name:          "forbid",
+			policy:        ShellPolicyForbid,
+			expectedError: "rblf_shell is forbidden in hermetic mode: \"echo hi\"",
+		},
+		{
+			name:          "allowlist partial",
+			policy:        ShellPolicyAllowlist,
+			allowlist:     "# comment\necho [a-z]*\n",
+			expectedError: "rblf_shell command is not in the allowlist: \"echo fail; exit 3\"",
+		},
+		{
+			name:      "allowlist",
+			policy:    ShellPolicyAllowlist,
+			allowlist: "echo [a-z]*\necho fail; exit [0-9]\n",
+			expectedA: "hi",
+		},
+		{
+			name:   "replay",
+			policy: ShellPolicyReplay,
+			replay: []ShellRecord{
+				{"echo fail; exit 3", 3, "recorded\nfail\n"},
+				{"echo hi", 0, "recorded hi\n"},
+			},
+			expectedA: "recorded hi",
+		},
+		{
+			name:          "replay missing",
+			policy:        ShellPolicyReplay,
+			replay:        []ShellRecord{{"echo hi", 0, "hi\n"}},
+			expectedError: "rblf_shell command is not in the replay log: \"echo fail; exit 3\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := testdataInterpreter(ExecutionModeRbc, "hermetic")
+			in.ShellPolicy = tt.policy
+			in.ShellReplay = tt.replay
+			if tt.allowlist != "" {
+				allowlist, err := ReadShellAllowlist(strings.NewReader(tt.allowlist))
+				if err != nil {
+					t.Fatal(err)
+				}
+				in.ShellAllowlist = allowlist
+			}
+			if tt.policy == ShellPolicyReplay {
+				// Replay must not need a shell.
+				in.shell = ""
+			}
+			vars, _, err := in.Run("entry.star", nil)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vars["a"] != starlark.String(tt.expectedA) {
+				t.Errorf("Expected a = %q, got %v", tt.expectedA, vars["a"])
+			}
+		})
+	}
+}
+
+func TestReadShellAllowlist(t *testing.T) {
+	allowlist, err := ReadShellAllowlist(strings.NewReader("echo a|echo b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*regexp.Regexp{regexp.MustCompile("^(?:echo a|echo b)$")}
+	if !reflect.DeepEqual(allowlist, want) {
+		t.Errorf("Unexpected allowlist %v, want %v", allowlist, want)
+	}
+	if allowlist[0].MatchString("echo a; rm -rf /") {
+		t.Errorf("Allowlist must match whole commands")
+	}
+	if _, err := ReadShellAllowlist(strings.NewReader("\n(unbalanced\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error for line 2, got %v", err)
+	}
+	if _, err := ParseShellPolicy("sometimes"); err == nil {
+		t.Errorf("Expected error for unknown shell policy")
+	}
+}
@@ -18,12 +18,16 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	starlarkmath "go.starlark.net/lib/math"
 	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
 	"go.starlark.net/starlarkstruct"
 )
 
@@ -35,7 +39,9 @@ const (
 
 const allowExternalEntrypointKey = "allowExternalEntrypoint"
 const callingFileKey = "callingFile"
+const evaluationKey = "evaluation"
 const executionModeKey = "executionMode"
+const rootDirKey = "rootDir"
 const shellKey = "shell"
 
 type modentry struct {
@@ -43,12 +49,252 @@ type modentry struct {
 	err     error
 }
 
-var moduleCache = make(map[string]*modentry)
+// Interpreter evaluates Roboleaf configuration files. Each call to Run is an
+// independent evaluation with its own module cache, so a single Interpreter
+// can evaluate several products or release configs concurrently, and
+// repeated calls never see stale modules. (thread safe)
+//
+// Set the exported fields before the first call to Run.
+type Interpreter struct {
+	// Mode is the execution mode of the entrypoint file.
+	Mode ExecutionMode
+
+	// RootDir is the value of // for load paths. Empty means the current
+	// directory.
+	RootDir string
+
+	// AllowExternalEntrypoint allows the entrypoint file to be outside of
+	// RootDir.
+	AllowExternalEntrypoint bool
+
+	// RbcBuiltins are the predeclared symbols of rbc modules.
+	RbcBuiltins starlark.StringDict
+
+	// SclBuiltins are the predeclared symbols of scl modules.
+	SclBuiltins starlark.StringDict
+
+	// ShareSclModules reuses loaded .scl modules across evaluations. .scl
+	// modules are hermetic and frozen once loaded, so evaluations loading the
+	// same .scl file can share the result instead of re-executing it. Only
+	// successful loads get shared.
+	ShareSclModules bool
+
+	// ShellPolicy decides what rblf_shell does. Policies other than
+	// ShellPolicyRun make evaluation hermetic.
+	ShellPolicy ShellPolicy
+
+	// ShellAllowlist lists the expressions matching the commands allowed by
+	// ShellPolicyAllowlist.
+	ShellAllowlist []*regexp.Regexp
+
+	// ShellReplay lists the recorded calls served by ShellPolicyReplay.
+	ShellReplay []ShellRecord
+
+	// ShellLog, when not nil, records every command run or replayed with its
+	// exit code and output.
+	ShellLog *ShellLog
+
+	// CliVars are the variables set on the command line. rbc modules see them
+	// as the frozen rblf_cli struct.
+	CliVars map[string]string
+
+	// Env, when not nil, are the environment variables rbc modules see as
+	// rblf_env. The variables read are recorded in the dependencies. Nil
+	// leaves rblf_env undefined.
+	Env map[string]string
+
+	// LoadGraph, when not nil, records every load and the time spent
+	// executing each module.
+	LoadGraph *LoadGraph
+
+	// shell is the path to the shell for rblf_shell, or empty if missing.
+	shell string
+
+	// sclModules maps .scl module path to *sclModule when ShareSclModules.
+	sclModules sync.Map
+}
+
+// sclModule is a loaded .scl module shared across evaluations.
+type sclModule struct {
+	globals starlark.StringDict
+
+	// loads lists the paths to the modules loaded directly by the module.
+	loads []string
+}
+
+// NewInterpreter returns an Interpreter evaluating entrypoint files in `mode`
+// with the default builtins.
+func NewInterpreter(mode ExecutionMode) *Interpreter {
+	// NOTE(asmundak): OS-specific. Behave similar to Linux `system` call,
+	// which always uses /bin/sh to run the command
+	shellPath := "/bin/sh"
+	if _, err := os.Stat(shellPath); err != nil {
+		shellPath = ""
+	}
+	return &Interpreter{
+		Mode:        mode,
+		RbcBuiltins: rbcBuiltins,
+		SclBuiltins: sclBuiltins,
+		shell:       shellPath,
+	}
+}
+
+// evaluation is the state of a single call to Interpreter.Run. Loads happen
+// one at a time on the goroutine calling Run, so it needs no locking.
+type evaluation struct {
+	in *Interpreter
+
+	// modules maps module path to its entry, or to nil while loading.
+	modules map[string]*modentry
+
+	// loads maps module path to the paths of the modules it loads directly.
+	loads map[string][]string
+
+	// globs records the rblf_wildcard expansions.
+	globs []GlobDependency
+
+	// trees records the rblf_find_files walks.
+	trees []TreeDependency
+
+	// shellCommands records the rblf_shell commands.
+	shellCommands []string
+
+	// envVars records the rblf_env variables read.
+	envVars map[string]struct{}
+
+	// rbcBuiltins are the predeclared symbols of rbc modules including
+	// rblf_cli and rblf_env, once needed.
+	rbcBuiltins starlark.StringDict
+
+	// childTimes holds, for each module executing, the time spent executing
+	// the modules it loaded so far.
+	childTimes []time.Duration
+}
+
+func newEvaluation(in *Interpreter) *evaluation {
+	return &evaluation{
+		in:      in,
+		modules: make(map[string]*modentry),
+		loads:   make(map[string][]string),
+		envVars: make(map[string]struct{}),
+	}
+}
+
+// loadedFiles returns the sorted paths to the modules loaded so far.
+func (ev *evaluation) loadedFiles() []string {
+	files := make([]string, 0, len(ev.modules))
+	for file := range ev.modules {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// dependencies returns everything the evaluation depended on so far.
+func (ev *evaluation) dependencies() *Dependencies {
+	return &Dependencies{
+		LoadedFiles:   ev.loadedFiles(),
+		Globs:         append([]GlobDependency{}, ev.globs...),
+		Trees:         append([]TreeDependency{}, ev.trees...),
+		ShellCommands: append([]string{}, ev.shellCommands...),
+		EnvVars:       sortedKeys(ev.envVars),
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// builtins returns the predeclared symbols for `mode`.
+func (ev *evaluation) builtins(mode ExecutionMode) (starlark.StringDict, error) {
+	switch mode {
+	case ExecutionModeRbc:
+		if ev.rbcBuiltins == nil {
+			ev.rbcBuiltins = make(starlark.StringDict, len(ev.in.RbcBuiltins)+2)
+			for k, v := range ev.in.RbcBuiltins {
+				ev.rbcBuiltins[k] = v
+			}
+			ev.rbcBuiltins["rblf_cli"] = cliStruct(ev.in.CliVars)
+			if ev.in.Env != nil {
+				ev.rbcBuiltins["rblf_env"] = &envStruct{ev.in.Env, ev}
+			}
+		}
+		return ev.rbcBuiltins, nil
+	case ExecutionModeScl:
+		return ev.in.SclBuiltins, nil
+	}
+	return nil, fmt.Errorf("unknown executionMode %d", mode)
+}
+
+// useShared adds the shared .scl module at `modulePath` and the modules it
+// loads to the evaluation, and returns its entry, or nil if not shared.
+func (ev *evaluation) useShared(modulePath string) *modentry {
+	if !ev.in.ShareSclModules || !strings.HasSuffix(modulePath, ".scl") {
+		return nil
+	}
+	v, ok := ev.in.sclModules.Load(modulePath)
+	if !ok {
+		return nil
+	}
+	m := v.(*sclModule)
+	e := &modentry{m.globals, nil}
+	ev.modules[modulePath] = e
+	ev.loads[modulePath] = m.loads
+	for _, dep := range m.loads {
+		if _, ok := ev.modules[dep]; !ok {
+			if ev.useShared(dep) == nil {
+				ev.modules[dep] = &modentry{}
+			}
+		}
+	}
+	return e
+}
+
+// share offers the successfully loaded .scl module at `modulePath` to other
+// evaluations.
+func (ev *evaluation) share(modulePath string, e *modentry) {
+	if !ev.in.ShareSclModules || !strings.HasSuffix(modulePath, ".scl") || e.err != nil {
+		return
+	}
+	ev.in.sclModules.LoadOrStore(modulePath, &sclModule{e.globals, ev.loads[modulePath]})
+}
+
+// existingDir returns `dir` or its nearest existing ancestor relative to the
+// root directory of `thread`.
+func existingDir(thread *starlark.Thread, dir string) string {
+	for {
+		if fi, err := os.Stat(rootedPath(thread, dir)); err == nil && fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// rootedPath returns the location of `path` relative to the root directory of
+// `thread`.
+func rootedPath(thread *starlark.Thread, path string) string {
+	root, _ := thread.Local(rootDirKey).(string)
+	if root == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
 
 var rbcBuiltins starlark.StringDict = starlark.StringDict{
 	"struct":   starlark.NewBuiltin("struct", starlarkstruct.Make),
 	// To convert find-copy-subdir and product-copy-files-by pattern
 	"rblf_find_files": starlark.NewBuiltin("rblf_find_files", find),
+	// To convert makefile's $(wildcard foo) of a single file
+	"rblf_file_exists": starlark.NewBuiltin("rblf_file_exists", fileExists),
 	// To convert makefile's $(shell cmd)
 	"rblf_shell": starlark.NewBuiltin("rblf_shell", shell),
 	// Output to stderr
@@ -59,6 +305,12 @@ var rbcBuiltins starlark.StringDict = starlark.StringDict{
 
 var sclBuiltins starlark.StringDict = starlark.StringDict{
 	"struct":   starlark.NewBuiltin("struct", starlarkstruct.Make),
+	// The standard library, see stdlib.go
+	"json":    starlarkjson.Module,
+	"math":    starlarkmath.Module,
+	"semver":  semverModule,
+	"set":     starlark.Universe["set"],
+	"strings": stringsModule,
 }
 
 func isSymlink(filepath string) (bool, error) {
@@ -139,18 +391,24 @@ func loader(thread *starlark.Thread, module string) (starlark.StringDict, error)
 	if err != nil {
 		return nil, err
 	}
-	e, ok := moduleCache[modulePath]
+	ev := thread.Local(evaluationKey).(*evaluation)
+	ev.loads[callingFile] = append(ev.loads[callingFile], modulePath)
+	e, ok := ev.modules[modulePath]
+	if e == nil && !ok {
+		e = ev.useShared(modulePath)
+	}
+	edge := LoadEdge{From: callingFile, To: modulePath, Optional: !mustLoad, CacheHit: e != nil}
 	if e == nil {
 		if ok {
 			return nil, fmt.Errorf("cycle in load graph")
 		}
 
 		// Add a placeholder to indicate "load in progress".
-		moduleCache[modulePath] = nil
+		ev.modules[modulePath] = nil
 
 		// Decide if we should load.
 		if !mustLoad {
-			if _, err := os.Stat(modulePath); err == nil {
+			if _, err := os.Stat(rootedPath(thread, modulePath)); err == nil {
 				mustLoad = true
 			}
 		}
@@ -165,7 +423,7 @@ func loader(thread *starlark.Thread, module string) (starlark.StringDict, error)
 				mode = ExecutionModeScl
 			}
 
-			if sym, err := isSymlink(modulePath); sym && err == nil {
+			if sym, err := isSymlink(rootedPath(thread, modulePath)); sym && err == nil {
 				return nil, fmt.Errorf("symlinks to starlark files are not allowed. Instead, load the target file and re-export its symbols: %s", modulePath)
 			} else if err != nil {
 				return nil, err
@@ -183,30 +441,41 @@ func loader(thread *starlark.Thread, module string) (starlark.StringDict, error)
 			// Only the entrypoint starlark file allows external loads.
 			childThread.SetLocal(allowExternalEntrypointKey, false)
 			childThread.SetLocal(callingFileKey, modulePath)
+			childThread.SetLocal(evaluationKey, ev)
 			childThread.SetLocal(executionModeKey, mode)
+			childThread.SetLocal(rootDirKey, thread.Local(rootDirKey))
 			childThread.SetLocal(shellKey, thread.Local(shellKey))
-			if mode == ExecutionModeRbc {
-				globals, err := starlark.ExecFile(childThread, modulePath, nil, rbcBuiltins)
-				e = &modentry{globals, err}
-			} else if mode == ExecutionModeScl {
-				globals, err := starlark.ExecFile(childThread, modulePath, nil, sclBuiltins)
-				e = &modentry{globals, err}
+			builtins, err := ev.builtins(mode)
+			if err != nil {
+				return nil, err
+			}
+			src, err := os.ReadFile(rootedPath(thread, modulePath))
+			if err != nil {
+				e = &modentry{nil, err}
 			} else {
-				return nil, fmt.Errorf("unknown executionMode %d", mode)
+				ev.timeExec(modulePath, func() {
+					globals, err := starlark.ExecFile(childThread, modulePath, src, builtins)
+					e = &modentry{globals, err}
+				})
+				ev.share(modulePath, e)
 			}
 		} else {
 			e = &modentry{starlark.StringDict{defaultSymbol: starlark.None}, nil}
 		}
 
 		// Update the cache.
-		moduleCache[modulePath] = e
+		ev.modules[modulePath] = e
+		edge.Missing = !mustLoad
+	}
+	if ev.in.LoadGraph != nil {
+		ev.in.LoadGraph.AddEdge(edge)
 	}
 	return e.globals, e.err
 }
 
 // wildcard(pattern, top=None) expands shell's glob pattern. If 'top' is present,
 // the 'top/pattern' is globbed and then 'top/' prefix is removed.
-func wildcard(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+func wildcard(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 	kwargs []starlark.Tuple) (starlark.Value, error) {
 	var pattern string
 	var top string
@@ -215,14 +484,23 @@ func wildcard(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 		return starlark.None, err
 	}
 
+	// Relative patterns expand in the root directory, if any.
+	rootPrefix := ""
+	if root, _ := thread.Local(rootDirKey).(string); root != "" && !filepath.IsAbs(top+pattern) {
+		rootPrefix = root + string(filepath.Separator)
+	}
+
 	var files []string
 	var err error
 	if top == "" {
-		if files, err = filepath.Glob(pattern); err != nil {
+		if files, err = filepath.Glob(rootPrefix + pattern); err != nil {
 			return starlark.None, err
 		}
+		for i := range files {
+			files[i] = strings.TrimPrefix(files[i], rootPrefix)
+		}
 	} else {
-		prefix := top + string(filepath.Separator)
+		prefix := rootPrefix + top + string(filepath.Separator)
 		if files, err = filepath.Glob(prefix + pattern); err != nil {
 			return starlark.None, err
 		}
@@ -234,6 +512,18 @@ func wildcard(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 	// because GLOB_NOSORT is not passed. Go's glob is not
 	// guaranteed to sort the results.
 	sort.Strings(files)
+
+	if ev, ok := thread.Local(evaluationKey).(*evaluation); ok {
+		fullPattern := pattern
+		if top != "" {
+			fullPattern = filepath.Join(top, pattern)
+		}
+		dirs := globDirs(rootPrefix, fullPattern)
+		for i := range dirs {
+			dirs[i] = existingDir(thread, dirs[i])
+		}
+		ev.globs = append(ev.globs, GlobDependency{fullPattern, dirs})
+	}
 	return makeStringList(files), nil
 }
 
@@ -241,7 +531,7 @@ func wildcard(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 // whose basename matches 'pattern' (which is a shell's glob pattern).
 // If 'only_files' is non-zero, only the paths to the regular files are
 // returned. The returned paths are relative to 'top'.
-func find(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+func find(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 	kwargs []starlark.Tuple) (starlark.Value, error) {
 	var top, pattern string
 	var onlyFiles int
@@ -249,8 +539,11 @@ func find(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 		"top", &top, "pattern", &pattern, "only_files?", &onlyFiles); err != nil {
 		return starlark.None, err
 	}
-	top = filepath.Clean(top)
+	unrootedTop := filepath.Clean(top)
+	top = rootedPath(thread, unrootedTop)
 	pattern = filepath.Clean(pattern)
+	ev, _ := thread.Local(evaluationKey).(*evaluation)
+	var walked []string
 	// Go's filepath.Walk is slow, consider using OS's find
 	var res []string
 	err := filepath.WalkDir(top, func(path string, d fs.DirEntry, err error) error {
@@ -265,6 +558,9 @@ func find(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 		if len(relPath) > 0 && relPath[0] == os.PathSeparator {
 			relPath = relPath[1:]
 		}
+		if ev != nil && d.IsDir() {
+			walked = append(walked, filepath.Join(unrootedTop, relPath))
+		}
 		// Do not return top-level dir
 		if len(relPath) == 0 {
 			return nil
@@ -274,6 +570,13 @@ func find(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 		}
 		return nil
 	})
+	if ev != nil {
+		if len(walked) == 0 {
+			// A missing top matters once it appears.
+			walked = []string{existingDir(thread, filepath.Dir(unrootedTop))}
+		}
+		ev.trees = append(ev.trees, TreeDependency{unrootedTop, pattern, walked})
+	}
 	return makeStringList(res), err
 }
 
@@ -288,14 +591,17 @@ func shell(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
 		return starlark.None, err
 	}
 	shellPath := thread.Local(shellKey).(string)
-	if shellPath == "" {
-		return starlark.None,
-			fmt.Errorf("cannot run shell, /bin/sh is missing (running on Windows?)")
-	}
-	cmd := exec.Command(shellPath, "-c", command)
-	// We ignore command's status
-	bytes, _ := cmd.Output()
-	output := string(bytes)
+	root, _ := thread.Local(rootDirKey).(string)
+	// Without an evaluation e.g. in tests, run the command unrestricted.
+	in := &Interpreter{}
+	if ev, ok := thread.Local(evaluationKey).(*evaluation); ok {
+		ev.shellCommands = append(ev.shellCommands, command)
+		in = ev.in
+	}
+	output, err := in.runShell(shellPath, root, command)
+	if err != nil {
+		return starlark.None, err
+	}
 	if strings.HasSuffix(output, "\n") {
 		output = strings.TrimSuffix(output, "\n")
 	} else {
@@ -346,13 +652,41 @@ func log(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwa
 //   (it can be a string, or a byte array, or an io.Reader instance)
 // Returns the top-level starlark variables, the list of starlark files loaded, and an error
 func Run(filename string, src interface{}, mode ExecutionMode, allowExternalEntrypoint bool) (starlark.StringDict, []string, error) {
-	// NOTE(asmundak): OS-specific. Behave similar to Linux `system` call,
-	// which always uses /bin/sh to run the command
-	shellPath := "/bin/sh"
-	if _, err := os.Stat(shellPath); err != nil {
-		shellPath = ""
+	in := NewInterpreter(mode)
+	in.AllowExternalEntrypoint = allowExternalEntrypoint
+	return in.Run(filename, src)
+}
+
+// Run parses, resolves, and executes the Starlark file `filename` relative to
+// the root directory, or `src` if not nil, as for the package Run function.
+// (thread safe)
+func (in *Interpreter) Run(filename string, src interface{}) (starlark.StringDict, []string, error) {
+	globals, deps, err := in.RunWithDependencies(filename, src)
+	if deps == nil {
+		return globals, nil, err
 	}
+	return globals, deps.LoadedFiles, err
+}
 
+// RunWithDependencies is like Run, but returns everything the evaluation
+// depends on instead of only the loaded files. (thread safe)
+func (in *Interpreter) RunWithDependencies(filename string, src interface{}) (starlark.StringDict, *Dependencies, error) {
+	_, ev, results, err := in.execFile(nil, filename, src, in.Mode, nil)
+	if ev == nil {
+		return nil, nil, err
+	}
+	return results, ev.dependencies(), err
+}
+
+// execFile executes `filename`, or `src` if not nil, in `mode` with `extra`
+// symbols predeclared in addition to the builtins of `mode`. It continues
+// evaluation `ev` reusing the modules it loaded, or starts a new one if nil.
+// It returns the thread the file executed on for calling its functions, and
+// a nil evaluation if execution never started.
+func (in *Interpreter) execFile(ev *evaluation, filename string, src interface{}, mode ExecutionMode, extra starlark.StringDict) (*starlark.Thread, *evaluation, starlark.StringDict, error) {
+	if ev == nil {
+		ev = newEvaluation(in)
+	}
 	mainThread := &starlark.Thread{
 		Name:  "main",
 		Print: func(_ *starlark.Thread, msg string) {
@@ -365,52 +699,78 @@ func Run(filename string, src interface{}, mode ExecutionMode, allowExternalEntr
 		},
 		Load:  loader,
 	}
-	filename, err := filepath.Abs(filename)
-	if err != nil {
-		return nil, nil, err
-	}
-	if wd, err := os.Getwd(); err == nil {
-		filename, err = filepath.Rel(wd, filename)
+	root := in.RootDir
+	if root == "" {
+		wd, err := os.Getwd()
 		if err != nil {
-			return nil, nil, err
-		}
-		if !allowExternalEntrypoint && strings.HasPrefix(filename, "../") {
-			return nil, nil, fmt.Errorf("path could not be made relative to workspace root: %s", filename)
+			return nil, nil, nil, err
 		}
-	} else {
-		return nil, nil, err
+		root = wd
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(root, filename)
+	}
+	filename, err = filepath.Rel(root, filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !in.AllowExternalEntrypoint && strings.HasPrefix(filename, "../") {
+		return nil, nil, nil, fmt.Errorf("path could not be made relative to workspace root: %s", filename)
 	}
 
-	if sym, err := isSymlink(filename); sym && err == nil {
-		return nil, nil, fmt.Errorf("symlinks to starlark files are not allowed. Instead, load the target file and re-export its symbols: %s", filename)
-	} else if err != nil {
-		return nil, nil, err
+	// An entrypoint given as `src` needs no file.
+	if src == nil {
+		if sym, err := isSymlink(filepath.Join(root, filename)); sym && err == nil {
+			return nil, nil, nil, fmt.Errorf("symlinks to starlark files are not allowed. Instead, load the target file and re-export its symbols: %s", filename)
+		} else if err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
 	if mode == ExecutionModeScl && !strings.HasSuffix(filename, ".scl") {
-		return nil, nil, fmt.Errorf("filename must end in .scl: %s", filename)
+		return nil, nil, nil, fmt.Errorf("filename must end in .scl: %s", filename)
 	}
 
 	// Add top-level file to cache for cycle detection purposes
-	moduleCache[filename] = nil
+	ev.modules[filename] = nil
 
-	var results starlark.StringDict
-	mainThread.SetLocal(allowExternalEntrypointKey, allowExternalEntrypoint)
-	mainThread.SetLocal(callingFileKey, filename)
-	mainThread.SetLocal(executionModeKey, mode)
-	mainThread.SetLocal(shellKey, shellPath)
-	if mode == ExecutionModeRbc {
-		results, err = starlark.ExecFile(mainThread, filename, src, rbcBuiltins)
-	} else if mode == ExecutionModeScl {
-		results, err = starlark.ExecFile(mainThread, filename, src, sclBuiltins)
-	} else {
-		return results, nil, fmt.Errorf("unknown executionMode %d", mode)
+	builtins, err := ev.builtins(mode)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	loadedStarlarkFiles := make([]string, 0, len(moduleCache))
-	for file := range moduleCache {
-		loadedStarlarkFiles = append(loadedStarlarkFiles, file)
+	if extra != nil {
+		predeclared := make(starlark.StringDict, len(builtins)+len(extra))
+		for k, v := range builtins {
+			predeclared[k] = v
+		}
+		for k, v := range extra {
+			predeclared[k] = v
+		}
+		builtins = predeclared
+	}
+	if src == nil {
+		data, err := os.ReadFile(filepath.Join(root, filename))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		src = data
 	}
-	sort.Strings(loadedStarlarkFiles)
 
-	return results, loadedStarlarkFiles, err
+	mainThread.SetLocal(allowExternalEntrypointKey, in.AllowExternalEntrypoint)
+	mainThread.SetLocal(callingFileKey, filename)
+	mainThread.SetLocal(evaluationKey, ev)
+	mainThread.SetLocal(executionModeKey, mode)
+	if in.RootDir != "" {
+		mainThread.SetLocal(rootDirKey, root)
+	}
+	mainThread.SetLocal(shellKey, in.shell)
+	var results starlark.StringDict
+	ev.timeExec(filename, func() {
+		results, err = starlark.ExecFile(mainThread, filename, src, builtins)
+	})
+	return mainThread, ev, results, err
 }
@@ -74,6 +74,14 @@ func dataDir() string {
 	return filepath.Join(filepath.Dir(thisSrcFile), "testdata")
 }
 
+// testdataInterpreter returns an Interpreter in `mode` rooted at the testdata
+// subdirectory `dir`.
+func testdataInterpreter(mode ExecutionMode, dir string) *Interpreter {
+	in := NewInterpreter(mode)
+	in.RootDir = filepath.Join(dataDir(), dir)
+	return in
+}
+
 func exerciseStarlarkTestFile(t *testing.T, starFile string) {
 	// In order to use "assert.star" from go/starlark.net/starlarktest in the tests, provide:
 	//  * load function that handles "assert.star"
@@ -112,6 +120,18 @@ func TestFileOps(t *testing.T) {
 	}
 }
 
+func TestSclStdlib(t *testing.T) {
+	suite := testdataInterpreter(ExecutionModeScl, "stdlib").RunTests("stdlib_test.scl")
+	if len(suite.Cases) == 0 {
+		t.Fatal("Expected stdlib_test.scl to have tests")
+	}
+	for _, tc := range suite.Cases {
+		if !tc.Passed() {
+			t.Errorf("%s failed:\n%s%s\n%s", tc.Name, tc.Failure, tc.Error, tc.Backtrace)
+		}
+	}
+}
+
 func TestLoad(t *testing.T) {
 	// TODO(asmundak): convert this to use exerciseStarlarkTestFile
 	thread := testSetup(t)
@@ -128,6 +148,7 @@ func TestLoad(t *testing.T) {
 	}
 	thread.SetLocal(allowExternalEntrypointKey, false)
 	thread.SetLocal(callingFileKey, "testdata/load.star")
+	thread.SetLocal(evaluationKey, newEvaluation(NewInterpreter(ExecutionModeRbc)))
 	thread.SetLocal(executionModeKey, ExecutionModeRbc)
 	if _, err := starlark.ExecFile(thread, "testdata/load.star", nil, rbcBuiltins); err != nil {
 		if err, ok := err.(*starlark.EvalError); ok {
@@ -138,7 +159,6 @@ func TestLoad(t *testing.T) {
 }
 
 func TestBzlLoadsScl(t *testing.T) {
-	moduleCache = make(map[string]*modentry)
 	dir := dataDir()
 	if err := os.Chdir(filepath.Dir(dir)); err != nil {
 		t.Fatal(err)
@@ -155,7 +175,6 @@ func TestBzlLoadsScl(t *testing.T) {
 }
 
 func TestNonEntrypointBzlLoadsScl(t *testing.T) {
-	moduleCache = make(map[string]*modentry)
 	dir := dataDir()
 	if err := os.Chdir(filepath.Dir(dir)); err != nil {
 		t.Fatal(err)
@@ -172,7 +191,6 @@ func TestNonEntrypointBzlLoadsScl(t *testing.T) {
 }
 
 func TestSclLoadsBzl(t *testing.T) {
-	moduleCache = make(map[string]*modentry)
 	dir := dataDir()
 	if err := os.Chdir(filepath.Dir(dir)); err != nil {
 		t.Fatal(err)
@@ -187,7 +205,6 @@ func TestSclLoadsBzl(t *testing.T) {
 }
 
 func TestCantLoadSymlink(t *testing.T) {
-	moduleCache = make(map[string]*modentry)
 	dir := dataDir()
 	if err := os.Chdir(filepath.Dir(dir)); err != nil {
 		t.Fatal(err)
@@ -204,3 +221,86 @@ func TestCantLoadSymlink(t *testing.T) {
 func TestShell(t *testing.T) {
 	exerciseStarlarkTestFile(t, "testdata/shell.star")
 }
+
+func TestInterpreterRootDir(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("entry.star", "load(\":mod.star\", _x = \"x\")\n\nx = _x\nfiles = rblf_wildcard(\"*.star\")\n")
+	writeFile("mod.star", "x = 1\n")
+
+	// Evaluate from a different working directory.
+	if err := os.Chdir(dataDir()); err != nil {
+		t.Fatal(err)
+	}
+	in := NewInterpreter(ExecutionModeRbc)
+	in.RootDir = root
+	for _, want := range []string{"1", "2"} {
+		writeFile("mod.star", "x = "+want+"\n")
+		vars, loaded, err := in.Run("entry.star", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := vars["x"].String(); got != want {
+			t.Errorf("Expected x = %s, got %s", want, got)
+		}
+		if got := vars["files"].String(); got != `["entry.star", "mod.star"]` {
+			t.Errorf("Expected files relative to the root, got %s", got)
+		}
+		if got := strings.Join(loaded, " "); got != "entry.star mod.star" {
+			t.Errorf("Expected loaded files \"entry.star mod.star\", got %q", got)
+		}
+	}
+}
+
+func TestInterpreterConcurrent(t *testing.T) {
+	in := NewInterpreter(ExecutionModeRbc)
+	in.RootDir = filepath.Dir(dataDir())
+	in.ShareSclModules = true
+
+	const n = 8
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			vars, loaded, err := in.Run("testdata/bzl_loads_scl.bzl", nil)
+			if err == nil && vars["foo"] != starlark.String("bar") {
+				err = fmt.Errorf("Expected \"bar\", got %v", vars["foo"])
+			}
+			if got := strings.Join(loaded, " "); err == nil && got != "testdata/bzl_loads_scl.bzl testdata/test_scl.scl" {
+				err = fmt.Errorf("Unexpected loaded files %q", got)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestInterpreterShareSclModules(t *testing.T) {
+	for _, share := range []bool{false, true} {
+		in := NewInterpreter(ExecutionModeScl)
+		in.RootDir = filepath.Dir(dataDir())
+		in.ShareSclModules = share
+
+		var lists []*starlark.List
+		for i := 0; i < 2; i++ {
+			vars, loaded, err := in.Run("testdata/loads_shared_list.scl", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(loaded, " "); got != "testdata/loads_shared_list.scl testdata/shared_list.scl" {
+				t.Errorf("share=%v: unexpected loaded files %q", share, got)
+			}
+			lists = append(lists, vars["values"].(*starlark.List))
+		}
+		if (lists[0] == lists[1]) != share {
+			t.Errorf("share=%v: got same module value %v, want %v", share, lists[0] == lists[1], share)
+		}
+	}
+}
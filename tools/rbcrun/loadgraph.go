@@ -0,0 +1,164 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LoadEdge describes a single load statement.
+type LoadEdge struct {
+	// From is the path to the loading module.
+	From string `json:"from"`
+
+	// To is the path to the loaded module.
+	To string `json:"to"`
+
+	// Optional is true for the "|symbol" form.
+	Optional bool `json:"optional"`
+
+	// Missing is true when an optional module did not exist.
+	Missing bool `json:"missing"`
+
+	// CacheHit is true when the module was already loaded.
+	CacheHit bool `json:"cache_hit"`
+}
+
+// ModuleTiming is the wall time spent executing a module.
+type ModuleTiming struct {
+	// Path is the path to the module.
+	Path string
+
+	// Time includes the modules it loaded.
+	Time time.Duration
+
+	// SelfTime excludes the modules it loaded.
+	SelfTime time.Duration
+}
+
+// MarshalJSON writes the times in microseconds.
+func (m ModuleTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path       string `json:"path"`
+		TimeUs     int64  `json:"time_us"`
+		SelfTimeUs int64  `json:"self_time_us"`
+	}{m.Path, m.Time.Microseconds(), m.SelfTime.Microseconds()})
+}
+
+// LoadGraph records the loads and the module timings of evaluations.
+// (thread safe)
+type LoadGraph struct {
+	mu      sync.Mutex
+	edges   []LoadEdge
+	modules []ModuleTiming
+}
+
+// AddEdge appends `edge` to the graph.
+func (g *LoadGraph) AddEdge(edge LoadEdge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.edges = append(g.edges, edge)
+}
+
+// AddModule appends `timing` to the graph.
+func (g *LoadGraph) AddModule(timing ModuleTiming) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.modules = append(g.modules, timing)
+}
+
+// Edges returns the loads recorded so far in completion order, so nested
+// loads precede the loads of their callers.
+func (g *LoadGraph) Edges() []LoadEdge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]LoadEdge{}, g.edges...)
+}
+
+// Modules returns the modules executed so far in completion order.
+func (g *LoadGraph) Modules() []ModuleTiming {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ModuleTiming{}, g.modules...)
+}
+
+// WriteJSON writes the graph to `w` as JSON.
+func (g *LoadGraph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Modules []ModuleTiming `json:"modules"`
+		Edges   []LoadEdge     `json:"edges"`
+	}{g.Modules(), g.Edges()})
+}
+
+// WriteDOT writes the graph to `w` in Graphviz DOT format. Modules are
+// labeled with their times, optional loads are dashed, missing modules are
+// dotted and cache hits are gray.
+func (g *LoadGraph) WriteDOT(w io.Writer) error {
+	if _, err := io.WriteString(w, "digraph load_graph {\n"); err != nil {
+		return err
+	}
+	for _, m := range g.Modules() {
+		label := fmt.Sprintf("%s\n%s (self %s)", m.Path, m.Time.Round(time.Microsecond), m.SelfTime.Round(time.Microsecond))
+		if _, err := fmt.Fprintf(w, "  %s [label=%s];\n", strconv.Quote(m.Path), strconv.Quote(label)); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges() {
+		var attrs string
+		switch {
+		case e.Missing:
+			attrs = " [style=dotted]"
+		case e.Optional && e.CacheHit:
+			attrs = " [style=dashed, color=gray]"
+		case e.Optional:
+			attrs = " [style=dashed]"
+		case e.CacheHit:
+			attrs = " [color=gray]"
+		}
+		if _, err := fmt.Fprintf(w, "  %s -> %s%s;\n", strconv.Quote(e.From), strconv.Quote(e.To), attrs); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// timeExec calls `exec` to execute the module at `path`, and records its
+// wall time in the load graph of the evaluation, if any.
+func (ev *evaluation) timeExec(path string, exec func()) {
+	g := ev.in.LoadGraph
+	if g == nil {
+		exec()
+		return
+	}
+	start := time.Now()
+	ev.childTimes = append(ev.childTimes, 0)
+	exec()
+	elapsed := time.Since(start)
+	last := len(ev.childTimes) - 1
+	children := ev.childTimes[last]
+	ev.childTimes = ev.childTimes[:last]
+	if last > 0 {
+		ev.childTimes[last-1] += elapsed
+	}
+	g.AddModule(ModuleTiming{path, elapsed, elapsed - children})
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadGraphSetup() *Interpreter {
+	in := testdataInterpreter(ExecutionModeRbc, "load_graph")
+	in.LoadGraph = &LoadGraph{}
+	return in
+}
+
+func TestLoadGraph(t *testing.T) {
+	in := loadGraphSetup()
+	if _, _, err := in.Run("entry.star", nil); err != nil {
+		t.Fatal(err)
+	}
+	expectedEdges := []LoadEdge{
+		{From: "a.star", To: "b.star"},
+		{From: "entry.star", To: "a.star"},
+		{From: "entry.star", To: "b.star", CacheHit: true},
+		{From: "entry.star", To: "opt.star", Optional: true},
+		{From: "entry.star", To: "missing.star", Optional: true, Missing: true},
+	}
+	if got := in.LoadGraph.Edges(); !reflect.DeepEqual(got, expectedEdges) {
+		t.Errorf("Unexpected edges:\ngot  %+v\nwant %+v", got, expectedEdges)
+	}
+
+	modules := in.LoadGraph.Modules()
+	var paths []string
+	timings := make(map[string]ModuleTiming)
+	for _, m := range modules {
+		paths = append(paths, m.Path)
+		timings[m.Path] = m
+		if m.SelfTime < 0 || m.SelfTime > m.Time {
+			t.Errorf("Unexpected %s self time %s of %s", m.Path, m.SelfTime, m.Time)
+		}
+	}
+	if expected := []string{"b.star", "a.star", "opt.star", "entry.star"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Unexpected modules %v, want %v", paths, expected)
+	}
+	a, b := timings["a.star"], timings["b.star"]
+	if a.SelfTime != a.Time-b.Time {
+		t.Errorf("Expected a.star self time %s to exclude b.star time %s from %s", a.SelfTime, b.Time, a.Time)
+	}
+	entry := timings["entry.star"]
+	if children := a.Time + timings["opt.star"].Time; entry.SelfTime != entry.Time-children {
+		t.Errorf("Expected entry.star self time %s to exclude %s from %s", entry.SelfTime, children, entry.Time)
+	}
+}
+
+func TestLoadGraphOutput(t *testing.T) {
+	in := loadGraphSetup()
+	if _, _, err := in.Run("entry.star", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := in.LoadGraph.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var graph struct {
+		Modules []struct {
+			Path       string `json:"path"`
+			TimeUs     *int64 `json:"time_us"`
+			SelfTimeUs *int64 `json:"self_time_us"`
+		} `json:"modules"`
+		Edges []LoadEdge `json:"edges"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &graph); err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Modules) != 4 || graph.Modules[3].Path != "entry.star" || graph.Modules[3].TimeUs == nil || graph.Modules[3].SelfTimeUs == nil {
+		t.Errorf("Unexpected JSON modules in %s", buf.String())
+	}
+	if !reflect.DeepEqual(graph.Edges, in.LoadGraph.Edges()) {
+		t.Errorf("Unexpected JSON edges in %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := in.LoadGraph.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		"digraph load_graph {\n",
+		"  \"b.star\" [label=\"b.star\\n",
+		"  \"a.star\" -> \"b.star\";\n",
+		"  \"entry.star\" -> \"b.star\" [color=gray];\n",
+		"  \"entry.star\" -> \"opt.star\" [style=dashed];\n",
+		"  \"entry.star\" -> \"missing.star\" [style=dotted];\n",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected %q in DOT:\n%s", want, dot)
+		}
+	}
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("Expected DOT to end with }:\n%s", dot)
+	}
+}
@@ -15,9 +15,11 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"rbcrun"
 	"regexp"
 	"strings"
@@ -26,28 +28,62 @@ import (
 )
 
 var (
+	execstr  = flag.String("c", "", "the script to run instead of a file")
+	fileFlag = flag.String("f", "", "the file to run, e.g. when its name contains =")
+	allowEnv = flag.Bool("allow_env", false, "expose the environment to rbc files as rblf_env, recording the variables read in the -depfile JSON sidecar")
 	allowExternalEntrypoint = flag.Bool("allow_external_entrypoint", false, "allow the entrypoint starlark file to be outside of the source tree")
-	modeFlag  = flag.String("mode", "", "the general behavior of rbcrun. Can be \"rbc\" or \"make\". Required.")
+	modeFlag  = flag.String("mode", "", "the general behavior of rbcrun. Can be \"rbc\", \"make\" or \"test\". Required.")
 	rootdir  = flag.String("d", ".", "the value of // for load paths")
 	perfFile = flag.String("perf", "", "save performance data")
+	depFile = flag.String("depfile", "", "write a ninja/make depfile of the loaded files, globbed directories and walked trees, plus a JSON sidecar depfile.json also listing the shell commands")
+	depFileTarget = flag.String("depfile_target", "", "the target named in the depfile (default the depfile path without its .d suffix)")
+	hermetic = flag.String("hermetic", "", "restrict rblf_shell. Can be \"forbid\", \"allowlist\" or \"replay\"")
+	shellAllowlist = flag.String("shell_allowlist", "", "file with one regular expression per line matching the commands allowed by -hermetic=allowlist")
+	shellReplay = flag.String("shell_replay", "", "shell log file with the command outputs served by -hermetic=replay")
+	shellLog = flag.String("shell_log", "", "write each rblf_shell command with its exit code and output to this file")
+	outputFormat = flag.String("output_format", "make", "the format of the exported variables. Can be \"make\", \"json\" or \"textproto\"")
+	checkFlag = flag.Bool("check", false, "report the violations of the file and the files it loads without executing them")
+	replFlag = flag.Bool("repl", false, "evaluate the file, then read, evaluate and print Starlark statements on its globals from stdin")
+	loadGraph = flag.String("load_graph", "", "write every load and the time spent executing each module to this file, as DOT if it ends in .dot and as JSON otherwise")
+	junitOutput = flag.String("junit_output", "", "with -mode=test, write the test results to this file as JUnit XML")
+	outputGlobals = flag.Bool("output_globals", false, "export all public top-level globals instead of variables_to_export_to_make. Requires a -output_format other than \"make\"")
 	identifierRe = regexp.MustCompile("[a-zA-Z_][a-zA-Z0-9_]*")
 )
 
-func getEntrypointStarlarkFile() string {
-	filename := ""
+// getEntrypoint returns the entrypoint starlark file, or a name for it
+// and its source with -c, and the variables set by VAR=value arguments.
+func getEntrypoint(mode rbcrun.ExecutionMode) (string, interface{}, map[string]string) {
+	filename := *fileFlag
+	var src interface{}
+	cliVars := make(map[string]string)
 
 	for _, arg := range flag.Args() {
-		if filename == "" {
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			if identifierRe.FindString(name) != name {
+				quit("%s: variable name must be a valid c identifier\n", arg)
+			}
+			cliVars[name] = value
+		} else if filename == "" {
 			filename = arg
 		} else {
 			quit("only one file can be executed\n")
 		}
 	}
+	if *execstr != "" {
+		if filename != "" {
+			quit("cannot run both -c and a file\n")
+		}
+		filename = "<cmdline>"
+		if mode == rbcrun.ExecutionModeScl {
+			filename += ".scl"
+		}
+		src = *execstr
+	}
 	if filename == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
-	return filename
+	return filename, src, cliVars
 }
 
 func getMode() rbcrun.ExecutionMode {
@@ -59,7 +95,7 @@ func getMode() rbcrun.ExecutionMode {
 	case "":
 		quit("-mode flag is required.")
 	default:
-		quit("Unknown -mode value %q, expected 1 of \"rbc\", \"make\"", *modeFlag)
+		quit("Unknown -mode value %q, expected 1 of \"rbc\", \"make\", \"test\"", *modeFlag)
 	}
 	return rbcrun.ExecutionModeScl
 }
@@ -111,28 +147,20 @@ func printVarsInMakeFormat(globals starlark.StringDict) error {
 	// a variables_to_export_to_make dictionary, but that wouldn't allow for exporting a
 	// runtime-defined number of variables to make. This can be important because dictionaries
 	// in make are often represented by a unique variable for every key in the dictionary.
-	variablesValue, ok := globals["variables_to_export_to_make"]
-	if !ok {
-		return fmt.Errorf("expected top-level starlark file to have a \"variables_to_export_to_make\" variable")
-	}
-	variables, ok := variablesValue.(*starlark.Dict)
-	if !ok {
-		return fmt.Errorf("expected variables_to_export_to_make to be a dict, got %s", variablesValue.Type())
+	variables, err := rbcrun.ExportedVariables(globals)
+	if err != nil {
+		return err
 	}
 
-	for _, varTuple := range variables.Items() {
-		varNameStarlark, ok := varTuple.Index(0).(starlark.String)
-		if !ok {
-			return fmt.Errorf("all keys in variables_to_export_to_make must be strings, but got %q", varTuple.Index(0).Type())
-		}
-		varName := varNameStarlark.GoString()
+	for _, variable := range variables {
+		varName := variable.Name
 		if !identifierRe.MatchString(varName) {
 			return fmt.Errorf("all variables at the top level starlark file must be valid c identifiers, but got %q", varName)
 		}
 		if varName == "LOADED_STARLARK_FILES" {
 			return fmt.Errorf("the name LOADED_STARLARK_FILES is reserved for use by the starlark interpreter")
 		}
-		valueMake, err := getValueInMakeFormat(varTuple.Index(1), true)
+		valueMake, err := getValueInMakeFormat(variable.Value, true)
 		if err != nil {
 			return err
 		}
@@ -142,14 +170,81 @@ func printVarsInMakeFormat(globals starlark.StringDict) error {
 	return nil
 }
 
+// printVars writes the exported variables, or all globals with
+// -output_globals, to stdout in `format`.
+func printVars(globals starlark.StringDict, format string) error {
+	var variables []rbcrun.Variable
+	if *outputGlobals {
+		variables = rbcrun.GlobalVariables(globals)
+	} else {
+		var err error
+		if variables, err = rbcrun.ExportedVariables(globals); err != nil {
+			return err
+		}
+	}
+	if format == "json" {
+		return rbcrun.WriteJSON(os.Stdout, variables)
+	}
+	return rbcrun.WriteTextproto(os.Stdout, variables)
+}
+
 func main() {
 	flag.Parse()
-	filename := getEntrypointStarlarkFile()
+	if *modeFlag == "test" {
+		os.Exit(runTests())
+	}
 	mode := getMode()
+	filename, src, cliVars := getEntrypoint(mode)
+	if mode == rbcrun.ExecutionModeScl && (len(cliVars) > 0 || *allowEnv) {
+		quit("VAR=value and -allow_env require -mode=rbc, scl files are hermetic\n")
+	}
+	switch *outputFormat {
+	case "make":
+		if *outputGlobals {
+			quit("-output_globals requires -output_format=json or -output_format=textproto\n")
+		}
+	case "json", "textproto":
+	default:
+		quit("Unknown -output_format value %q, expected 1 of \"make\", \"json\", \"textproto\"\n", *outputFormat)
+	}
+
+	// The depfile paths are relative to the original working directory.
+	if *depFile != "" {
+		if *depFileTarget == "" {
+			*depFileTarget = strings.TrimSuffix(*depFile, ".d")
+		}
+		abs, err := filepath.Abs(*depFile)
+		if err != nil {
+			quit("%s: %s\n", *depFile, err)
+		}
+		*depFile = abs
+	}
+
+	interpreter := rbcrun.NewInterpreter(mode)
+	interpreter.AllowExternalEntrypoint = *allowExternalEntrypoint
+	interpreter.CliVars = cliVars
+	if *allowEnv {
+		interpreter.Env = rbcrun.EnvironMap(os.Environ())
+	}
+	if *loadGraph != "" {
+		abs, err := filepath.Abs(*loadGraph)
+		if err != nil {
+			quit("%s: %s\n", *loadGraph, err)
+		}
+		*loadGraph = abs
+		interpreter.LoadGraph = &rbcrun.LoadGraph{}
+	}
+	setupHermetic(interpreter)
 
 	if os.Chdir(*rootdir) != nil {
 		quit("could not chdir to %s\n", *rootdir)
 	}
+	if *checkFlag {
+		os.Exit(runCheck(interpreter, filename, src))
+	}
+	if *replFlag {
+		os.Exit(runRepl(interpreter, filename, src))
+	}
 	if *perfFile != "" {
 		pprof, err := os.Create(*perfFile)
 		if err != nil {
@@ -160,7 +255,39 @@ func main() {
 			quit("%s\n", err)
 		}
 	}
-	variables, loadedStarlarkFiles, err := rbcrun.Run(filename, nil, mode, *allowExternalEntrypoint)
+	variables, deps, err := interpreter.RunWithDependencies(filename, src)
+	if src != nil && deps != nil {
+		// The -c script is not a file to depend on.
+		var files []string
+		for _, f := range deps.LoadedFiles {
+			if f != filename {
+				files = append(files, f)
+			}
+		}
+		deps.LoadedFiles = files
+	}
+	if interpreter.ShellLog != nil {
+		var buf bytes.Buffer
+		if err := interpreter.ShellLog.WriteJSON(&buf); err != nil {
+			quit("%s\n", err)
+		}
+		if err := os.WriteFile(*shellLog, buf.Bytes(), 0666); err != nil {
+			quit("%s\n", err)
+		}
+	}
+	if interpreter.LoadGraph != nil {
+		var buf bytes.Buffer
+		write := interpreter.LoadGraph.WriteJSON
+		if strings.HasSuffix(*loadGraph, ".dot") {
+			write = interpreter.LoadGraph.WriteDOT
+		}
+		if err := write(&buf); err != nil {
+			quit("%s\n", err)
+		}
+		if err := os.WriteFile(*loadGraph, buf.Bytes(), 0666); err != nil {
+			quit("%s\n", err)
+		}
+	}
 	rc := 0
 	if *perfFile != "" {
 		if err2 := starlark.StopProfile(); err2 != nil {
@@ -175,15 +302,200 @@ func main() {
 			quit("%s\n", err)
 		}
 	}
-	if mode == rbcrun.ExecutionModeScl {
+	if *outputFormat != "make" {
+		if err := printVars(variables, *outputFormat); err != nil {
+			quit("%s\n", err)
+		}
+	} else if mode == rbcrun.ExecutionModeScl {
 		if err := printVarsInMakeFormat(variables); err != nil {
 			quit("%s\n", err)
 		}
-		fmt.Printf("LOADED_STARLARK_FILES := %s\n", strings.Join(loadedStarlarkFiles, " "))
+		fmt.Printf("LOADED_STARLARK_FILES := %s\n", strings.Join(deps.LoadedFiles, " "))
+	}
+	if *depFile != "" {
+		// The dependencies are relative to -d, the depfile to the original
+		// working directory.
+		if err := writeDepFiles(deps.Rebase(*rootdir), *depFile, *depFileTarget); err != nil {
+			quit("%s\n", err)
+		}
 	}
 	os.Exit(rc)
 }
 
+// runTests runs the tests in the files and directories given as arguments,
+// and returns the exit code.
+func runTests() int {
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *depFile != "" || *outputFormat != "make" || *outputGlobals {
+		quit("-depfile, -output_format and -output_globals are not supported with -mode=test\n")
+	}
+	if *junitOutput != "" {
+		abs, err := filepath.Abs(*junitOutput)
+		if err != nil {
+			quit("%s: %s\n", *junitOutput, err)
+		}
+		*junitOutput = abs
+	}
+	interpreter := rbcrun.NewInterpreter(rbcrun.ExecutionModeScl)
+	interpreter.AllowExternalEntrypoint = *allowExternalEntrypoint
+	setupHermetic(interpreter)
+	if os.Chdir(*rootdir) != nil {
+		quit("could not chdir to %s\n", *rootdir)
+	}
+	files, err := interpreter.FindTestFiles(flag.Args())
+	if err != nil {
+		quit("%s\n", err)
+	}
+
+	rc := 0
+	var suites []*rbcrun.TestSuite
+	for _, file := range files {
+		suite := interpreter.RunTests(file)
+		for _, tc := range suite.Cases {
+			if tc.Passed() {
+				fmt.Printf("PASS: %s: %s (%.3fs)\n", file, tc.Name, tc.Duration.Seconds())
+				continue
+			}
+			rc = 1
+			fmt.Printf("FAIL: %s: %s (%.3fs)\n", file, tc.Name, tc.Duration.Seconds())
+			if tc.Backtrace != "" {
+				fmt.Println(tc.Backtrace)
+			} else {
+				fmt.Println(tc.Failure + tc.Error)
+			}
+		}
+		suites = append(suites, suite)
+	}
+	if *junitOutput != "" {
+		var buf bytes.Buffer
+		if err := rbcrun.WriteJUnit(&buf, suites); err != nil {
+			quit("%s\n", err)
+		}
+		if err := os.WriteFile(*junitOutput, buf.Bytes(), 0666); err != nil {
+			quit("%s\n", err)
+		}
+	}
+	if interpreter.ShellLog != nil {
+		var buf bytes.Buffer
+		if err := interpreter.ShellLog.WriteJSON(&buf); err != nil {
+			quit("%s\n", err)
+		}
+		if err := os.WriteFile(*shellLog, buf.Bytes(), 0666); err != nil {
+			quit("%s\n", err)
+		}
+	}
+	return rc
+}
+
+// runCheck prints the violations of `filename` and the files it loads, and
+// returns the exit code.
+func runCheck(interpreter *rbcrun.Interpreter, filename string, src interface{}) int {
+	if src != nil {
+		quit("-check cannot check a -c script\n")
+	}
+	violations, err := interpreter.Check(filename)
+	if err != nil {
+		quit("%s\n", err)
+	}
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, v)
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runRepl evaluates `filename`, then runs a REPL on its globals, and returns
+// the exit code.
+func runRepl(interpreter *rbcrun.Interpreter, filename string, src interface{}) int {
+	if src != nil {
+		quit("-repl cannot run a -c script\n")
+	}
+	repl, err := interpreter.NewRepl(filename, os.Stdout, os.Stderr)
+	if repl == nil {
+		quit("%s\n", err)
+	}
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		fmt.Fprintln(os.Stderr, evalErr.Backtrace())
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	fmt.Println("Helpers: loaded_modules(), where(name), reload(path). End with Ctrl-D.")
+	if err := repl.Run(os.Stdin, true); err != nil {
+		quit("%s\n", err)
+	}
+	return 0
+}
+
+// setupHermetic configures the shell policy of `interpreter` from the flags.
+// Paths are relative to the original working directory.
+func setupHermetic(interpreter *rbcrun.Interpreter) {
+	if *hermetic != "" {
+		policy, err := rbcrun.ParseShellPolicy(*hermetic)
+		if err != nil || policy == rbcrun.ShellPolicyRun {
+			quit("Unknown -hermetic value %q, expected 1 of \"forbid\", \"allowlist\", \"replay\"\n", *hermetic)
+		}
+		interpreter.ShellPolicy = policy
+	}
+	if (*shellAllowlist != "") != (interpreter.ShellPolicy == rbcrun.ShellPolicyAllowlist) {
+		quit("-shell_allowlist and -hermetic=allowlist require each other\n")
+	}
+	if (*shellReplay != "") != (interpreter.ShellPolicy == rbcrun.ShellPolicyReplay) {
+		quit("-shell_replay and -hermetic=replay require each other\n")
+	}
+	if *shellAllowlist != "" {
+		f, err := os.Open(*shellAllowlist)
+		if err != nil {
+			quit("%s\n", err)
+		}
+		interpreter.ShellAllowlist, err = rbcrun.ReadShellAllowlist(f)
+		f.Close()
+		if err != nil {
+			quit("%s: %s\n", *shellAllowlist, err)
+		}
+	}
+	if *shellReplay != "" {
+		f, err := os.Open(*shellReplay)
+		if err != nil {
+			quit("%s\n", err)
+		}
+		interpreter.ShellReplay, err = rbcrun.ReadShellRecords(f)
+		f.Close()
+		if err != nil {
+			quit("%s: %s\n", *shellReplay, err)
+		}
+	}
+	if *shellLog != "" {
+		abs, err := filepath.Abs(*shellLog)
+		if err != nil {
+			quit("%s: %s\n", *shellLog, err)
+		}
+		*shellLog = abs
+		interpreter.ShellLog = &rbcrun.ShellLog{}
+	}
+}
+
+// writeDepFiles writes the depfile `depFile` for `target`, and the JSON
+// sidecar `depFile`.json.
+func writeDepFiles(deps *rbcrun.Dependencies, depFile, target string) error {
+	var buf bytes.Buffer
+	if err := deps.WriteDepfile(&buf, target); err != nil {
+		return err
+	}
+	if err := os.WriteFile(depFile, buf.Bytes(), 0666); err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := deps.WriteJSON(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(depFile+".json", buf.Bytes(), 0666)
+}
+
 func quit(format string, s ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, s...)
 	os.Exit(2)
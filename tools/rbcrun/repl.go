@@ -0,0 +1,370 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Repl is an interactive Starlark session on the globals of an evaluated
+// entrypoint file. Besides the builtins, it provides:
+//
+//	loaded_modules()  returns the paths to the loaded modules
+//	where(name)       prints where the global `name` was defined
+//	reload(path)      evaluates the changed module at `path` and the
+//	                  modules loading it again, and updates the globals
+type Repl struct {
+	in       *Interpreter
+	filename string
+	thread   *starlark.Thread
+	ev       *evaluation
+
+	// globals are the globals of the session.
+	globals starlark.StringDict
+
+	// fileGlobals are the names of the globals of the entrypoint file.
+	fileGlobals []string
+
+	out    io.Writer
+	errOut io.Writer
+}
+
+// NewRepl evaluates the entrypoint file `filename` like Run, and returns a
+// session on its globals writing to `out` and `errOut`. The session starts
+// even if the evaluation fails, with the error returned, so that the file can
+// be fixed and reloaded.
+func (in *Interpreter) NewRepl(filename string, out, errOut io.Writer) (*Repl, error) {
+	thread, ev, globals, err := in.execFile(nil, filename, nil, in.Mode, nil)
+	if ev == nil {
+		return nil, err
+	}
+	r := &Repl{
+		in:       in,
+		filename: thread.Local(callingFileKey).(string),
+		thread:   thread,
+		ev:       ev,
+		globals:  make(starlark.StringDict),
+		out:      out,
+		errOut:   errOut,
+	}
+	builtins, _ := ev.builtins(in.Mode)
+	for k, v := range builtins {
+		r.globals[k] = v
+	}
+	r.globals["loaded_modules"] = starlark.NewBuiltin("loaded_modules", r.loadedModules)
+	r.globals["where"] = starlark.NewBuiltin("where", r.where)
+	r.globals["reload"] = starlark.NewBuiltin("reload", r.reload)
+	r.setFileGlobals(globals)
+	return r, err
+}
+
+// Globals returns the globals of the session.
+func (r *Repl) Globals() starlark.StringDict {
+	return r.globals
+}
+
+// setFileGlobals replaces the globals of the entrypoint file with `globals`
+// and the symbols bound by its load statements.
+func (r *Repl) setFileGlobals(globals starlark.StringDict) {
+	for _, name := range r.fileGlobals {
+		delete(r.globals, name)
+	}
+	fileGlobals := r.loadBindings()
+	for name, v := range globals {
+		fileGlobals[name] = v
+	}
+	r.fileGlobals = fileGlobals.Keys()
+	for name, v := range fileGlobals {
+		r.globals[name] = v
+	}
+}
+
+// loadBindings returns the symbols bound by the load statements of the
+// entrypoint file, which are not globals of the file.
+func (r *Repl) loadBindings() starlark.StringDict {
+	bindings := make(starlark.StringDict)
+	src, err := os.ReadFile(rootedPath(r.thread, r.filename))
+	if err != nil {
+		return bindings
+	}
+	f, err := syntax.LegacyFileOptions().Parse(r.filename, src, 0)
+	if err != nil {
+		return bindings
+	}
+	for _, stmt := range f.Stmts {
+		load, ok := stmt.(*syntax.LoadStmt)
+		if !ok {
+			continue
+		}
+		modulePath, err := r.loadPath(load, r.filename, r.in.AllowExternalEntrypoint)
+		if err != nil {
+			continue
+		}
+		e := r.ev.modules[modulePath]
+		if e == nil {
+			continue
+		}
+		for i, to := range load.To {
+			if v, ok := e.globals[load.From[i].Name]; ok {
+				bindings[to.Name] = v
+			}
+		}
+	}
+	return bindings
+}
+
+// loadPath returns the path to the module loaded by `load` in the module
+// at `path`.
+func (r *Repl) loadPath(load *syntax.LoadStmt, path string, allowExternal bool) (string, error) {
+	module := load.Module.Value.(string)
+	if pipe := strings.LastIndex(module, "|"); pipe >= 0 {
+		module = module[:pipe]
+	}
+	return cleanModuleName(module, filepath.Dir(path), allowExternal)
+}
+
+// Run reads, evaluates and prints each statement from `input` until EOF,
+// printing `prompt` before each line. Starlark errors are printed, and only
+// read errors are returned.
+func (r *Repl) Run(input io.Reader, prompt bool) error {
+	reader := bufio.NewReader(input)
+	opts := *syntax.LegacyFileOptions()
+	// Load bindings are global in a REPL.
+	opts.LoadBindsGlobally = true
+	for {
+		eof := false
+		ps := ">>> "
+		readline := func() ([]byte, error) {
+			if prompt {
+				fmt.Fprint(r.out, ps)
+				ps = "... "
+			}
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF && len(line) > 0 {
+				return append(line, '\n'), nil
+			}
+			if err == io.EOF {
+				eof = true
+			}
+			return line, err
+		}
+		f, err := opts.ParseCompoundStmt("<stdin>", readline)
+		if err != nil {
+			if eof {
+				if prompt {
+					fmt.Fprintln(r.out)
+				}
+				return nil
+			}
+			if _, ok := err.(syntax.Error); !ok {
+				return err
+			}
+			r.printError(err)
+			continue
+		}
+		r.exec(f)
+	}
+}
+
+// exec executes `f`, printing the value of a sole expression.
+func (r *Repl) exec(f *syntax.File) {
+	if len(f.Stmts) == 1 {
+		if stmt, ok := f.Stmts[0].(*syntax.ExprStmt); ok {
+			v, err := starlark.EvalExprOptions(f.Options, r.thread, stmt.X, r.globals)
+			if err != nil {
+				r.printError(err)
+			} else if v != starlark.None {
+				fmt.Fprintln(r.out, v)
+			}
+			return
+		}
+	}
+	if err := starlark.ExecREPLChunk(f, r.thread, r.globals); err != nil {
+		r.printError(err)
+	}
+}
+
+func (r *Repl) printError(err error) {
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		fmt.Fprintln(r.errOut, evalErr.Backtrace())
+	} else {
+		fmt.Fprintln(r.errOut, err)
+	}
+}
+
+// loaded_modules() returns the sorted paths to the loaded modules.
+func (r *Repl) loadedModules(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, err
+	}
+	return makeStringList(r.ev.loadedFiles()), nil
+}
+
+// where(name) prints where the global `name` of the entrypoint file was
+// defined, following the load statements binding it.
+func (r *Repl) where(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &name); err != nil {
+		return starlark.None, err
+	}
+	steps, err := r.definitions(r.filename, name, r.in.AllowExternalEntrypoint)
+	if err != nil {
+		return starlark.None, err
+	}
+	if len(steps) == 0 {
+		if _, ok := r.globals[name]; !ok && !starlark.Universe.Has(name) {
+			return starlark.None, fmt.Errorf("%s is not defined", name)
+		}
+		steps = []string{name + ": builtin"}
+	}
+	for _, step := range steps {
+		fmt.Fprintln(r.out, step)
+	}
+	return starlark.None, nil
+}
+
+// definitions returns where the module at `path` defines or loads the
+// global `name`, following loads into the loaded modules.
+func (r *Repl) definitions(path, name string, allowExternal bool) ([]string, error) {
+	src, err := os.ReadFile(rootedPath(r.thread, path))
+	if err != nil {
+		return nil, err
+	}
+	f, err := syntax.LegacyFileOptions().Parse(path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	var steps []string
+	var walkErr error
+	syntax.Walk(f, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.LoadStmt:
+			for i, to := range n.To {
+				if to.Name != name {
+					continue
+				}
+				modulePath, err := r.loadPath(n, path, allowExternal)
+				if err != nil {
+					walkErr = err
+					return false
+				}
+				from := n.From[i].Name
+				steps = append(steps, fmt.Sprintf("%s: %s loads %s from %s", to.NamePos, name, from, modulePath))
+				loaded, err := r.definitions(modulePath, from, false)
+				if err != nil {
+					walkErr = err
+					return false
+				}
+				steps = append(steps, loaded...)
+			}
+		case *syntax.AssignStmt:
+			for _, id := range assignedIdents(n.LHS) {
+				if id.Name == name {
+					steps = append(steps, fmt.Sprintf("%s: %s assigned", id.NamePos, name))
+				}
+			}
+		case *syntax.DefStmt:
+			if n.Name.Name == name {
+				steps = append(steps, fmt.Sprintf("%s: %s defined", n.Name.NamePos, name))
+			}
+			// Assignments in functions are local.
+			return false
+		}
+		return true
+	})
+	return steps, walkErr
+}
+
+// assignedIdents returns the identifiers assigned by the left-hand side
+// `lhs` of an assignment.
+func assignedIdents(lhs syntax.Expr) []*syntax.Ident {
+	switch lhs := lhs.(type) {
+	case *syntax.Ident:
+		return []*syntax.Ident{lhs}
+	case *syntax.ParenExpr:
+		return assignedIdents(lhs.X)
+	case *syntax.TupleExpr:
+		var ids []*syntax.Ident
+		for _, x := range lhs.List {
+			ids = append(ids, assignedIdents(x)...)
+		}
+		return ids
+	case *syntax.ListExpr:
+		var ids []*syntax.Ident
+		for _, x := range lhs.List {
+			ids = append(ids, assignedIdents(x)...)
+		}
+		return ids
+	}
+	return nil
+}
+
+// reload(path) evaluates the module at `path`, the modules loading it and
+// the entrypoint file again, reusing the other loaded modules, and updates
+// the globals of the entrypoint file.
+func (r *Repl) reload(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &path); err != nil {
+		return starlark.None, err
+	}
+	path = filepath.Clean(path)
+	if _, ok := r.ev.modules[path]; !ok {
+		return starlark.None, fmt.Errorf("%s is not loaded", path)
+	}
+
+	// Forget the module and every module loading it, directly or not.
+	stale := map[string]bool{path: true}
+	for changed := true; changed; {
+		changed = false
+		for from, tos := range r.ev.loads {
+			if stale[from] {
+				continue
+			}
+			for _, to := range tos {
+				if stale[to] {
+					stale[from] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	stale[r.filename] = true
+	for p := range stale {
+		delete(r.ev.modules, p)
+		delete(r.ev.loads, p)
+		r.in.sclModules.Delete(p)
+	}
+
+	thread, _, globals, err := r.in.execFile(r.ev, r.filename, nil, r.in.Mode, nil)
+	if thread != nil {
+		r.thread = thread
+	}
+	if globals != nil {
+		r.setFileGlobals(globals)
+	}
+	return starlark.None, err
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// copyTestdata copies the files of the testdata subdirectory `dir` for a
+// test changing them, and returns the copy.
+func copyTestdata(t *testing.T, dir string) string {
+	root := t.TempDir()
+	src := filepath.Join(dataDir(), dir)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, e.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func runRepl(t *testing.T, r *Repl, input string) (string, string) {
+	var out, errOut bytes.Buffer
+	r.out, r.errOut = &out, &errOut
+	if err := r.Run(strings.NewReader(input), false); err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), errOut.String()
+}
+
+func TestRepl(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "repl")
+	r, err := in.NewRepl("entry.star", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "x + 1\nloaded_modules()\ndef g():\n    return local\n\ng()\nz = c\nz\nundefined\n"
+	out, errOut := runRepl(t, r, input)
+	expected := "3\n[\"a.star\", \"b.star\", \"c.star\", \"entry.star\"]\n4\n3\n"
+	if out != expected {
+		t.Errorf("Unexpected output %q, want %q", out, expected)
+	}
+	if !strings.Contains(errOut, "undefined: undefined") {
+		t.Errorf("Expected an undefined error, got %q", errOut)
+	}
+}
+
+func TestReplWhere(t *testing.T) {
+	in := testdataInterpreter(ExecutionModeRbc, "repl")
+	r, err := in.NewRepl("entry.star", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, errOut := runRepl(t, r, "where(\"x\")\nwhere(\"local\")\nwhere(\"len\")\nwhere(\"nope\")\n")
+	expected := "entry.star:1:17: x loads y from a.star\n" +
+		"a.star:2:1: y assigned\n" +
+		"entry.star:3:1: local assigned\n" +
+		"len: builtin\n"
+	if out != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out, expected)
+	}
+	if !strings.Contains(errOut, "Error in where: nope is not defined") {
+		t.Errorf("Expected a not defined error, got %q", errOut)
+	}
+}
+
+func TestReplReload(t *testing.T) {
+	root := copyTestdata(t, "repl")
+	in := NewInterpreter(ExecutionModeRbc)
+	in.RootDir = root
+	in.LoadGraph = &LoadGraph{}
+	r, err := in.NewRepl("entry.star", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.star"), []byte("b = 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := len(in.LoadGraph.Modules())
+	out, errOut := runRepl(t, r, "x\nreload(\"b.star\")\nx\nlocal\nreload(\"missing.star\")\n")
+	if expected := "2\n11\n22\n"; out != expected {
+		t.Errorf("Unexpected output %q, want %q", out, expected)
+	}
+	if !strings.Contains(errOut, "Error in reload: missing.star is not loaded") {
+		t.Errorf("Expected a not loaded error, got %q", errOut)
+	}
+
+	// Only b.star and the modules loading it run again.
+	var reloaded []string
+	for _, m := range in.LoadGraph.Modules()[before:] {
+		reloaded = append(reloaded, m.Path)
+	}
+	if expected := []string{"b.star", "a.star", "entry.star"}; !reflect.DeepEqual(reloaded, expected) {
+		t.Errorf("Unexpected modules run again %v, want %v", reloaded, expected)
+	}
+}
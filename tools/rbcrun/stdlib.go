@@ -0,0 +1,289 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// The standard library of scl mode. Like the rest of scl, it must be
+// deterministic and must not access the filesystem or the environment.
+
+// semverRe matches a semantic version as defined by https://semver.org.
+var semverRe = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+	`(?:-((?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// semverModule is predeclared as `semver` in scl mode.
+var semverModule = &starlarkstruct.Module{
+	Name: "semver",
+	Members: starlark.StringDict{
+		"parse":   starlark.NewBuiltin("semver.parse", semverParse),
+		"valid":   starlark.NewBuiltin("semver.valid", semverValid),
+		"compare": starlark.NewBuiltin("semver.compare", semverCompare),
+	},
+}
+
+// version is a parsed semantic version.
+type version struct {
+	major, minor, patch int
+	prerelease          []string
+	build               string
+}
+
+func parseVersion(s string) (*version, error) {
+	m := semverRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid semantic version %q", s)
+	}
+	v := &version{build: m[5]}
+	for i, p := range []*int{&v.major, &v.minor, &v.patch} {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version %q: %s", s, err)
+		}
+		*p = n
+	}
+	if m[4] != "" {
+		v.prerelease = strings.Split(m[4], ".")
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 as `v` has lower, equal or higher precedence
+// than `other`. Build metadata does not affect precedence.
+func (v *version) compare(other *version) int {
+	for _, d := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	// A version without prerelease has higher precedence.
+	if len(v.prerelease) == 0 || len(other.prerelease) == 0 {
+		return sign(len(other.prerelease) - len(v.prerelease))
+	}
+	for i := 0; i < len(v.prerelease) && i < len(other.prerelease); i++ {
+		a, b := v.prerelease[i], other.prerelease[i]
+		aNum, bNum := isNumeric(a), isNumeric(b)
+		switch {
+		case aNum && bNum:
+			// Numeric identifiers have no leading zeros, so the longer
+			// one is larger; this also avoids overflowing an int.
+			if len(a) != len(b) {
+				return sign(len(a) - len(b))
+			}
+			if a != b {
+				return strings.Compare(a, b)
+			}
+		case aNum:
+			// Numeric identifiers have lower precedence.
+			return -1
+		case bNum:
+			return 1
+		case a != b:
+			return strings.Compare(a, b)
+		}
+	}
+	return sign(len(v.prerelease) - len(other.prerelease))
+}
+
+// isNumeric reports whether a prerelease identifier consists of digits
+// only. Identifiers such as "-1" are alphanumeric.
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// semver.parse(version) returns a struct with the major, minor and patch
+// ints, and the prerelease and build strings of the semantic version.
+func semverParse(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return starlark.None, err
+	}
+	v, err := parseVersion(s)
+	if err != nil {
+		return starlark.None, err
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"major":      starlark.MakeInt(v.major),
+		"minor":      starlark.MakeInt(v.minor),
+		"patch":      starlark.MakeInt(v.patch),
+		"prerelease": starlark.String(strings.Join(v.prerelease, ".")),
+		"build":      starlark.String(v.build),
+	}), nil
+}
+
+// semver.valid(version) returns whether version is a semantic version.
+func semverValid(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return starlark.None, err
+	}
+	return starlark.Bool(semverRe.MatchString(s)), nil
+}
+
+// semver.compare(a, b) returns -1, 0 or 1 as the semantic version a has
+// lower, equal or higher precedence than b.
+func semverCompare(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x, y string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil {
+		return starlark.None, err
+	}
+	vx, err := parseVersion(x)
+	if err != nil {
+		return starlark.None, err
+	}
+	vy, err := parseVersion(y)
+	if err != nil {
+		return starlark.None, err
+	}
+	return starlark.MakeInt(vx.compare(vy)), nil
+}
+
+// stringsModule is predeclared as `strings` in scl mode. It complements the
+// string methods.
+var stringsModule = &starlarkstruct.Module{
+	Name: "strings",
+	Members: starlark.StringDict{
+		"dedup":       starlark.NewBuiltin("strings.dedup", stringsDedup),
+		"pad_left":    starlark.NewBuiltin("strings.pad_left", stringsPadLeft),
+		"pad_right":   starlark.NewBuiltin("strings.pad_right", stringsPadRight),
+		"shell_quote": starlark.NewBuiltin("strings.shell_quote", stringsShellQuote),
+		"trim_prefix": starlark.NewBuiltin("strings.trim_prefix", stringsTrimPrefix),
+		"trim_suffix": starlark.NewBuiltin("strings.trim_suffix", stringsTrimSuffix),
+	},
+}
+
+// strings.dedup(list) returns the strings of list without duplicates, in the
+// order they first appear.
+func stringsDedup(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var list starlark.Iterable
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &list); err != nil {
+		return starlark.None, err
+	}
+	seen := make(map[string]bool)
+	var result []string
+	iter := list.Iterate()
+	defer iter.Done()
+	var v starlark.Value
+	for iter.Next(&v) {
+		s, ok := v.(starlark.String)
+		if !ok {
+			return starlark.None, fmt.Errorf("got %s, want string", v.Type())
+		}
+		if !seen[string(s)] {
+			seen[string(s)] = true
+			result = append(result, string(s))
+		}
+	}
+	return makeStringList(result), nil
+}
+
+// padding returns the padding of `s` to `width` runes with `fill`.
+func padding(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (string, string, error) {
+	var s string
+	var width int
+	fill := " "
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s, "width", &width, "fill?", &fill); err != nil {
+		return "", "", err
+	}
+	if len([]rune(fill)) != 1 {
+		return "", "", fmt.Errorf("fill must be a single character, got %q", fill)
+	}
+	n := width - len([]rune(s))
+	if n <= 0 {
+		return s, "", nil
+	}
+	return s, strings.Repeat(fill, n), nil
+}
+
+// strings.pad_left(s, width, fill = " ") returns s preceded by enough fill
+// characters to be width characters long.
+func stringsPadLeft(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	s, pad, err := padding(b, args, kwargs)
+	if err != nil {
+		return starlark.None, err
+	}
+	return starlark.String(pad + s), nil
+}
+
+// strings.pad_right(s, width, fill = " ") returns s followed by enough fill
+// characters to be width characters long.
+func stringsPadRight(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	s, pad, err := padding(b, args, kwargs)
+	if err != nil {
+		return starlark.None, err
+	}
+	return starlark.String(s + pad), nil
+}
+
+// strings.shell_quote(s) returns s quoted as a single POSIX shell word.
+func stringsShellQuote(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return starlark.None, err
+	}
+	return starlark.String("'" + strings.ReplaceAll(s, "'", `'\''`) + "'"), nil
+}
+
+// strings.trim_prefix(s, prefix) returns s without the leading prefix, if
+// present.
+func stringsTrimPrefix(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s, prefix string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &s, &prefix); err != nil {
+		return starlark.None, err
+	}
+	return starlark.String(strings.TrimPrefix(s, prefix)), nil
+}
+
+// strings.trim_suffix(s, suffix) returns s without the trailing suffix, if
+// present.
+func stringsTrimSuffix(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s, suffix string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &s, &suffix); err != nil {
+		return starlark.None, err
+	}
+	return starlark.String(strings.TrimSuffix(s, suffix)), nil
+}
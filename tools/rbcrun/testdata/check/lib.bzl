@@ -0,0 +1 @@
+y = 1
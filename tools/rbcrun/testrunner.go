@@ -0,0 +1,367 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// testFileSuffixes are the suffixes of the test files found in directories.
+var testFileSuffixes = []string{"_test.scl", "_test.rbc"}
+
+// AssertionError is the error of a failed assert module check.
+type AssertionError struct {
+	Msg string
+}
+
+func (e *AssertionError) Error() string {
+	return e.Msg
+}
+
+// assertModule is predeclared as `assert` in test files.
+var assertModule = &starlarkstruct.Module{
+	Name: "assert",
+	Members: starlark.StringDict{
+		"eq":       starlark.NewBuiltin("assert.eq", assertEq),
+		"ne":       starlark.NewBuiltin("assert.ne", assertNe),
+		"true":     starlark.NewBuiltin("assert.true", assertTrue),
+		"contains": starlark.NewBuiltin("assert.contains", assertContains),
+		"fails":    starlark.NewBuiltin("assert.fails", assertFails),
+	},
+}
+
+// assertionError returns an AssertionError with `format`, followed by `msg`
+// if not empty. The backtrace names the assertion.
+func assertionError(msg string, format string, args ...interface{}) error {
+	s := fmt.Sprintf(format, args...)
+	if msg != "" {
+		s += ": " + msg
+	}
+	return &AssertionError{s}
+}
+
+// assert.eq(actual, expected, msg = "") checks that actual == expected.
+func assertEq(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var actual, expected starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "actual", &actual, "expected", &expected, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if eq, err := starlark.Equal(actual, expected); err != nil {
+		return starlark.None, err
+	} else if !eq {
+		return starlark.None, assertionError(msg, "%s != %s", actual, expected)
+	}
+	return starlark.None, nil
+}
+
+// assert.ne(actual, unexpected, msg = "") checks that actual != unexpected.
+func assertNe(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var actual, unexpected starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "actual", &actual, "unexpected", &unexpected, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if eq, err := starlark.Equal(actual, unexpected); err != nil {
+		return starlark.None, err
+	} else if eq {
+		return starlark.None, assertionError(msg, "%s == %s", actual, unexpected)
+	}
+	return starlark.None, nil
+}
+
+// assert.true(cond, msg = "") checks that cond is truthy.
+func assertTrue(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cond starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "cond", &cond, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if !cond.Truth() {
+		return starlark.None, assertionError(msg, "%s is not true", cond)
+	}
+	return starlark.None, nil
+}
+
+// assert.contains(container, element, msg = "") checks that element is in
+// container.
+func assertContains(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var container, element starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "container", &container, "element", &element, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	found, err := starlark.Binary(syntax.IN, element, container)
+	if err != nil {
+		return starlark.None, err
+	}
+	if !found.Truth() {
+		return starlark.None, assertionError(msg, "%s does not contain %s", container, element)
+	}
+	return starlark.None, nil
+}
+
+// assert.fails(fn, pattern) checks that calling fn fails with an error
+// matching the regular expression pattern.
+func assertFails(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fn starlark.Callable
+	var pattern string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fn", &fn, "pattern", &pattern); err != nil {
+		return starlark.None, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return starlark.None, err
+	}
+	_, err = starlark.Call(thread, fn, nil, nil)
+	if err == nil {
+		return starlark.None, assertionError("", "%s did not fail", fn.Name())
+	}
+	if !re.MatchString(err.Error()) {
+		return starlark.None, assertionError("", "%s failed with %q, not matching %q", fn.Name(), err.Error(), pattern)
+	}
+	return starlark.None, nil
+}
+
+// TestCase is the result of a single test function.
+type TestCase struct {
+	// Name is the name of the test function.
+	Name string
+
+	// Duration is the time the test took including loading its file.
+	Duration time.Duration
+
+	// Failure is the message of the failed assertion, if any.
+	Failure string
+
+	// Error is the message of any other error, if any.
+	Error string
+
+	// Backtrace is the Starlark backtrace of the failure or error.
+	Backtrace string
+}
+
+// Passed returns whether the test neither failed nor had an error.
+func (tc *TestCase) Passed() bool {
+	return tc.Failure == "" && tc.Error == ""
+}
+
+// TestSuite is the result of the tests in one file.
+type TestSuite struct {
+	// File is the path to the test file relative to the root directory.
+	File string
+
+	// Cases lists the results of the test functions sorted by name, or a
+	// single case named after the file when it fails to execute.
+	Cases []TestCase
+
+	// Duration is the time the suite took.
+	Duration time.Duration
+}
+
+// Passed returns whether all the tests in the suite passed.
+func (ts *TestSuite) Passed() bool {
+	for i := range ts.Cases {
+		if !ts.Cases[i].Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// FindTestFiles returns the test files at `paths` relative to the root
+// directory. Files are returned as is, and directories are searched for
+// files ending in _test.scl or _test.rbc.
+func (in *Interpreter) FindTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		rooted := path
+		if in.RootDir != "" && !filepath.IsAbs(path) {
+			rooted = filepath.Join(in.RootDir, path)
+		}
+		fi, err := os.Stat(rooted)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(rooted, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			for _, suffix := range testFileSuffixes {
+				if strings.HasSuffix(p, suffix) {
+					rel, err := filepath.Rel(rooted, p)
+					if err != nil {
+						return err
+					}
+					files = append(files, filepath.Join(path, rel))
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// RunTests runs the test_* functions of the test file `filename`. .scl files
+// run in scl mode, and other files in rbc mode. Each test runs in isolation
+// on a fresh evaluation of the file. (thread safe)
+func (in *Interpreter) RunTests(filename string) *TestSuite {
+	start := time.Now()
+	suite := &TestSuite{File: filename}
+	mode := ExecutionModeRbc
+	if strings.HasSuffix(filename, ".scl") {
+		mode = ExecutionModeScl
+	}
+	extra := starlark.StringDict{"assert": assertModule}
+	_, _, globals, err := in.execFile(nil, filename, nil, mode, extra)
+	if err != nil {
+		suite.Cases = []TestCase{testCase(filename, start, err)}
+		suite.Duration = time.Since(start)
+		return suite
+	}
+	var names []string
+	for name, v := range globals {
+		if _, ok := v.(*starlark.Function); ok && strings.HasPrefix(name, "test_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		caseStart := time.Now()
+		thread, _, globals, err := in.execFile(nil, filename, nil, mode, extra)
+		if err == nil {
+			_, err = starlark.Call(thread, globals[name], nil, nil)
+		}
+		suite.Cases = append(suite.Cases, testCase(name, caseStart, err))
+	}
+	suite.Duration = time.Since(start)
+	return suite
+}
+
+// testCase returns the result of test `name` started at `start` that ended
+// with `err`.
+func testCase(name string, start time.Time, err error) TestCase {
+	tc := TestCase{Name: name, Duration: time.Since(start)}
+	if err == nil {
+		return tc
+	}
+	var assertion *AssertionError
+	if errors.As(err, &assertion) {
+		tc.Failure = err.Error()
+	} else {
+		tc.Error = err.Error()
+	}
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		tc.Backtrace = evalErr.Backtrace()
+	}
+	return tc
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+// WriteJUnit writes `suites` as JUnit XML.
+func WriteJUnit(w io.Writer, suites []*TestSuite) error {
+	seconds := func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", d.Seconds())
+	}
+	var out junitTestSuites
+	for _, suite := range suites {
+		js := junitTestSuite{Name: suite.File, Tests: len(suite.Cases), Time: seconds(suite.Duration)}
+		for _, tc := range suite.Cases {
+			jc := junitTestCase{Name: tc.Name, Classname: suite.File, Time: seconds(tc.Duration)}
+			if tc.Failure != "" {
+				jc.Failure = &junitMessage{tc.Failure, tc.Backtrace}
+				js.Failures++
+			}
+			if tc.Error != "" {
+				jc.Error = &junitMessage{tc.Error, tc.Backtrace}
+				js.Errors++
+			}
+			js.Cases = append(js.Cases, jc)
+		}
+		out.Tests += js.Tests
+		out.Failures += js.Failures
+		out.Errors += js.Errors
+		out.Suites = append(out.Suites, js)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbcrun
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testRunnerInterpreter() *Interpreter {
+	in := NewInterpreter(ExecutionModeScl)
+	in.RootDir = dataDir()
+	return in
+}
+
+func TestFindTestFiles(t *testing.T) {
+	files, err := testRunnerInterpreter().FindTestFiles([]string{"test_runner", "test_scl.scl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"test_runner/broken_test.scl",
+		"test_runner/lib_test.scl",
+		"test_runner/product_test.rbc",
+		"test_scl.scl",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Unexpected test files %v, want %v", files, expected)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	type result struct {
+		name    string
+		failure string
+		error   string
+	}
+	tests := []struct {
+		file     string
+		expected []result
+	}{
+		{
+			file: "test_runner/lib_test.scl",
+			expected: []result{
+				{name: "test_double"},
+				{name: "test_error", error: `key "missing" not in dict`},
+				{name: "test_fails"},
+				{name: "test_wrong", failure: "4 != 5: double is off"},
+			},
+		},
+		{
+			file:     "test_runner/product_test.rbc",
+			expected: []result{{name: "test_contains"}},
+		},
+		{
+			file: "test_runner/broken_test.scl",
+			expected: []result{{
+				name:  "test_runner/broken_test.scl",
+				error: "cannot load :missing.scl: lstat ",
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			suite := testRunnerInterpreter().RunTests(test.file)
+			var got []result
+			for _, tc := range suite.Cases {
+				r := result{name: tc.Name, failure: tc.Failure, error: tc.Error}
+				// Keep only the stable prefix of OS errors.
+				if i := strings.Index(r.error, "lstat "); i >= 0 {
+					r.error = r.error[:i+len("lstat ")]
+				}
+				got = append(got, r)
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Unexpected results %+v, want %+v", got, test.expected)
+			}
+			if passed := len(test.expected) == 1 && test.expected[0].error == ""; suite.Passed() != passed {
+				t.Errorf("Unexpected Passed() %t", suite.Passed())
+			}
+		})
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	in := testRunnerInterpreter()
+	suites := []*TestSuite{
+		in.RunTests("test_runner/lib_test.scl"),
+		in.RunTests("test_runner/product_test.rbc"),
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, suites); err != nil {
+		t.Fatal(err)
+	}
+	got := regexp.MustCompile(`time="[0-9.]+"`).ReplaceAllString(buf.String(), `time="T"`)
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuites tests="5" failures="1" errors="1">`,
+		`<testsuite name="test_runner/lib_test.scl" tests="4" failures="1" errors="1" time="T">`,
+		`<testcase name="test_double" classname="test_runner/lib_test.scl" time="T"></testcase>`,
+		`<failure message="4 != 5: double is off"><![CDATA[Traceback`,
+		`<error message="key &#34;missing&#34; not in dict"><![CDATA[Traceback`,
+		`<testcase name="test_contains" classname="test_runner/product_test.rbc" time="T"></testcase>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in JUnit XML:\n%s", want, got)
+		}
+	}
+}